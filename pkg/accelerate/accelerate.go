@@ -3,13 +3,18 @@ package accelerate
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	k8sutilerrors "k8s.io/apimachinery/pkg/util/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/mounts3"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/mounttos"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/prepull"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/refdata"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/crontab"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
@@ -39,19 +44,97 @@ type Accelerator interface {
 }
 
 // NewAccelerator ...
-func NewAccelerator(vetesClient vetesclient.Client, kubeClient ctrlclient.Client, namespace string, opts *Options) (Accelerator, error) {
-	switch opts.Type {
+func NewAccelerator(vetesClient vetesclient.Client, kubeClient ctrlclient.Client, localStoreHelper localstore.Helper, namespace string, opts *Options) (Accelerator, error) {
+	if opts.Type != consts.CompositeAccelerateType {
+		return newAccelerator(opts.Type, vetesClient, kubeClient, localStoreHelper, namespace, opts)
+	}
+	c := &composite{}
+	for _, accelerateType := range opts.Chain {
+		accelerator, err := newAccelerator(accelerateType, vetesClient, kubeClient,
+			localstore.NewNamespacedHelper(localStoreHelper, accelerateType), namespace, opts)
+		if err != nil {
+			return nil, err
+		}
+		c.children = append(c.children, &compositeChild{accelerateType: accelerateType, accelerator: accelerator})
+	}
+	return c, nil
+}
+
+func newAccelerator(accelerateType string, vetesClient vetesclient.Client, kubeClient ctrlclient.Client, localStoreHelper localstore.Helper, namespace string, opts *Options) (Accelerator, error) {
+	switch accelerateType {
 	case consts.NullAccelerateType:
 		return &null{}, nil
 	case consts.MountTOSAccelerateType:
-		return mounttos.New(vetesClient, kubeClient, namespace, opts.MountTOS), nil
+		return mounttos.New(vetesClient, kubeClient, localStoreHelper, namespace, opts.MountTOS), nil
+	case consts.MountS3AccelerateType:
+		return mounts3.New(kubeClient, localStoreHelper, namespace, opts.MountS3), nil
+	case consts.PrePullAccelerateType:
+		return prepull.New(kubeClient, localStoreHelper, namespace, opts.PrePull), nil
+	case consts.RefDataAccelerateType:
+		return refdata.New(kubeClient, namespace, opts.RefData), nil
 	default:
-		return nil, fmt.Errorf("unsopportted accelerate type: %s", opts.Type)
+		return nil, fmt.Errorf("unsopportted accelerate type: %s", accelerateType)
+	}
+}
+
+// UpdateOptions applies the reloadable subset of opts to accelerator.
+// It returns whether some changed options require restart to take effect.
+func UpdateOptions(accelerator Accelerator, opts *Options) (needRestart bool, err error) {
+	c, ok := accelerator.(*composite)
+	if !ok {
+		return updateOptions(accelerator, opts.Type, opts)
+	}
+	if opts.Type != consts.CompositeAccelerateType || len(opts.Chain) != len(c.children) {
+		return true, nil
+	}
+	var errs []error
+	for index, child := range c.children {
+		if opts.Chain[index] != child.accelerateType {
+			return true, nil
+		}
+		childNeedRestart, err := updateOptions(child.accelerator, child.accelerateType, opts)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		needRestart = needRestart || childNeedRestart
+	}
+	return needRestart, k8sutilerrors.NewAggregate(errs)
+}
+
+func updateOptions(accelerator Accelerator, accelerateType string, opts *Options) (needRestart bool, err error) {
+	switch impl := accelerator.(type) {
+	case *null:
+		return accelerateType != consts.NullAccelerateType, nil
+	case *mounttos.Impl:
+		if accelerateType != consts.MountTOSAccelerateType {
+			return true, nil
+		}
+		return impl.UpdateOptions(opts.MountTOS), nil
+	case *mounts3.Impl:
+		// pv of mounted buckets are shared by tasks, so no options of mount-s3 are reloadable
+		return accelerateType != consts.MountS3AccelerateType || !reflect.DeepEqual(impl.Options(), opts.MountS3), nil
+	case *prepull.Impl:
+		return accelerateType != consts.PrePullAccelerateType || !reflect.DeepEqual(impl.Options(), opts.PrePull), nil
+	case *refdata.Impl:
+		if accelerateType != consts.RefDataAccelerateType {
+			return true, nil
+		}
+		return impl.UpdateOptions(opts.RefData), nil
+	default:
+		return false, fmt.Errorf("accelerator %T does not support updating options", accelerator)
 	}
 }
 
 // RegisterCrontab ...
 func RegisterCrontab(cron *crontab.Crontab, accelerator Accelerator) error {
+	if c, ok := accelerator.(*composite); ok {
+		for _, child := range c.children {
+			if err := RegisterCrontab(cron, child.accelerator); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	fn, period := accelerator.CronCleanFunc()
 	if fn == nil || period == nil {
 		return nil
@@ -0,0 +1,101 @@
+package bucket
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+// ExtractBucketsAndSort returns s3 buckets of inputs, sorted by the number of inputs in descending order
+func ExtractBucketsAndSort(inputs []*models.Input) []string {
+	bucketCounts := make(map[string]int)
+	for _, input := range inputs {
+		bucket := ExtractBucket(input.URL)
+		if bucket == "" {
+			continue
+		}
+		bucketCounts[bucket]++
+	}
+	res := make([]string, 0, len(bucketCounts))
+	for bucket := range bucketCounts {
+		res = append(res, bucket)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return bucketCounts[res[i]] > bucketCounts[res[j]]
+	})
+	return res
+}
+
+// ExtractBucket returns bucket of s3 url, or empty for other urls
+func ExtractBucket(taskURL string) string {
+	u, err := url.Parse(taskURL)
+	if err != nil {
+		log.Errorw("failed to parse task url", "err", err)
+		return ""
+	}
+	if u.Scheme != consts.S3Type {
+		return ""
+	}
+	return u.Host
+}
+
+// RewriteInputURLs rewrites `s3://bucket/path` of inputs in mounted buckets to `<mountPath(bucket)>/path`
+func RewriteInputURLs(inputs []*models.Input, mountedBuckets map[string]struct{}, mountPath func(bucket string) string) {
+	for _, input := range inputs {
+		bucket := ExtractBucket(input.URL)
+		if bucket == "" {
+			continue
+		}
+		if _, ok := mountedBuckets[bucket]; !ok {
+			continue
+		}
+		input.URL = strings.Replace(input.URL, fmt.Sprintf("s3://%s", bucket), mountPath(bucket), 1)
+	}
+}
+
+// CommonPrefixes returns the longest common directory of s3 inputs per bucket, without leading and trailing slash.
+// It is empty if inputs of the bucket share no directory.
+func CommonPrefixes(inputs []*models.Input) map[string]string {
+	res := make(map[string]string)
+	for _, input := range inputs {
+		bucket := ExtractBucket(input.URL)
+		if bucket == "" {
+			continue
+		}
+		key := strings.TrimPrefix(input.URL, fmt.Sprintf("s3://%s", bucket))
+		key = strings.TrimPrefix(key, "/")
+		dir := strings.TrimSuffix(key, "/")
+		if !strings.HasSuffix(key, "/") {
+			dir = path.Dir(key)
+		}
+		if dir == "." {
+			dir = ""
+		}
+		prefix, ok := res[bucket]
+		if !ok {
+			res[bucket] = dir
+			continue
+		}
+		res[bucket] = commonDir(prefix, dir)
+	}
+	return res
+}
+
+func commonDir(a, b string) string {
+	if a == "" || b == "" {
+		return ""
+	}
+	aParts, bParts := strings.Split(a, "/"), strings.Split(b, "/")
+	index := 0
+	for index < len(aParts) && index < len(bParts) && aParts[index] == bParts[index] {
+		index++
+	}
+	return strings.Join(aParts[:index], "/")
+}
@@ -0,0 +1,103 @@
+package accelerate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sutilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+// composite chains several accelerators. Accelerate names of each accelerator are namespaced by
+// its type in local store, such as `mount-tos/bucket`, and each accelerator only sees its own names.
+type composite struct {
+	children []*compositeChild
+}
+
+type compositeChild struct {
+	accelerateType string
+	accelerator    Accelerator
+}
+
+var _ Accelerator = (*composite)(nil)
+
+// CronCleanFunc returns nothing, cron functions of children are registered in RegisterCrontab
+func (c *composite) CronCleanFunc() (func(), *time.Duration) {
+	return nil, nil
+}
+
+// ModifySyncTask is executed by children in order, so inputs rewritten by former ones are skipped by latter ones
+func (c *composite) ModifySyncTask(ctx context.Context, taskFull *models.Task) (accelerateNames []string, err error) {
+	var errs []error
+	for _, child := range c.children {
+		names, err := child.accelerator.ModifySyncTask(ctx, taskFull)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("accelerator %s: %w", child.accelerateType, err))
+			continue
+		}
+		accelerateNames = append(accelerateNames, localstore.NamespacedAccelerateNames(child.accelerateType, names)...)
+	}
+	if err = k8sutilerrors.NewAggregate(errs); err != nil {
+		return nil, err
+	}
+	return accelerateNames, nil
+}
+
+// ModifyInputsFiler ...
+func (c *composite) ModifyInputsFiler(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	for _, child := range c.children {
+		child.accelerator.ModifyInputsFiler(podTemplate, child.localTask(localTask))
+	}
+}
+
+// ModifyExecutor ...
+func (c *composite) ModifyExecutor(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	for _, child := range c.children {
+		child.accelerator.ModifyExecutor(podTemplate, child.localTask(localTask))
+	}
+}
+
+// ModifyOutputsFiler ...
+func (c *composite) ModifyOutputsFiler(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	for _, child := range c.children {
+		child.accelerator.ModifyOutputsFiler(podTemplate, child.localTask(localTask))
+	}
+}
+
+// OnProcessTask ...
+func (c *composite) OnProcessTask(ctx context.Context, localTask *localstore.Task) (ctrl.Result, error) {
+	var errs []error
+	results := make([]ctrl.Result, 0, len(c.children))
+	for _, child := range c.children {
+		res, err := child.accelerator.OnProcessTask(ctx, child.localTask(localTask))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("accelerator %s: %w", child.accelerateType, err))
+			continue
+		}
+		results = append(results, res)
+	}
+	return utils.MergeCtrlResults(results...), k8sutilerrors.NewAggregate(errs)
+}
+
+// OnFinishTask ...
+func (c *composite) OnFinishTask(ctx context.Context, localTask *localstore.Task) error {
+	var errs []error
+	for _, child := range c.children {
+		if err := child.accelerator.OnFinishTask(ctx, child.localTask(localTask)); err != nil {
+			errs = append(errs, fmt.Errorf("accelerator %s: %w", child.accelerateType, err))
+		}
+	}
+	return k8sutilerrors.NewAggregate(errs)
+}
+
+func (c *compositeChild) localTask(localTask *localstore.Task) *localstore.Task {
+	taskCopy := *localTask
+	taskCopy.AccelerateNames = localTask.AccelerateNamesOf(c.accelerateType)
+	return &taskCopy
+}
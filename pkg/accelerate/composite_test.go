@@ -0,0 +1,88 @@
+package accelerate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+func TestComposite(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	fakeMount := fake.NewFakeAccelerator(mockCtrl)
+	fakePrePull := fake.NewFakeAccelerator(mockCtrl)
+	c := &composite{children: []*compositeChild{
+		{accelerateType: "mount", accelerator: fakeMount},
+		{accelerateType: "prepull", accelerator: fakePrePull},
+	}}
+
+	task := &models.Task{ID: "task-xxxx"}
+	fakeMount.EXPECT().ModifySyncTask(gomock.Any(), task).Return([]string{"bucket-a", "bucket-b"}, nil)
+	fakePrePull.EXPECT().ModifySyncTask(gomock.Any(), task).Return([]string{"image"}, nil)
+	names, err := c.ModifySyncTask(context.Background(), task)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(names).To(gomega.Equal([]string{"mount/bucket-a", "mount/bucket-b", "prepull/image"}))
+
+	localTask := &localstore.Task{ID: "task-xxxx", AccelerateNames: names}
+	podTemplate := &corev1.PodTemplateSpec{}
+	fakeMount.EXPECT().ModifyExecutor(podTemplate, &localstore.Task{ID: "task-xxxx", AccelerateNames: []string{"bucket-a", "bucket-b"}})
+	fakePrePull.EXPECT().ModifyExecutor(podTemplate, &localstore.Task{ID: "task-xxxx", AccelerateNames: []string{"image"}})
+	c.ModifyExecutor(podTemplate, localTask)
+
+	fakeMount.EXPECT().OnProcessTask(gomock.Any(), gomock.Any()).Return(ctrl.Result{RequeueAfter: time.Minute}, nil)
+	fakePrePull.EXPECT().OnProcessTask(gomock.Any(), gomock.Any()).Return(ctrl.Result{RequeueAfter: time.Second}, nil)
+	res, err := c.OnProcessTask(context.Background(), localTask)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(res).To(gomega.Equal(ctrl.Result{RequeueAfter: time.Second}))
+
+	fakeMount.EXPECT().OnFinishTask(gomock.Any(), gomock.Any()).Return(errors.New("mount error"))
+	fakePrePull.EXPECT().OnFinishTask(gomock.Any(), gomock.Any()).Return(errors.New("prepull error"))
+	err = c.OnFinishTask(context.Background(), localTask)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("mount error")))
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("prepull error")))
+}
+
+func TestCompositeCleanSkipsOtherAccelerators(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	fakeKubeClient := ctrlfake.NewClientBuilder().Build()
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(mockCtrl)
+	opts := NewOptions()
+	opts.Type = consts.CompositeAccelerateType
+	opts.Chain = []string{consts.MountTOSAccelerateType, consts.MountS3AccelerateType}
+	opts.MountS3.SecretName = "s3-secret"
+	accelerator, err := NewAccelerator(nil, fakeKubeClient, fakeLocalStoreHelper, "vetes", opts)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	c := accelerator.(*composite)
+
+	// the task using s3 volumes is finished, which makes them orphans for mount-tos
+	localTask := &localstore.Task{ID: "task-xxxx", AccelerateNames: []string{consts.MountS3AccelerateType + "/s3-bucket-a"}}
+	_, err = c.OnProcessTask(context.Background(), localTask)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{}, nil)
+
+	clean, _ := c.children[0].accelerator.CronCleanFunc()
+	clean()
+	pvs := &corev1.PersistentVolumeList{}
+	g.Expect(fakeKubeClient.List(context.Background(), pvs, ctrlclient.MatchingLabels{consts.LabelAccelerator: consts.MountS3AccelerateType})).To(gomega.Succeed())
+	g.Expect(pvs.Items).To(gomega.HaveLen(1))
+	g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Namespace: "vetes", Name: "s3-bucket-a"}, &corev1.PersistentVolumeClaim{})).To(gomega.Succeed())
+}
@@ -0,0 +1,397 @@
+package mounts3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sutilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	bucketutil "github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/bucket"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/podtemplate"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+// Impl mounts s3 buckets of inputs by a generic s3 CSI driver, and rewrites `s3://bucket/path`
+// to `<mountPathPrefix>/bucket/path`, just like mounttos, but independent of the TOS CSI driver.
+// Each bucket is mounted by a pvc named `s3-<bucket>` shared by tasks, bound to a pv named
+// `s3-<hash of namespace>-<bucket>`, because agents in other namespaces may mount the same bucket.
+type Impl struct {
+	kubeClient       ctrlclient.Client
+	localStoreHelper localstore.Helper
+	namespace        string
+	opts             *Options
+
+	// pvcLock serializes creating and deleting pv/pvc, references are derived from local store
+	pvcLock sync.Mutex
+}
+
+const (
+	pvcNamePrefix        = "s3-"
+	namespaceHashLength  = 10
+	deletingWaitDuration = time.Second * 5
+)
+
+var storageQuantity = resource.MustParse("20Gi")
+
+// New ...
+func New(kubeClient ctrlclient.Client, localStoreHelper localstore.Helper, namespace string, opts *Options) *Impl {
+	return &Impl{
+		kubeClient:       kubeClient,
+		localStoreHelper: localStoreHelper,
+		namespace:        namespace,
+		opts:             opts,
+	}
+}
+
+// Options ...
+func (i *Impl) Options() *Options {
+	return i.opts
+}
+
+// CronCleanFunc ...
+func (i *Impl) CronCleanFunc() (func(), *time.Duration) {
+	return nil, nil
+}
+
+// ModifySyncTask ...
+func (i *Impl) ModifySyncTask(_ context.Context, taskFull *models.Task) (accelerateNames []string, err error) {
+	buckets := bucketutil.ExtractBucketsAndSort(taskFull.Inputs)
+	if len(i.opts.Buckets) > 0 {
+		allowedSet := make(map[string]struct{}, len(i.opts.Buckets))
+		for _, bucket := range i.opts.Buckets {
+			allowedSet[bucket] = struct{}{}
+		}
+		allowed := make([]string, 0, len(buckets))
+		for _, bucket := range buckets {
+			if _, ok := allowedSet[bucket]; ok {
+				allowed = append(allowed, bucket)
+			}
+		}
+		buckets = allowed
+	}
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+	if len(buckets) > i.opts.BucketNumPerTask {
+		buckets = buckets[:i.opts.BucketNumPerTask]
+	}
+
+	bucketSet := make(map[string]struct{}, len(buckets))
+	pvcNames := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		bucketSet[bucket] = struct{}{}
+		pvcNames = append(pvcNames, pvcNameFromBucket(bucket))
+	}
+	bucketutil.RewriteInputURLs(taskFull.Inputs, bucketSet, i.mountPath)
+	return pvcNames, nil
+}
+
+// ModifyInputsFiler ...
+func (i *Impl) ModifyInputsFiler(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	i.mountPVC(podTemplate, localTask)
+	podtemplate.AddMountEnv(podTemplate, localTask)
+}
+
+// ModifyExecutor ...
+func (i *Impl) ModifyExecutor(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	i.mountPVC(podTemplate, localTask)
+}
+
+// ModifyOutputsFiler ...
+func (i *Impl) ModifyOutputsFiler(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	// inputs such as "script" may be uploaded by outputs-filer, so buckets are mounted as well
+	i.mountPVC(podTemplate, localTask)
+	podtemplate.AddMountEnv(podTemplate, localTask)
+}
+
+// OnProcessTask ...
+func (i *Impl) OnProcessTask(ctx context.Context, localTask *localstore.Task) (ctrl.Result, error) {
+	if len(localTask.AccelerateNames) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	i.pvcLock.Lock()
+	defer i.pvcLock.Unlock()
+
+	var errs []error
+	existDeleting := false
+	for _, pvcName := range localTask.AccelerateNames {
+		deleting, err := i.createPVAndPVC(ctx, pvcName)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if deleting {
+			existDeleting = true
+		}
+	}
+	if err := k8sutilerrors.NewAggregate(errs); err != nil {
+		return ctrl.Result{}, err
+	}
+	if existDeleting {
+		return ctrl.Result{RequeueAfter: deletingWaitDuration}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// OnFinishTask ...
+func (i *Impl) OnFinishTask(ctx context.Context, localTask *localstore.Task) error {
+	if len(localTask.AccelerateNames) == 0 {
+		return nil
+	}
+
+	i.pvcLock.Lock()
+	defer i.pvcLock.Unlock()
+
+	tasks, err := i.localStoreHelper.ListTasks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tasks in local store: %w", err)
+	}
+	referenced := make(map[string]struct{})
+	for _, task := range tasks {
+		if task.ID == localTask.ID {
+			continue
+		}
+		for _, pvcName := range task.AccelerateNames {
+			referenced[pvcName] = struct{}{}
+		}
+	}
+
+	var errs []error
+	for _, pvcName := range localTask.AccelerateNames {
+		if _, ok := referenced[pvcName]; ok {
+			continue
+		}
+		if err = i.deletePVAndPVC(ctx, pvcName); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return k8sutilerrors.NewAggregate(errs)
+}
+
+func (i *Impl) mountPath(bucket string) string {
+	return path.Join(i.opts.MountPathPrefix, bucket)
+}
+
+func (i *Impl) mountPVC(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	for _, pvcName := range localTask.AccelerateNames {
+		podTemplate.Spec.Volumes = append(podTemplate.Spec.Volumes, corev1.Volume{
+			Name: pvcName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: pvcName,
+					ReadOnly:  true,
+				},
+			},
+		})
+		for index := range podTemplate.Spec.Containers {
+			podTemplate.Spec.Containers[index].VolumeMounts = append(podTemplate.Spec.Containers[index].VolumeMounts, corev1.VolumeMount{
+				Name:      pvcName,
+				ReadOnly:  true,
+				MountPath: i.mountPath(bucketFromPVCName(pvcName)),
+			})
+		}
+	}
+}
+
+func (i *Impl) createPVAndPVC(ctx context.Context, pvcName string) (deleting bool, err error) {
+	gotPV := &corev1.PersistentVolume{}
+	pvExist := true
+	if err = i.kubeClient.Get(ctx, ctrlclient.ObjectKey{Name: i.pvName(pvcName)}, gotPV); err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to get pv: %w", err)
+		}
+		pvExist = false
+	}
+	if pvExist {
+		if err = i.checkPVClaim(gotPV, pvcName); err != nil {
+			return false, err
+		}
+		if !gotPV.DeletionTimestamp.IsZero() {
+			return true, nil
+		}
+	}
+
+	gotPVC := &corev1.PersistentVolumeClaim{}
+	pvcExist := true
+	if err = i.kubeClient.Get(ctx, ctrlclient.ObjectKey{Namespace: i.namespace, Name: pvcName}, gotPVC); err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to get pvc: %w", err)
+		}
+		pvcExist = false
+	}
+	if pvcExist && !gotPVC.DeletionTimestamp.IsZero() {
+		return true, nil
+	}
+
+	// create only if neither is deleting, otherwise a new pv may bind to the deleting pvc
+	if !pvExist {
+		pv, err := i.newPV(pvcName)
+		if err != nil {
+			return false, err
+		}
+		if err = i.kubeClient.Create(ctx, pv); err != nil {
+			if !k8sapierrors.IsAlreadyExists(err) {
+				return false, fmt.Errorf("failed to create pv: %w", err)
+			}
+			// created concurrently, which should be claimed by the same pvc
+			if err = i.kubeClient.Get(ctx, ctrlclient.ObjectKeyFromObject(pv), gotPV); err != nil {
+				return false, fmt.Errorf("failed to get pv: %w", err)
+			}
+			if err = i.checkPVClaim(gotPV, pvcName); err != nil {
+				return false, err
+			}
+		}
+	}
+	if !pvcExist {
+		if err = i.kubeClient.Create(ctx, i.newPVC(pvcName)); err != nil && !k8sapierrors.IsAlreadyExists(err) {
+			return false, fmt.Errorf("failed to create pvc: %w", err)
+		}
+	}
+	return false, nil
+}
+
+func (i *Impl) newPV(pvcName string) (*corev1.PersistentVolume, error) {
+	bucket := bucketFromPVCName(pvcName)
+	volumeAttributes, err := i.renderVolumeAttributes(pvcName, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: i.pvName(pvcName),
+			Labels: map[string]string{
+				consts.LabelBucketName:  bucket,
+				consts.LabelManagedBy:   consts.ManagedByVeTESK8SAgent,
+				consts.LabelAccelerator: consts.MountS3AccelerateType,
+			},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{
+					Driver:           i.opts.Driver,
+					VolumeAttributes: volumeAttributes,
+					// volume handle should be unique in cluster, pv of other agents may mount the same bucket
+					VolumeHandle: fmt.Sprintf("%s-%s", i.namespace, pvcName),
+				},
+			},
+			MountOptions:                  i.opts.MountOptions,
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+			VolumeMode:                    utils.Point(corev1.PersistentVolumeFilesystem),
+			Capacity: corev1.ResourceList{
+				corev1.ResourceStorage: storageQuantity,
+			},
+			ClaimRef: &corev1.ObjectReference{
+				Namespace: i.namespace,
+				Name:      pvcName,
+			},
+		},
+	}
+	if i.opts.SecretName != "" {
+		secretRef := &corev1.SecretReference{Name: i.opts.SecretName, Namespace: i.namespace}
+		pv.Spec.CSI.NodePublishSecretRef = secretRef
+		pv.Spec.CSI.NodeStageSecretRef = secretRef
+	}
+	return pv, nil
+}
+
+func (i *Impl) renderVolumeAttributes(pvcName, bucket string) (map[string]string, error) {
+	data := map[string]string{
+		"Bucket":    bucket,
+		"PVCName":   pvcName,
+		"Namespace": i.namespace,
+	}
+	res := make(map[string]string, len(i.opts.VolumeAttributes))
+	for key, value := range i.opts.VolumeAttributes {
+		tmpl, err := parseTemplate(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse volume attribute template %s: %w", key, err)
+		}
+		var buf bytes.Buffer
+		if err = tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render volume attribute template %s: %w", key, err)
+		}
+		res[key] = buf.String()
+	}
+	return res, nil
+}
+
+func (i *Impl) newPVC(pvcName string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pvcName,
+			Namespace: i.namespace,
+			Labels: map[string]string{
+				consts.LabelBucketName:  bucketFromPVCName(pvcName),
+				consts.LabelManagedBy:   consts.ManagedByVeTESK8SAgent,
+				consts.LabelAccelerator: consts.MountS3AccelerateType,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: storageQuantity,
+				},
+			},
+			StorageClassName: utils.Point(""),
+			VolumeMode:       utils.Point(corev1.PersistentVolumeFilesystem),
+			VolumeName:       i.pvName(pvcName),
+		},
+	}
+}
+
+func (i *Impl) deletePVAndPVC(ctx context.Context, pvcName string) error {
+	if err := i.kubeClient.Delete(ctx, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name: pvcName, Namespace: i.namespace,
+	}}, ctrlclient.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8sapierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete pvc: %w", err)
+	}
+	if err := i.kubeClient.Delete(ctx, &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{
+		Name: i.pvName(pvcName),
+	}}, ctrlclient.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8sapierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete pv: %w", err)
+	}
+	return nil
+}
+
+// pvName is unique in cluster, while pvc names of the same bucket are the same in all namespaces
+func (i *Impl) pvName(pvcName string) string {
+	sum := sha256.Sum256([]byte(i.namespace))
+	return fmt.Sprintf("%s%s-%s", pvcNamePrefix, hex.EncodeToString(sum[:])[:namespaceHashLength], bucketFromPVCName(pvcName))
+}
+
+// checkPVClaim makes sure the existing pv is claimed by pvc of this agent, otherwise the pvc never binds
+func (i *Impl) checkPVClaim(pv *corev1.PersistentVolume, pvcName string) error {
+	claimRef := pv.Spec.ClaimRef
+	if claimRef == nil || claimRef.Namespace != i.namespace || claimRef.Name != pvcName {
+		return fmt.Errorf("pv %s is not claimed by pvc %s/%s", pv.Name, i.namespace, pvcName)
+	}
+	return nil
+}
+
+func pvcNameFromBucket(bucket string) string {
+	return pvcNamePrefix + bucket
+}
+
+func bucketFromPVCName(pvcName string) string {
+	return strings.TrimPrefix(pvcName, pvcNamePrefix)
+}
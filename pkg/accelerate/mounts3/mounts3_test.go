@@ -0,0 +1,137 @@
+package mounts3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+const fakeNamespace = "vetes"
+
+func TestModifySyncTask(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	opts := NewOptions()
+	opts.Buckets = []string{"bucket-a", "bucket-b"}
+	i := New(nil, nil, fakeNamespace, opts)
+
+	task := &models.Task{Inputs: []*models.Input{
+		{URL: "s3://bucket-a/path/to/a"},
+		{URL: "s3://bucket-a/path/to/b"},
+		{URL: "s3://bucket-c/path/to/c"},
+		{URL: "http://example.com/d"},
+	}}
+	names, err := i.ModifySyncTask(context.Background(), task)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(names).To(gomega.Equal([]string{"s3-bucket-a"}))
+	g.Expect(task.Inputs[0].URL).To(gomega.Equal("/s3-data/bucket-a/path/to/a"))
+	g.Expect(task.Inputs[1].URL).To(gomega.Equal("/s3-data/bucket-a/path/to/b"))
+	g.Expect(task.Inputs[2].URL).To(gomega.Equal("s3://bucket-c/path/to/c"))
+	g.Expect(task.Inputs[3].URL).To(gomega.Equal("http://example.com/d"))
+}
+
+func TestProcessAndFinishTask(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	opts := NewOptions()
+	opts.Driver = "ru.yandex.s3.csi"
+	opts.VolumeAttributes = map[string]string{
+		"mounter": "geesefs",
+		"bucket":  "{{ .Bucket }}",
+		"options": "--memory-limit 1000 --dir-mode 0777 --file-mode 0666 -o {{ .Namespace }}",
+	}
+	opts.SecretName = "s3-secret"
+	fakeKubeClient := ctrlfake.NewClientBuilder().Build()
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	i := New(fakeKubeClient, fakeLocalStoreHelper, fakeNamespace, opts)
+
+	localTask := &localstore.Task{ID: "task-01", AccelerateNames: []string{"s3-bucket-a"}}
+	res, err := i.OnProcessTask(context.Background(), localTask)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(res.IsZero()).To(gomega.BeTrue())
+
+	pv := &corev1.PersistentVolume{}
+	g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Name: i.pvName("s3-bucket-a")}, pv)).To(gomega.Succeed())
+	g.Expect(pv.Name).To(gomega.HavePrefix("s3-"))
+	g.Expect(pv.Name).To(gomega.HaveSuffix("-bucket-a"))
+	g.Expect(pv.Spec.CSI.Driver).To(gomega.Equal("ru.yandex.s3.csi"))
+	g.Expect(pv.Spec.CSI.VolumeHandle).To(gomega.Equal("vetes-s3-bucket-a"))
+	g.Expect(pv.Spec.CSI.VolumeAttributes).To(gomega.Equal(map[string]string{
+		"mounter": "geesefs",
+		"bucket":  "bucket-a",
+		"options": "--memory-limit 1000 --dir-mode 0777 --file-mode 0666 -o vetes",
+	}))
+	g.Expect(pv.Spec.CSI.NodePublishSecretRef).To(gomega.Equal(&corev1.SecretReference{Name: "s3-secret", Namespace: fakeNamespace}))
+	g.Expect(pv.Labels).To(gomega.HaveKeyWithValue(consts.LabelAccelerator, consts.MountS3AccelerateType))
+	pvc := &corev1.PersistentVolumeClaim{}
+	g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Namespace: fakeNamespace, Name: "s3-bucket-a"}, pvc)).To(gomega.Succeed())
+	g.Expect(pvc.Spec.VolumeName).To(gomega.Equal(pv.Name))
+	g.Expect(pvc.Labels).To(gomega.HaveKeyWithValue(consts.LabelAccelerator, consts.MountS3AccelerateType))
+
+	podTemplate := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "executor"}}}}
+	i.ModifyExecutor(podTemplate, localTask)
+	g.Expect(podTemplate.Spec.Volumes).To(gomega.HaveLen(1))
+	g.Expect(podTemplate.Spec.Containers[0].VolumeMounts).To(gomega.Equal([]corev1.VolumeMount{{
+		Name: "s3-bucket-a", ReadOnly: true, MountPath: "/s3-data/bucket-a",
+	}}))
+
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{{Task: *localTask}}, nil)
+	g.Expect(i.OnFinishTask(context.Background(), localTask)).To(gomega.Succeed())
+	err = fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Name: pv.Name}, &corev1.PersistentVolume{})
+	g.Expect(k8sapierrors.IsNotFound(err)).To(gomega.BeTrue())
+}
+
+func TestProcessTaskInTwoNamespaces(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	fakeKubeClient := ctrlfake.NewClientBuilder().Build()
+	localTask := &localstore.Task{ID: "task-01", AccelerateNames: []string{"s3-bucket-a"}}
+	impls := []*Impl{
+		New(fakeKubeClient, nil, fakeNamespace, NewOptions()),
+		New(fakeKubeClient, nil, "vetes-other", NewOptions()),
+	}
+	for _, i := range impls {
+		_, err := i.OnProcessTask(context.Background(), localTask)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+	g.Expect(impls[0].pvName("s3-bucket-a")).NotTo(gomega.Equal(impls[1].pvName("s3-bucket-a")))
+
+	for _, i := range impls {
+		pv := &corev1.PersistentVolume{}
+		g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Name: i.pvName("s3-bucket-a")}, pv)).To(gomega.Succeed())
+		g.Expect(pv.Spec.ClaimRef.Namespace).To(gomega.Equal(i.namespace))
+		pvc := &corev1.PersistentVolumeClaim{}
+		g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Namespace: i.namespace, Name: "s3-bucket-a"}, pvc)).To(gomega.Succeed())
+		g.Expect(pvc.Spec.VolumeName).To(gomega.Equal(pv.Name))
+	}
+
+	// pv claimed by another pvc is not reused
+	pv := &corev1.PersistentVolume{}
+	g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Name: impls[0].pvName("s3-bucket-a")}, pv)).To(gomega.Succeed())
+	pv.Spec.ClaimRef.Namespace = "vetes-other"
+	g.Expect(fakeKubeClient.Update(context.Background(), pv)).To(gomega.Succeed())
+	_, err := impls[0].OnProcessTask(context.Background(), localTask)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestValidate(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	opts := NewOptions()
+	g.Expect(opts.Validate()).To(gomega.Succeed())
+	opts.VolumeAttributes = map[string]string{"bucketName": "{{ .Bucket "}
+	g.Expect(opts.Validate()).NotTo(gomega.Succeed())
+}
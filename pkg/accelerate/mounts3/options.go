@@ -0,0 +1,70 @@
+package mounts3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/pflag"
+)
+
+// Options ...
+type Options struct {
+	// Driver is the CSI driver name, such as s3.csi.aws.com (mountpoint-s3), ru.yandex.s3.csi (geesefs/s3fs)
+	Driver string `mapstructure:"driver"`
+	// VolumeAttributes are templates of CSI volume attributes, rendered with .Bucket, .PVCName and .Namespace
+	VolumeAttributes map[string]string `mapstructure:"volumeAttributes"`
+	// MountOptions are mount options of pv, mountpoint-s3 reads them
+	MountOptions []string `mapstructure:"mountOptions"`
+	// SecretName is the secret for CSI node publish and node stage, in namespace of agent
+	SecretName       string `mapstructure:"secretName"`
+	MountPathPrefix  string `mapstructure:"mountPathPrefix"`
+	BucketNumPerTask int    `mapstructure:"bucketNumPerTask"`
+	// Buckets are buckets allowed to mount, empty means all buckets
+	Buckets []string `mapstructure:"buckets"`
+}
+
+// NewOptions ...
+func NewOptions() *Options {
+	return &Options{
+		Driver:           "s3.csi.aws.com",
+		VolumeAttributes: map[string]string{"bucketName": "{{ .Bucket }}"},
+		MountPathPrefix:  "/s3-data",
+		BucketNumPerTask: 10,
+	}
+}
+
+// Validate ...
+func (o *Options) Validate() error {
+	if o.Driver == "" {
+		return errors.New("empty mountS3 csi driver")
+	}
+	for key, value := range o.VolumeAttributes {
+		if _, err := parseTemplate(key, value); err != nil {
+			return fmt.Errorf("invalid mountS3 volume attribute template %s: %w", key, err)
+		}
+	}
+	if !strings.HasPrefix(o.MountPathPrefix, "/") {
+		return fmt.Errorf("mountS3 mount path prefix %s should be absolute", o.MountPathPrefix)
+	}
+	if o.BucketNumPerTask <= 0 {
+		return fmt.Errorf("bucketNumPerTask %d must be greater than 0", o.BucketNumPerTask)
+	}
+	return nil
+}
+
+// AddFlags ...
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Driver, "mount-s3-driver", o.Driver, "csi driver to mount s3 buckets")
+	fs.StringToStringVar(&o.VolumeAttributes, "mount-s3-volume-attributes", o.VolumeAttributes, "templates of csi volume attributes, rendered with .Bucket, .PVCName and .Namespace")
+	fs.StringSliceVar(&o.MountOptions, "mount-s3-mount-options", o.MountOptions, "mount options of pv")
+	fs.StringVar(&o.SecretName, "mount-s3-secret-name", o.SecretName, "secret for csi node publish and node stage")
+	fs.StringVar(&o.MountPathPrefix, "mount-s3-mount-path-prefix", o.MountPathPrefix, "path prefix to mount s3 buckets")
+	fs.IntVar(&o.BucketNumPerTask, "mount-s3-bucket-num-per-task", o.BucketNumPerTask, "the number of mounting bucket per task")
+	fs.StringSliceVar(&o.Buckets, "mount-s3-buckets", o.Buckets, "buckets allowed to mount, empty means all buckets")
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
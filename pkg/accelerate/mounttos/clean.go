@@ -0,0 +1,129 @@
+package mounttos
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+)
+
+// clean deletes pv/pvc/secret which are not referenced by any task in local store.
+// Tasks are processed and cleaned in the leader only, so holding pvcLock here makes
+// OnProcessTask of a new task either be referenced, or see the pvc deleting and wait for recreating.
+func (i *Impl) clean() {
+	ctx := context.Background()
+
+	i.pvcLock.Lock()
+	defer i.pvcLock.Unlock()
+
+	referencedPVCs, referencedSecrets, err := i.references(ctx, "")
+	if err != nil {
+		log.Errorw("failed to clean mount-tos resources", "err", err)
+		return
+	}
+
+	opts := i.options()
+	expiredFunc := func(referenced map[string]struct{}) func(obj metav1.Object) bool {
+		return func(obj metav1.Object) bool {
+			_, ok := referenced[obj.GetName()]
+			return !ok && obj.GetDeletionTimestamp().IsZero() && time.Since(obj.GetCreationTimestamp().Time) > opts.CleanGracePeriod
+		}
+	}
+
+	pvcNames, err := i.listOrphanPVCNames(ctx, expiredFunc(referencedPVCs))
+	if err != nil {
+		log.Errorw("failed to list orphan mount-tos pv/pvc", "err", err)
+		return
+	}
+	for _, pvcName := range pvcNames {
+		if opts.CleanDryRun {
+			log.Infow("[dry-run] delete orphan mount-tos pv/pvc", "pvc", pvcName)
+			continue
+		}
+		log.Infow("delete orphan mount-tos pv/pvc", "pvc", pvcName)
+		if err = i.deletePVAndPVC(ctx, pvcName); err != nil {
+			log.Errorw("failed to delete orphan mount-tos pv/pvc", "pvc", pvcName, "err", err)
+		}
+	}
+
+	secretNames, err := i.listOrphanSecretNames(ctx, expiredFunc(referencedSecrets))
+	if err != nil {
+		log.Errorw("failed to list orphan mount-tos secrets", "err", err)
+		return
+	}
+	for _, secretName := range secretNames {
+		if opts.CleanDryRun {
+			log.Infow("[dry-run] delete orphan mount-tos secret", "secret", secretName)
+			continue
+		}
+		log.Infow("delete orphan mount-tos secret", "secret", secretName)
+		if err = i.deleteKubeSecret(ctx, i.namespace, secretName); err != nil {
+			log.Errorw("failed to delete orphan mount-tos secret", "secret", secretName, "err", err)
+		}
+	}
+}
+
+// listOrphanPVCNames returns names of pvc whose pv and pvc are both expired or not exist
+func (i *Impl) listOrphanPVCNames(ctx context.Context, expired func(obj metav1.Object) bool) ([]string, error) {
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := i.kubeClient.List(ctx, pvcs, ctrlclient.InNamespace(i.namespace), ctrlclient.HasLabels{consts.LabelBucketName}); err != nil {
+		return nil, fmt.Errorf("failed to list pvc: %w", err)
+	}
+	pvs := &corev1.PersistentVolumeList{}
+	if err := i.kubeClient.List(ctx, pvs, ctrlclient.HasLabels{consts.LabelBucketName}); err != nil {
+		return nil, fmt.Errorf("failed to list pv: %w", err)
+	}
+
+	orphans := make(map[string]bool) // pvcName -> expired
+	for index := range pvcs.Items {
+		if !ownedByMountTOS(&pvcs.Items[index]) {
+			continue
+		}
+		orphans[pvcs.Items[index].Name] = expired(&pvcs.Items[index])
+	}
+	for index := range pvs.Items {
+		pv := &pvs.Items[index]
+		// pv is cluster scoped, skip pv of agents in other namespaces
+		if !ownedByMountTOS(pv) || pv.Spec.CSI == nil || pv.Spec.CSI.NodePublishSecretRef == nil || pv.Spec.CSI.NodePublishSecretRef.Namespace != i.namespace {
+			continue
+		}
+		pvcExpired, ok := orphans[pv.Name]
+		orphans[pv.Name] = expired(pv) && (!ok || pvcExpired)
+	}
+
+	res := make([]string, 0)
+	for pvcName, isOrphan := range orphans {
+		if isOrphan {
+			res = append(res, pvcName)
+		}
+	}
+	return res, nil
+}
+
+func (i *Impl) listOrphanSecretNames(ctx context.Context, expired func(obj metav1.Object) bool) ([]string, error) {
+	secrets := &corev1.SecretList{}
+	if err := i.kubeClient.List(ctx, secrets, ctrlclient.InNamespace(i.namespace),
+		ctrlclient.MatchingLabels{consts.LabelManagedBy: consts.ManagedByVeTESK8SAgent}); err != nil {
+		return nil, fmt.Errorf("failed to list secret: %w", err)
+	}
+	res := make([]string, 0)
+	for index := range secrets.Items {
+		if ownedByMountTOS(&secrets.Items[index]) && expired(&secrets.Items[index]) {
+			res = append(res, secrets.Items[index].Name)
+		}
+	}
+	return res, nil
+}
+
+// ownedByMountTOS skips resources of other accelerators in composite mode, resources created by
+// older versions are not labeled with accelerator
+func ownedByMountTOS(obj metav1.Object) bool {
+	accelerator, ok := obj.GetLabels()[consts.LabelAccelerator]
+	return !ok || accelerator == consts.MountTOSAccelerateType
+}
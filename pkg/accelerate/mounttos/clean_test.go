@@ -0,0 +1,112 @@
+package mounttos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+)
+
+const fakeNamespace = "vetes"
+
+func fakePVAndPVC(accelerateName, namespace string, creationTime time.Time) []ctrlclient.Object {
+	i := &Impl{namespace: namespace, opts: NewOptions()}
+	target := parseAccelerateName(accelerateName)
+	pv := i.newPV(target, "secret", i.opts.FusePodResources, "")
+	pv.CreationTimestamp = metav1.NewTime(creationTime)
+	pvc := i.newPVC(target)
+	pvc.CreationTimestamp = metav1.NewTime(creationTime)
+	return []ctrlclient.Object{pv, pvc}
+}
+
+func fakeSecret(name string, creationTime time.Time) *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace:         fakeNamespace,
+		Name:              name,
+		Labels:            map[string]string{consts.LabelManagedBy: consts.ManagedByVeTESK8SAgent},
+		CreationTimestamp: metav1.NewTime(creationTime),
+	}}
+}
+
+func TestClean(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	old := time.Now().Add(-time.Hour)
+	// external bucket mounted with a prefix, secret is named by submission and bucket
+	externalTarget := newMountTarget("sub-referenced", "referenced", "path/to")
+	objs := make([]ctrlclient.Object, 0)
+	objs = append(objs, fakePVAndPVC("workflow-referenced", fakeNamespace, old)...)
+	objs = append(objs, fakePVAndPVC(externalTarget.accelerateName(), fakeNamespace, old)...)
+	objs = append(objs, fakePVAndPVC("workflow-stopped", fakeNamespace, old)...)
+	objs = append(objs, fakePVAndPVC("workflow-orphan", fakeNamespace, old)...)
+	objs = append(objs, fakePVAndPVC("workflow-new", fakeNamespace, time.Now())...)
+	objs = append(objs, fakePVAndPVC("workflow-other", "other-namespace", old)[0])
+	objs = append(objs, fakeSecret("sub-referenced", old), fakeSecret("sub-orphan", old))
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(objs...).Build()
+
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{{
+		Task: localstore.Task{
+			ID:              "task-01",
+			BioosInfo:       &localstore.BioosInfo{SubmissionID: "sub"},
+			AccelerateNames: []string{"workflow-referenced", externalTarget.accelerateName()},
+		},
+	}, {
+		Task: localstore.Task{
+			ID:              "task-02",
+			AccelerateNames: []string{"workflow-stopped"},
+		},
+		Stop: utils.Point(consts.TaskCanceled),
+	}}, nil).Times(2)
+
+	i := New(nil, fakeKubeClient, fakeLocalStoreHelper, fakeNamespace, NewOptions())
+
+	listNames := func() []string {
+		res := make([]string, 0)
+		pvs := &corev1.PersistentVolumeList{}
+		g.Expect(fakeKubeClient.List(context.Background(), pvs)).To(gomega.Succeed())
+		for _, pv := range pvs.Items {
+			res = append(res, "pv/"+pv.Name)
+		}
+		pvcs := &corev1.PersistentVolumeClaimList{}
+		g.Expect(fakeKubeClient.List(context.Background(), pvcs)).To(gomega.Succeed())
+		for _, pvc := range pvcs.Items {
+			res = append(res, "pvc/"+pvc.Name)
+		}
+		secrets := &corev1.SecretList{}
+		g.Expect(fakeKubeClient.List(context.Background(), secrets)).To(gomega.Succeed())
+		for _, secret := range secrets.Items {
+			res = append(res, "secret/"+secret.Name)
+		}
+		return res
+	}
+	all := listNames()
+
+	i.opts.CleanDryRun = true
+	i.clean()
+	g.Expect(listNames()).To(gomega.ConsistOf(all))
+
+	i.opts.CleanDryRun = false
+	i.clean()
+	g.Expect(listNames()).To(gomega.ConsistOf(
+		"pv/workflow-referenced", "pvc/workflow-referenced",
+		"pv/"+externalTarget.pvcName, "pvc/"+externalTarget.pvcName,
+		"pv/workflow-stopped", "pvc/workflow-stopped",
+		"pv/workflow-new", "pvc/workflow-new",
+		"pv/workflow-other",
+		"secret/sub-referenced",
+	))
+}
@@ -3,8 +3,6 @@ package mounttos
 import (
 	"context"
 	"fmt"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -13,7 +11,8 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
-	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	bucketutil "github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/bucket"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/podtemplate"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
@@ -37,27 +36,51 @@ import (
 
 // Impl ...
 type Impl struct {
-	vetesClient vetesclient.Client
-	kubeClient  ctrlclient.Client
-	namespace   string
+	vetesClient      vetesclient.Client
+	kubeClient       ctrlclient.Client
+	localStoreHelper localstore.Helper
+	namespace        string
 
-	opts *Options
+	optsLock sync.RWMutex
+	opts     *Options
 
-	pvcWithTasksCacheLock sync.Mutex
-	pvcWithTasksCache     map[string]map[string]struct{} // pvcName -> taskID set
+	// pvcLock serializes creating and deleting pv/pvc/secret. References of them are derived from
+	// AccelerateNames of tasks in local store, so that they are still correct after restart.
+	pvcLock sync.Mutex
 }
 
 // New ...
-func New(vetesClient vetesclient.Client, kubeClient ctrlclient.Client, namespace string, opts *Options) *Impl {
+func New(vetesClient vetesclient.Client, kubeClient ctrlclient.Client, localStoreHelper localstore.Helper, namespace string, opts *Options) *Impl {
 	return &Impl{
-		vetesClient:       vetesClient,
-		kubeClient:        kubeClient,
-		namespace:         namespace,
-		opts:              opts,
-		pvcWithTasksCache: make(map[string]map[string]struct{}),
+		vetesClient:      vetesClient,
+		kubeClient:       kubeClient,
+		localStoreHelper: localStoreHelper,
+		namespace:        namespace,
+		opts:             opts,
 	}
 }
 
+func (i *Impl) options() *Options {
+	i.optsLock.RLock()
+	defer i.optsLock.RUnlock()
+	return i.opts
+}
+
+// UpdateOptions applies the reloadable subset of opts, which only affects pvs created later.
+// It returns whether some changed options require restart to take effect.
+func (i *Impl) UpdateOptions(opts *Options) (needRestart bool) {
+	i.optsLock.Lock()
+	defer i.optsLock.Unlock()
+	res := *i.opts
+	res.BucketNumPerTask = opts.BucketNumPerTask
+	res.FusePodResources = opts.FusePodResources
+	res.AdditionalArgs = opts.AdditionalArgs
+	res.CleanGracePeriod = opts.CleanGracePeriod
+	res.CleanDryRun = opts.CleanDryRun
+	i.opts = &res
+	return res != *opts
+}
+
 // ModifySyncTask ...
 func (i *Impl) ModifySyncTask(ctx context.Context, taskFull *models.Task) (accelerateNames []string, retErr error) {
 	if taskFull.BioosInfo == nil || taskFull.BioosInfo.Meta == nil ||
@@ -80,37 +103,30 @@ func (i *Impl) ModifySyncTask(ctx context.Context, taskFull *models.Task) (accel
 		return nil, nil
 	}
 
+	// only the longest common prefix of inputs is mounted, so that the task can not see other data in the bucket
+	prefixes := bucketutil.CommonPrefixes(taskFull.Inputs)
 	bucketSet := make(map[string]struct{}, len(buckets))
-	pvcNames := make([]string, 0, len(buckets))
+	names := make([]string, 0, len(buckets))
 	for _, bucket := range buckets {
+		baseName := pvcNameFromBucket(bucket)
 		if _, ok := externalBucketSet[bucket]; ok {
-			pvcNames = append(pvcNames, pvcNameFromExternalBucket(taskFull.BioosInfo.SubmissionID, bucket))
-		} else {
-			pvcNames = append(pvcNames, pvcNameFromBucket(bucket))
+			baseName = pvcNameFromExternalBucket(taskFull.BioosInfo.SubmissionID, bucket)
 		}
+		names = append(names, newMountTarget(baseName, bucket, prefixes[bucket]).accelerateName())
 		bucketSet[bucket] = struct{}{}
 	}
-	for _, input := range taskFull.Inputs {
-		bucket := extractBucket(input.URL)
-		if bucket == "" {
-			continue
-		}
-		if _, ok := bucketSet[bucket]; !ok {
-			continue
-		}
-		input.URL = strings.Replace(input.URL, fmt.Sprintf("s3://%s", bucket), mountTOSPath(bucket), 1)
-	}
+	bucketutil.RewriteInputURLs(taskFull.Inputs, bucketSet, mountTOSPath)
 
-	return pvcNames, nil
+	return names, nil
 }
 
 func (i *Impl) getAccelerateBuckets(ctx context.Context, taskFull *models.Task, externalBucketSet map[string]struct{}) ([]string, error) {
-	buckets := extractBucketsAndSort(taskFull.Inputs)
+	buckets := bucketutil.ExtractBucketsAndSort(taskFull.Inputs)
 	if len(buckets) == 0 {
 		return nil, nil
 	}
 
-	defaultSecretName := i.opts.StaticTOSSecret.Name
+	defaultSecretName := i.options().StaticTOSSecret.Name
 	// if no aksk config for shared cluster or no static tos secret for private cloud, defaultSupport is false,
 	// but there may be some external buckets can be mounted
 	var defaultSupportMountTOS bool = defaultSecretName != ""
@@ -125,8 +141,8 @@ func (i *Impl) getAccelerateBuckets(ctx context.Context, taskFull *models.Task,
 		}
 		buckets = externalBuckets
 	}
-	if len(buckets) > i.opts.BucketNumPerTask {
-		buckets = buckets[:i.opts.BucketNumPerTask]
+	if len(buckets) > i.options().BucketNumPerTask {
+		buckets = buckets[:i.options().BucketNumPerTask]
 	}
 	return buckets, nil
 }
@@ -134,7 +150,7 @@ func (i *Impl) getAccelerateBuckets(ctx context.Context, taskFull *models.Task,
 // ModifyInputsFiler ...
 func (i *Impl) ModifyInputsFiler(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
 	i.mountPVC(podTemplate, localTask)
-	i.addMountTOSEnv(podTemplate, localTask)
+	podtemplate.AddMountEnv(podTemplate, localTask)
 }
 
 // ModifyExecutor ...
@@ -147,7 +163,7 @@ func (i *Impl) ModifyOutputsFiler(podTemplate *corev1.PodTemplateSpec, localTask
 	// although we don't need to upload file by mount tos, but sometimes we have to upload file from input,
 	// such as "script", so we have to mount tos on outputs-filer
 	i.mountPVC(podTemplate, localTask)
-	i.addMountTOSEnv(podTemplate, localTask)
+	podtemplate.AddMountEnv(podTemplate, localTask)
 }
 
 // OnProcessTask ...
@@ -155,12 +171,11 @@ func (i *Impl) OnProcessTask(ctx context.Context, localTask *localstore.Task) (c
 	if localTask.BioosInfo == nil {
 		return ctrl.Result{}, nil
 	}
-	pvcNames := localTask.AccelerateNames
-	if len(pvcNames) == 0 {
+	if len(localTask.AccelerateNames) == 0 {
 		return ctrl.Result{}, nil
 	}
 
-	defaultSecretName := i.opts.StaticTOSSecret.Name
+	defaultSecretName := i.options().StaticTOSSecret.Name
 
 	externalBucketAuthInfo := make(map[string][]string) // bucket -> [ak, sk]
 	if localTask.BioosInfo.Meta != nil && localTask.BioosInfo.Meta.BucketsAuthInfo != nil && len(localTask.BioosInfo.Meta.BucketsAuthInfo.External) > 0 {
@@ -169,20 +184,15 @@ func (i *Impl) OnProcessTask(ctx context.Context, localTask *localstore.Task) (c
 		}
 	}
 
-	i.pvcWithTasksCacheLock.Lock()
-	defer i.pvcWithTasksCacheLock.Unlock()
+	i.pvcLock.Lock()
+	defer i.pvcLock.Unlock()
 
 	var errs []error
 	existDeleting := false
-	for _, pvcName := range pvcNames {
-		if _, ok := i.pvcWithTasksCache[pvcName]; ok {
-			i.pvcWithTasksCache[pvcName][localTask.ID] = struct{}{}
-			continue
-		}
-		bucket := parseBucketFromPVCName(pvcName)
+	for _, target := range parseAccelerateNames(localTask.AccelerateNames) {
 		secretName := defaultSecretName
-		if authInfo, ok := externalBucketAuthInfo[bucket]; ok {
-			secretName = externalBucketTOSSecretName(localTask.BioosInfo.SubmissionID, bucket)
+		if authInfo, ok := externalBucketAuthInfo[target.bucket]; ok {
+			secretName = externalBucketTOSSecretName(localTask.BioosInfo.SubmissionID, target.bucket)
 			deleting, err := i.storeKubeSecret(ctx, secretName, authInfo[0], authInfo[1])
 			if err != nil {
 				errs = append(errs, err)
@@ -193,17 +203,14 @@ func (i *Impl) OnProcessTask(ctx context.Context, localTask *localstore.Task) (c
 				continue
 			}
 		}
-		deleting, err := i.createPVAndPVC(ctx, pvcName, secretName, i.opts.FusePodResources, i.opts.AdditionalArgs)
+		deleting, err := i.createPVAndPVC(ctx, target, secretName, i.options().FusePodResources, i.options().AdditionalArgs)
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
 		if deleting {
 			existDeleting = true
-			continue
 		}
-		i.pvcWithTasksCache[pvcName] = make(map[string]struct{})
-		i.pvcWithTasksCache[pvcName][localTask.ID] = struct{}{}
 	}
 
 	reterr := k8sutilerrors.NewAggregate(errs)
@@ -221,8 +228,7 @@ func (i *Impl) OnFinishTask(ctx context.Context, localTask *localstore.Task) err
 	if localTask.BioosInfo == nil {
 		return nil
 	}
-	pvcNames := localTask.AccelerateNames
-	if len(pvcNames) == 0 {
+	if len(localTask.AccelerateNames) == 0 {
 		return nil
 	}
 
@@ -233,75 +239,89 @@ func (i *Impl) OnFinishTask(ctx context.Context, localTask *localstore.Task) err
 		}
 	}
 
-	i.pvcWithTasksCacheLock.Lock()
-	defer i.pvcWithTasksCacheLock.Unlock()
+	i.pvcLock.Lock()
+	defer i.pvcLock.Unlock()
+
+	referencedPVCs, referencedSecrets, err := i.references(ctx, localTask.ID)
+	if err != nil {
+		return err
+	}
 
 	var errs []error
-	for _, pvcName := range pvcNames {
-		if _, ok := i.pvcWithTasksCache[pvcName]; ok {
-			delete(i.pvcWithTasksCache[pvcName], localTask.ID)
-			if len(i.pvcWithTasksCache[pvcName]) > 0 {
-				continue
-			}
+	for _, target := range parseAccelerateNames(localTask.AccelerateNames) {
+		if _, ok := referencedPVCs[target.pvcName]; ok {
+			continue
 		}
-		if err := i.deletePVAndPVC(ctx, pvcName); err != nil {
+		if err := i.deletePVAndPVC(ctx, target.pvcName); err != nil {
 			errs = append(errs, err)
 			continue
 		}
-		bucket := parseBucketFromPVCName(pvcName)
-		if _, ok := externalBucketSet[bucket]; ok {
-			secretName := externalBucketTOSSecretName(localTask.BioosInfo.SubmissionID, bucket)
-			if err := i.deleteKubeSecret(ctx, i.namespace, secretName); err != nil {
-				errs = append(errs, err)
-				continue
-			}
+		if _, ok := externalBucketSet[target.bucket]; !ok {
+			continue
+		}
+		// other prefixes of the bucket in the same submission may still use the secret
+		secretName := externalBucketTOSSecretName(localTask.BioosInfo.SubmissionID, target.bucket)
+		if _, ok := referencedSecrets[secretName]; ok {
+			continue
+		}
+		if err := i.deleteKubeSecret(ctx, i.namespace, secretName); err != nil {
+			errs = append(errs, err)
 		}
-		delete(i.pvcWithTasksCache, pvcName)
 	}
 
 	return k8sutilerrors.NewAggregate(errs)
 }
 
+// references returns names of pvc and external bucket secrets referenced by tasks in local store except excludeTaskID
+func (i *Impl) references(ctx context.Context, excludeTaskID string) (pvcNames, secretNames map[string]struct{}, err error) {
+	tasks, err := i.localStoreHelper.ListTasks(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tasks in local store: %w", err)
+	}
+	pvcNames = make(map[string]struct{})
+	secretNames = make(map[string]struct{})
+	for _, task := range tasks {
+		if task.ID == excludeTaskID {
+			continue
+		}
+		for _, target := range parseAccelerateNames(task.AccelerateNames) {
+			pvcNames[target.pvcName] = struct{}{}
+			if task.BioosInfo != nil {
+				secretNames[externalBucketTOSSecretName(task.BioosInfo.SubmissionID, target.bucket)] = struct{}{}
+			}
+		}
+	}
+	return pvcNames, secretNames, nil
+}
+
 func (i *Impl) mountPVC(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
 	if len(localTask.AccelerateNames) == 0 {
 		return
 	}
 
-	for _, pvcName := range localTask.AccelerateNames {
+	for _, target := range parseAccelerateNames(localTask.AccelerateNames) {
 		podTemplate.Spec.Volumes = append(podTemplate.Spec.Volumes, corev1.Volume{
-			Name: pvcName,
+			Name: target.pvcName,
 			VolumeSource: corev1.VolumeSource{
 				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: pvcName,
+					ClaimName: target.pvcName,
 					ReadOnly:  true,
 				},
 			},
 		})
 		for i := range podTemplate.Spec.Containers {
 			podTemplate.Spec.Containers[i].VolumeMounts = append(podTemplate.Spec.Containers[i].VolumeMounts, corev1.VolumeMount{
-				Name:      pvcName,
+				Name:      target.pvcName,
 				ReadOnly:  true,
-				MountPath: mountTOSPath(parseBucketFromPVCName(pvcName)),
+				MountPath: target.mountPath(),
 			})
 		}
 	}
 }
 
-func (i *Impl) addMountTOSEnv(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
-	if len(localTask.AccelerateNames) == 0 {
-		return
-	}
-	for i := range podTemplate.Spec.Containers {
-		podTemplate.Spec.Containers[i].Env = append(podTemplate.Spec.Containers[i].Env, corev1.EnvVar{
-			Name:  consts.IsMountTOS,
-			Value: strconv.FormatBool(true),
-		})
-	}
-}
-
 // CronCleanFunc ...
 func (i *Impl) CronCleanFunc() (func(), *time.Duration) {
-	return nil, nil
+	return i.clean, &i.options().CleanPeriod
 }
 
 func mountTOSPath(bucket string) string {
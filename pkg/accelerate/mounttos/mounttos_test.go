@@ -0,0 +1,141 @@
+package mounttos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+func TestOnFinishTaskSharedPVC(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(fakePVAndPVC("workflow-bucket", fakeNamespace, time.Now())...).Build()
+	task01 := &localstore.TaskInfo{Task: localstore.Task{
+		ID:              "task-01",
+		BioosInfo:       &localstore.BioosInfo{SubmissionID: "sub"},
+		AccelerateNames: []string{"workflow-bucket"},
+	}}
+	task02 := &localstore.TaskInfo{Task: localstore.Task{
+		ID:              "task-02",
+		BioosInfo:       &localstore.BioosInfo{SubmissionID: "sub"},
+		AccelerateNames: []string{"workflow-bucket"},
+	}}
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	// task-02 is processed before restart, so it is only known from local store
+	i := New(nil, fakeKubeClient, fakeLocalStoreHelper, fakeNamespace, NewOptions())
+
+	pvcExist := func() bool {
+		err := fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Namespace: fakeNamespace, Name: "workflow-bucket"}, &corev1.PersistentVolumeClaim{})
+		if k8sapierrors.IsNotFound(err) {
+			return false
+		}
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		return true
+	}
+
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{task01, task02}, nil)
+	g.Expect(i.OnFinishTask(context.Background(), &task01.Task)).To(gomega.Succeed())
+	g.Expect(pvcExist()).To(gomega.BeTrue())
+
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{task02}, nil)
+	g.Expect(i.OnFinishTask(context.Background(), &task02.Task)).To(gomega.Succeed())
+	g.Expect(pvcExist()).To(gomega.BeFalse())
+}
+
+func TestModifySyncTaskMountPrefix(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	opts := NewOptions()
+	opts.StaticTOSSecret.Name = "secret"
+	i := New(nil, nil, nil, fakeNamespace, opts)
+
+	task := &models.Task{
+		BioosInfo: &models.BioosInfo{
+			SubmissionID: "sub",
+			Meta: &models.BioosInfoMeta{
+				MountTOS: utils.Point(true),
+				BucketsAuthInfo: &models.BucketsAuthInfo{
+					External: []*models.ExternalBucketAuthInfo{{Bucket: "external"}},
+				},
+			},
+		},
+		Inputs: []*models.Input{
+			{URL: "s3://bucket/sub/run-a/x.bam"},
+			{URL: "s3://bucket/sub/run-b/y.bam"},
+			{URL: "s3://external/z.txt"},
+		},
+	}
+	names, err := i.ModifySyncTask(context.Background(), task)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	prefixTarget := newMountTarget("workflow-bucket", "bucket", "sub")
+	g.Expect(prefixTarget.pvcName).To(gomega.HavePrefix("workflow-bucket-"))
+	g.Expect(names).To(gomega.ConsistOf(prefixTarget.accelerateName(), "sub-external"))
+	g.Expect(task.Inputs[0].URL).To(gomega.Equal("/tos-data/bucket/sub/run-a/x.bam"))
+	g.Expect(task.Inputs[2].URL).To(gomega.Equal("/tos-data/external/z.txt"))
+
+	g.Expect(parseAccelerateName(prefixTarget.accelerateName())).To(gomega.Equal(prefixTarget))
+	g.Expect(parseAccelerateName("sub-external")).To(gomega.Equal(mountTarget{pvcName: "sub-external", bucket: "external"}))
+
+	podTemplate := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "executor"}}}}
+	i.ModifyExecutor(podTemplate, &localstore.Task{AccelerateNames: names})
+	mountPaths := make([]string, 0)
+	for _, volumeMount := range podTemplate.Spec.Containers[0].VolumeMounts {
+		mountPaths = append(mountPaths, volumeMount.MountPath)
+	}
+	g.Expect(mountPaths).To(gomega.ConsistOf("/tos-data/bucket/sub", "/tos-data/external"))
+	g.Expect(i.newPV(prefixTarget, "secret", opts.FusePodResources, "").Spec.CSI.VolumeAttributes["path"]).To(gomega.Equal("/sub"))
+}
+
+func TestOnFinishTaskSharedSecret(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	targetA := newMountTarget("sub-external", "external", "a")
+	targetB := newMountTarget("sub-external", "external", "b")
+	objs := append(fakePVAndPVC(targetA.accelerateName(), fakeNamespace, time.Now()),
+		fakePVAndPVC(targetB.accelerateName(), fakeNamespace, time.Now())...)
+	objs = append(objs, fakeSecret("sub-external", time.Now()))
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(objs...).Build()
+	bioosInfo := &localstore.BioosInfo{SubmissionID: "sub", Meta: &localstore.BioosInfoMeta{
+		BucketsAuthInfo: &localstore.BucketsAuthInfo{External: []*localstore.ExternalBucketAuthInfo{{Bucket: "external"}}},
+	}}
+	task01 := &localstore.TaskInfo{Task: localstore.Task{ID: "task-01", BioosInfo: bioosInfo, AccelerateNames: []string{targetA.accelerateName()}}}
+	task02 := &localstore.TaskInfo{Task: localstore.Task{ID: "task-02", BioosInfo: bioosInfo, AccelerateNames: []string{targetB.accelerateName()}}}
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	i := New(nil, fakeKubeClient, fakeLocalStoreHelper, fakeNamespace, NewOptions())
+
+	exist := func(obj ctrlclient.Object, name string) bool {
+		err := fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Namespace: fakeNamespace, Name: name}, obj)
+		if k8sapierrors.IsNotFound(err) {
+			return false
+		}
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		return true
+	}
+
+	// pvc of task-01 is deleted, but the secret is still used by task-02
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{task01, task02}, nil)
+	g.Expect(i.OnFinishTask(context.Background(), &task01.Task)).To(gomega.Succeed())
+	g.Expect(exist(&corev1.PersistentVolumeClaim{}, targetA.pvcName)).To(gomega.BeFalse())
+	g.Expect(exist(&corev1.Secret{}, "sub-external")).To(gomega.BeTrue())
+
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{task02}, nil)
+	g.Expect(i.OnFinishTask(context.Background(), &task02.Task)).To(gomega.Succeed())
+	g.Expect(exist(&corev1.PersistentVolumeClaim{}, targetB.pvcName)).To(gomega.BeFalse())
+	g.Expect(exist(&corev1.Secret{}, "sub-external")).To(gomega.BeFalse())
+}
@@ -16,6 +16,10 @@ type Options struct {
 	StaticTOSSecret  StaticTOSSecretOptions `mapstructure:"staticTOSSecret"`
 	FusePodResources FusePodResources       `mapstructure:"fusePodResources"`
 	AdditionalArgs   string                 `mapstructure:"additionalArgs"`
+	// CleanGracePeriod is the minimum age of unreferenced pv/pvc/secret to be cleaned
+	CleanGracePeriod time.Duration `mapstructure:"cleanGracePeriod"`
+	// CleanDryRun only logs unreferenced pv/pvc/secret instead of deleting them
+	CleanDryRun bool `mapstructure:"cleanDryRun"`
 }
 
 // StaticTOSSecretOptions ...
@@ -41,6 +45,7 @@ func NewOptions() *Options {
 	return &Options{
 		BucketNumPerTask: 10,
 		CleanPeriod:      10 * time.Minute,
+		CleanGracePeriod: 10 * time.Minute,
 		FusePodResources: FusePodResources{
 			Requests: FusePodResource{
 				CPU:    "100m",
@@ -69,6 +74,9 @@ func (o *Options) Validate() error {
 	if o.CleanPeriod < time.Second {
 		return fmt.Errorf("mountTOS clean period %s should not less than 1s", o.CleanPeriod.String())
 	}
+	if o.CleanGracePeriod < 0 {
+		return fmt.Errorf("mountTOS clean grace period %s should not be negative", o.CleanGracePeriod.String())
+	}
 	if o.StaticTOSSecret.Enable {
 		if o.StaticTOSSecret.Name == "" {
 			return fmt.Errorf("empty static tos secret name")
@@ -82,6 +90,8 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.TOSS3URL, "mount-tos-s3-url", o.TOSS3URL, "tos s3 url with scheme")
 	fs.IntVar(&o.BucketNumPerTask, "mount-tos-bucket-num-per-task", o.BucketNumPerTask, "the number of mounting bucket per task")
 	fs.DurationVar(&o.CleanPeriod, "mount-tos-clean-period", o.CleanPeriod, "mount tos clean period")
+	fs.DurationVar(&o.CleanGracePeriod, "mount-tos-clean-grace-period", o.CleanGracePeriod, "minimum age of unreferenced mount tos pv/pvc/secret to be cleaned")
+	fs.BoolVar(&o.CleanDryRun, "mount-tos-clean-dry-run", o.CleanDryRun, "only log unreferenced mount tos pv/pvc/secret instead of deleting them")
 	fs.BoolVar(&o.StaticTOSSecret.Enable, "mount-tos-static-secret-enable", o.StaticTOSSecret.Enable, "use static secret to mount tos")
 	fs.StringVar(&o.StaticTOSSecret.Name, "mount-tos-static-secret-name", o.StaticTOSSecret.Name, "static secret name to mount tos")
 	fs.StringVar(&o.FusePodResources.Requests.CPU, "mount-tos-fuse-pod-requests-cpu", o.FusePodResources.Requests.CPU, "mount tos fuse pod requests cpu")
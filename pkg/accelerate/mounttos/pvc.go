@@ -18,7 +18,8 @@ import (
 
 const deletingWaitDuration = time.Second * 5
 
-func (i *Impl) createPVAndPVC(ctx context.Context, pvcName, secretName string, fusePodResources FusePodResources, fuseAdditionalArgs string) (deleting bool, err error) {
+func (i *Impl) createPVAndPVC(ctx context.Context, target mountTarget, secretName string, fusePodResources FusePodResources, fuseAdditionalArgs string) (deleting bool, err error) {
+	pvcName := target.pvcName
 	gotPV := &corev1.PersistentVolume{}
 	pvExist := true
 	if err = i.kubeClient.Get(ctx, ctrlclient.ObjectKey{Name: pvcName}, gotPV); err != nil {
@@ -46,7 +47,7 @@ func (i *Impl) createPVAndPVC(ctx context.Context, pvcName, secretName string, f
 	// Only if both PV and PVC are not deleting, we can create them. Otherwise, a new created
 	// PV may bound to a deleting PVC.
 	if !pvExist {
-		if err = i.kubeClient.Create(ctx, i.newPV(pvcName, secretName, fusePodResources, fuseAdditionalArgs)); err != nil {
+		if err = i.kubeClient.Create(ctx, i.newPV(target, secretName, fusePodResources, fuseAdditionalArgs)); err != nil {
 			if k8sapierrors.IsAlreadyExists(err) {
 				return false, nil
 			}
@@ -54,7 +55,7 @@ func (i *Impl) createPVAndPVC(ctx context.Context, pvcName, secretName string, f
 		}
 	}
 	if !pvcExist {
-		if err = i.kubeClient.Create(ctx, i.newPVC(pvcName)); err != nil {
+		if err = i.kubeClient.Create(ctx, i.newPVC(target)); err != nil {
 			if k8sapierrors.IsAlreadyExists(err) {
 				return false, nil
 			}
@@ -65,12 +66,10 @@ func (i *Impl) createPVAndPVC(ctx context.Context, pvcName, secretName string, f
 	return false, nil
 }
 
-func (i *Impl) newPV(pvcName, secretName string, fusePodResources FusePodResources, fuseAdditionalArgs string) *corev1.PersistentVolume {
-	bucket := parseBucketFromPVCName(pvcName)
-
+func (i *Impl) newPV(target mountTarget, secretName string, fusePodResources FusePodResources, fuseAdditionalArgs string) *corev1.PersistentVolume {
 	pv := &corev1.PersistentVolume{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: pvcName,
+			Name: target.pvcName,
 		},
 		Spec: corev1.PersistentVolumeSpec{
 			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
@@ -86,15 +85,15 @@ func (i *Impl) newPV(pvcName, secretName string, fusePodResources FusePodResourc
 						Namespace: i.namespace,
 					},
 					VolumeAttributes: map[string]string{
-						"bucket":                  bucket,
-						"path":                    "/",
-						"url":                     i.opts.TOSS3URL,
+						"bucket":                  target.bucket,
+						"path":                    "/" + target.prefix,
+						"url":                     i.options().TOSS3URL,
 						"fuse_pod_cpu_request":    fusePodResources.Requests.CPU,
 						"fuse_pod_cpu_limit":      fusePodResources.Limits.CPU,
 						"fuse_pod_memory_request": fusePodResources.Requests.Memory,
 						"fuse_pod_memory_limit":   fusePodResources.Limits.Memory,
 					},
-					VolumeHandle: pvcName,
+					VolumeHandle: target.pvcName,
 				},
 			},
 			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
@@ -109,17 +108,19 @@ func (i *Impl) newPV(pvcName, secretName string, fusePodResources FusePodResourc
 		pv.Spec.PersistentVolumeSource.CSI.VolumeAttributes["additional_args"] = fuseAdditionalArgs
 	}
 
-	pv.Labels = map[string]string{consts.LabelBucketName: bucket}
+	pv.Labels = map[string]string{
+		consts.LabelBucketName:  target.bucket,
+		consts.LabelManagedBy:   consts.ManagedByVeTESK8SAgent,
+		consts.LabelAccelerator: consts.MountTOSAccelerateType,
+	}
 
 	return pv
 }
 
-func (i *Impl) newPVC(pvcName string) *corev1.PersistentVolumeClaim {
-	bucket := parseBucketFromPVCName(pvcName)
-
+func (i *Impl) newPVC(target mountTarget) *corev1.PersistentVolumeClaim {
 	pvc := &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      pvcName,
+			Name:      target.pvcName,
 			Namespace: i.namespace,
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
@@ -130,11 +131,15 @@ func (i *Impl) newPVC(pvcName string) *corev1.PersistentVolumeClaim {
 				},
 			},
 			VolumeMode: utils.Point(corev1.PersistentVolumeFilesystem),
-			VolumeName: pvcName,
+			VolumeName: target.pvcName,
 		},
 	}
 
-	pvc.Labels = map[string]string{consts.LabelBucketName: bucket}
+	pvc.Labels = map[string]string{
+		consts.LabelBucketName:  target.bucket,
+		consts.LabelManagedBy:   consts.ManagedByVeTESK8SAgent,
+		consts.LabelAccelerator: consts.MountTOSAccelerateType,
+	}
 
 	return pvc
 }
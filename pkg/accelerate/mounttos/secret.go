@@ -54,7 +54,8 @@ func (i *Impl) createKubeSecret(ctx context.Context, name, ak, sk string) error
 			Namespace: i.namespace,
 			Name:      name,
 			Labels: map[string]string{
-				consts.LabelManagedBy: consts.ManagedByVeTESK8SAgent,
+				consts.LabelManagedBy:   consts.ManagedByVeTESK8SAgent,
+				consts.LabelAccelerator: consts.MountTOSAccelerateType,
 			},
 		},
 		Data: map[string][]byte{
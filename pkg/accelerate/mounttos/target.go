@@ -0,0 +1,66 @@
+package mounttos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// accelerateNameSeparator separates pvc name and `<bucket>/<prefix>` in accelerate name
+	accelerateNameSeparator = "@"
+	prefixHashLength        = 10
+)
+
+// mountTarget is a bucket prefix mounted by a pv/pvc. Tasks mounting the same prefix share the pv/pvc.
+type mountTarget struct {
+	pvcName string
+	bucket  string
+	// prefix is without leading and trailing slash, empty for bucket root
+	prefix string
+}
+
+// newMountTarget names pv/pvc of bucket root as baseName, which is compatible with older versions,
+// and pv/pvc of a prefix as `<baseName>-<hash of prefix>`.
+func newMountTarget(baseName, bucket, prefix string) mountTarget {
+	pvcName := baseName
+	if prefix != "" {
+		sum := sha256.Sum256([]byte(prefix))
+		pvcName = fmt.Sprintf("%s-%s", baseName, hex.EncodeToString(sum[:])[:prefixHashLength])
+	}
+	return mountTarget{pvcName: pvcName, bucket: bucket, prefix: prefix}
+}
+
+// accelerateName is `<pvcName>` for bucket root, or `<pvcName>@<bucket>/<prefix>` for a prefix
+func (t mountTarget) accelerateName() string {
+	if t.prefix == "" {
+		return t.pvcName
+	}
+	return fmt.Sprintf("%s%s%s/%s", t.pvcName, accelerateNameSeparator, t.bucket, t.prefix)
+}
+
+// mountPath keeps `s3://bucket/path` mapping to `/tos-data/bucket/path`, no matter which prefix is mounted
+func (t mountTarget) mountPath() string {
+	if t.prefix == "" {
+		return mountTOSPath(t.bucket)
+	}
+	return fmt.Sprintf("%s/%s", mountTOSPath(t.bucket), t.prefix)
+}
+
+func parseAccelerateName(name string) mountTarget {
+	pvcName, bucketPrefix, found := strings.Cut(name, accelerateNameSeparator)
+	if !found {
+		return mountTarget{pvcName: name, bucket: parseBucketFromPVCName(name)}
+	}
+	bucket, prefix, _ := strings.Cut(bucketPrefix, "/")
+	return mountTarget{pvcName: pvcName, bucket: bucket, prefix: prefix}
+}
+
+func parseAccelerateNames(names []string) []mountTarget {
+	res := make([]mountTarget, 0, len(names))
+	for _, name := range names {
+		res = append(res, parseAccelerateName(name))
+	}
+	return res
+}
@@ -5,14 +5,22 @@ import (
 
 	"github.com/spf13/pflag"
 
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/mounts3"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/mounttos"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/prepull"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/refdata"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 )
 
 // Options ...
 type Options struct {
-	Type     string            `mapstructure:"type"`
+	Type string `mapstructure:"type"`
+	// Chain is accelerator types of composite accelerator in order, inputs claimed by former ones are skipped by latter ones
+	Chain    []string          `mapstructure:"chain"`
 	MountTOS *mounttos.Options `mappstructure:"mounttos"`
+	MountS3  *mounts3.Options  `mapstructure:"mountS3"`
+	PrePull  *prepull.Options  `mapstructure:"prePull"`
+	RefData  *refdata.Options  `mapstructure:"refData"`
 }
 
 // NewOptions ...
@@ -20,19 +28,57 @@ func NewOptions() *Options {
 	return &Options{
 		Type:     consts.NullAccelerateType,
 		MountTOS: mounttos.NewOptions(),
+		MountS3:  mounts3.NewOptions(),
+		PrePull:  prepull.NewOptions(),
+		RefData:  refdata.NewOptions(),
 	}
 }
 
 // Validate ...
 func (o *Options) Validate() error {
-	switch o.Type {
+	if o.Type != consts.CompositeAccelerateType {
+		return o.validateType(o.Type)
+	}
+	if len(o.Chain) == 0 {
+		return fmt.Errorf("empty chain of composite accelerator")
+	}
+	seen := make(map[string]struct{}, len(o.Chain))
+	for _, accelerateType := range o.Chain {
+		if accelerateType == consts.NullAccelerateType || accelerateType == consts.CompositeAccelerateType {
+			return fmt.Errorf("invalid accelerate type in chain: %s", accelerateType)
+		}
+		if _, ok := seen[accelerateType]; ok {
+			return fmt.Errorf("duplicated accelerate type in chain: %s", accelerateType)
+		}
+		seen[accelerateType] = struct{}{}
+		if err := o.validateType(accelerateType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Options) validateType(accelerateType string) error {
+	switch accelerateType {
 	case consts.NullAccelerateType:
 	case consts.MountTOSAccelerateType:
 		if err := o.MountTOS.Validate(); err != nil {
 			return err
 		}
+	case consts.MountS3AccelerateType:
+		if err := o.MountS3.Validate(); err != nil {
+			return err
+		}
+	case consts.PrePullAccelerateType:
+		if err := o.PrePull.Validate(); err != nil {
+			return err
+		}
+	case consts.RefDataAccelerateType:
+		if err := o.RefData.Validate(); err != nil {
+			return err
+		}
 	default:
-		return fmt.Errorf("invalid accelerate type: %s", o.Type)
+		return fmt.Errorf("invalid accelerate type: %s", accelerateType)
 	}
 	return nil
 }
@@ -40,5 +86,9 @@ func (o *Options) Validate() error {
 // AddFlags ...
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.Type, "accelerate-type", o.Type, "accelerate type")
+	fs.StringSliceVar(&o.Chain, "accelerate-chain", o.Chain, "accelerate types of composite accelerator in order")
 	o.MountTOS.AddFlags(fs)
+	o.MountS3.AddFlags(fs)
+	o.PrePull.AddFlags(fs)
+	o.RefData.AddFlags(fs)
 }
@@ -0,0 +1,36 @@
+package podtemplate
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+)
+
+// AddMountEnv tells filer that inputs are mounted if the task has accelerate names.
+// It is skipped for containers which already have it, because accelerators in composite may all set it.
+func AddMountEnv(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	if len(localTask.AccelerateNames) == 0 {
+		return
+	}
+	for index := range podTemplate.Spec.Containers {
+		if hasEnv(podTemplate.Spec.Containers[index].Env, consts.IsMountTOS) {
+			continue
+		}
+		podTemplate.Spec.Containers[index].Env = append(podTemplate.Spec.Containers[index].Env, corev1.EnvVar{
+			Name:  consts.IsMountTOS,
+			Value: strconv.FormatBool(true),
+		})
+	}
+}
+
+func hasEnv(envs []corev1.EnvVar, name string) bool {
+	for _, env := range envs {
+		if env.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package podtemplate
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+)
+
+func TestAddMountEnv(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	podTemplate := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "filer"}}}}
+	AddMountEnv(podTemplate, &localstore.Task{})
+	g.Expect(podTemplate.Spec.Containers[0].Env).To(gomega.BeEmpty())
+
+	// accelerators in composite add it only once
+	localTask := &localstore.Task{AccelerateNames: []string{"bucket"}}
+	AddMountEnv(podTemplate, localTask)
+	AddMountEnv(podTemplate, localTask)
+	g.Expect(podTemplate.Spec.Containers[0].Env).To(gomega.Equal([]corev1.EnvVar{{Name: consts.IsMountTOS, Value: "true"}}))
+}
@@ -0,0 +1,65 @@
+package prepull
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Options ...
+type Options struct {
+	// HotImageNum is the max number of images warmed on nodes
+	HotImageNum int `mapstructure:"hotImageNum"`
+	// MinTaskNum is the min number of tasks in local store using an image to warm it
+	MinTaskNum int           `mapstructure:"minTaskNum"`
+	SyncPeriod time.Duration `mapstructure:"syncPeriod"`
+	// ToolImage provides a static busybox, which is copied to keep containers of images running without shell
+	ToolImage            string   `mapstructure:"toolImage"`
+	ImagePullSecretNames []string `mapstructure:"imagePullSecretNames"`
+	// NodeSelector and Tolerations select executor node pools to warm images
+	NodeSelector map[string]string   `mapstructure:"nodeSelector"`
+	Tolerations  []corev1.Toleration `mapstructure:"tolerations"`
+	// IfNotPresentForDigest uses IfNotPresent pull policy for digest-pinned executor images
+	IfNotPresentForDigest bool `mapstructure:"ifNotPresentForDigest"`
+}
+
+// NewOptions ...
+func NewOptions() *Options {
+	return &Options{
+		HotImageNum: 10,
+		MinTaskNum:  1,
+		SyncPeriod:  time.Minute,
+		ToolImage:   "busybox:1.36",
+	}
+}
+
+// Validate ...
+func (o *Options) Validate() error {
+	if o.HotImageNum <= 0 {
+		return fmt.Errorf("prepull hotImageNum %d must be greater than 0", o.HotImageNum)
+	}
+	if o.MinTaskNum <= 0 {
+		return fmt.Errorf("prepull minTaskNum %d must be greater than 0", o.MinTaskNum)
+	}
+	if o.SyncPeriod < time.Second {
+		return fmt.Errorf("prepull sync period %s should not less than 1s", o.SyncPeriod.String())
+	}
+	if o.ToolImage == "" {
+		return errors.New("empty prepull tool image")
+	}
+	return nil
+}
+
+// AddFlags ...
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&o.HotImageNum, "prepull-hot-image-num", o.HotImageNum, "max number of images warmed on nodes")
+	fs.IntVar(&o.MinTaskNum, "prepull-min-task-num", o.MinTaskNum, "min number of tasks using an image to warm it")
+	fs.DurationVar(&o.SyncPeriod, "prepull-sync-period", o.SyncPeriod, "period to sync warmed images")
+	fs.StringVar(&o.ToolImage, "prepull-tool-image", o.ToolImage, "image providing a static busybox")
+	fs.StringSliceVar(&o.ImagePullSecretNames, "prepull-image-pull-secret-names", o.ImagePullSecretNames, "image pull secrets of prepull daemonset")
+	fs.StringToStringVar(&o.NodeSelector, "prepull-node-selector", o.NodeSelector, "node selector of prepull daemonset")
+	fs.BoolVar(&o.IfNotPresentForDigest, "prepull-if-not-present-for-digest", o.IfNotPresentForDigest, "use IfNotPresent pull policy for digest-pinned executor images")
+}
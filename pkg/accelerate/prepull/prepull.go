@@ -0,0 +1,299 @@
+package prepull
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+// Impl warms executor images on nodes. Images of executors are recorded as accelerate names of tasks,
+// the hottest images of tasks in local store are pulled by containers of a managed DaemonSet.
+type Impl struct {
+	kubeClient       ctrlclient.Client
+	localStoreHelper localstore.Helper
+	namespace        string
+	opts             *Options
+
+	// syncLock protects considered, which are images counted in last sync
+	syncLock   sync.Mutex
+	considered map[string]struct{}
+}
+
+const (
+	daemonSetName   = "vetes-image-prepull"
+	toolVolumeName  = "prepull-tool"
+	toolMountPath   = "/prepull-tool"
+	digestSeparator = "@"
+	// sleepSeconds keeps image containers running, which exit and restart in about 68 years
+	sleepSeconds = "2147483647"
+)
+
+var (
+	prePullCPU    = resource.MustParse("10m")
+	prePullMemory = resource.MustParse("16Mi")
+)
+
+// New ...
+func New(kubeClient ctrlclient.Client, localStoreHelper localstore.Helper, namespace string, opts *Options) *Impl {
+	return &Impl{
+		kubeClient:       kubeClient,
+		localStoreHelper: localStoreHelper,
+		namespace:        namespace,
+		opts:             opts,
+		considered:       make(map[string]struct{}),
+	}
+}
+
+// Options ...
+func (i *Impl) Options() *Options {
+	return i.opts
+}
+
+// CronCleanFunc syncs warmed images periodically, images of finished tasks are removed here
+func (i *Impl) CronCleanFunc() (func(), *time.Duration) {
+	return func() {
+		i.syncLock.Lock()
+		defer i.syncLock.Unlock()
+		if err := i.sync(context.Background()); err != nil {
+			log.Errorw("failed to sync prepull images", "err", err)
+		}
+	}, &i.opts.SyncPeriod
+}
+
+// ModifySyncTask records images of executors, inputs are not modified
+func (i *Impl) ModifySyncTask(_ context.Context, taskFull *models.Task) (accelerateNames []string, err error) {
+	seen := make(map[string]struct{}, len(taskFull.Executors))
+	for _, executor := range taskFull.Executors {
+		if executor == nil || executor.Image == "" {
+			continue
+		}
+		if _, ok := seen[executor.Image]; ok {
+			continue
+		}
+		seen[executor.Image] = struct{}{}
+		accelerateNames = append(accelerateNames, executor.Image)
+	}
+	return accelerateNames, nil
+}
+
+// ModifyInputsFiler ...
+func (i *Impl) ModifyInputsFiler(_ *corev1.PodTemplateSpec, _ *localstore.Task) {
+	return
+}
+
+// ModifyExecutor uses IfNotPresent pull policy for digest-pinned images, which never change
+func (i *Impl) ModifyExecutor(podTemplate *corev1.PodTemplateSpec, _ *localstore.Task) {
+	if !i.opts.IfNotPresentForDigest {
+		return
+	}
+	for index := range podTemplate.Spec.Containers {
+		if strings.Contains(podTemplate.Spec.Containers[index].Image, digestSeparator) {
+			podTemplate.Spec.Containers[index].ImagePullPolicy = corev1.PullIfNotPresent
+		}
+	}
+}
+
+// ModifyOutputsFiler ...
+func (i *Impl) ModifyOutputsFiler(_ *corev1.PodTemplateSpec, _ *localstore.Task) {
+	return
+}
+
+// OnProcessTask syncs warmed images if some images of the task are not considered yet.
+// Warming is best effort, so errors are only logged and never block the task.
+func (i *Impl) OnProcessTask(ctx context.Context, localTask *localstore.Task) (ctrl.Result, error) {
+	i.syncLock.Lock()
+	defer i.syncLock.Unlock()
+
+	for _, image := range localTask.AccelerateNames {
+		if _, ok := i.considered[image]; !ok {
+			if err := i.sync(ctx); err != nil {
+				log.Warnw("failed to sync prepull images", "task", localTask.ID, "err", err)
+			}
+			break
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// OnFinishTask ...
+func (i *Impl) OnFinishTask(_ context.Context, _ *localstore.Task) error {
+	return nil
+}
+
+func (i *Impl) sync(ctx context.Context) error {
+	tasks, err := i.localStoreHelper.ListTasks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tasks in local store: %w", err)
+	}
+	counts := make(map[string]int)
+	for _, task := range tasks {
+		for _, image := range task.AccelerateNames {
+			counts[image]++
+		}
+	}
+	if err = i.applyDaemonSet(ctx, hotImages(counts, i.opts.HotImageNum, i.opts.MinTaskNum)); err != nil {
+		return err
+	}
+
+	i.considered = make(map[string]struct{}, len(counts))
+	for image := range counts {
+		i.considered[image] = struct{}{}
+	}
+	return nil
+}
+
+// hotImages returns at most num images used by at least minTaskNum tasks, sorted by name to keep DaemonSet stable
+func hotImages(counts map[string]int, num, minTaskNum int) []string {
+	images := make([]string, 0, len(counts))
+	for image, count := range counts {
+		if count >= minTaskNum {
+			images = append(images, image)
+		}
+	}
+	sort.Slice(images, func(a, b int) bool {
+		if counts[images[a]] != counts[images[b]] {
+			return counts[images[a]] > counts[images[b]]
+		}
+		return images[a] < images[b]
+	})
+	if len(images) > num {
+		images = images[:num]
+	}
+	sort.Strings(images)
+	return images
+}
+
+func (i *Impl) applyDaemonSet(ctx context.Context, images []string) error {
+	if len(images) == 0 {
+		if err := i.kubeClient.Delete(ctx, &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace: i.namespace, Name: daemonSetName,
+		}}); err != nil && !k8sapierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete prepull daemonset: %w", err)
+		}
+		return nil
+	}
+
+	expected := i.newDaemonSet(images)
+	got := &appsv1.DaemonSet{}
+	if err := i.kubeClient.Get(ctx, ctrlclient.ObjectKeyFromObject(expected), got); err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get prepull daemonset: %w", err)
+		}
+		if err = i.kubeClient.Create(ctx, expected); err != nil {
+			return fmt.Errorf("failed to create prepull daemonset: %w", err)
+		}
+		log.Infow("created prepull daemonset", "images", images)
+		return nil
+	}
+	if got.Annotations[consts.AnnoTemplateHash] == expected.Annotations[consts.AnnoTemplateHash] {
+		return nil
+	}
+	got.Annotations = expected.Annotations
+	got.Spec.Template = expected.Spec.Template
+	if err := i.kubeClient.Update(ctx, got); err != nil {
+		return fmt.Errorf("failed to update prepull daemonset: %w", err)
+	}
+	log.Infow("updated prepull daemonset", "images", images)
+	return nil
+}
+
+func (i *Impl) newDaemonSet(images []string) *appsv1.DaemonSet {
+	labels := map[string]string{
+		consts.LabelType:      consts.ImagePrePullType,
+		consts.LabelManagedBy: consts.ManagedByVeTESK8SAgent,
+	}
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: prePullCPU, corev1.ResourceMemory: prePullMemory},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: prePullCPU, corev1.ResourceMemory: prePullMemory},
+	}
+	toolMount := corev1.VolumeMount{Name: toolVolumeName, MountPath: toolMountPath}
+
+	// busybox is static, so it sleeps in images of any distribution, or even without shell
+	initContainers := []corev1.Container{{
+		Name:            "tool",
+		Image:           i.opts.ToolImage,
+		Command:         []string{"cp", "/bin/busybox", toolMountPath + "/busybox"},
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Resources:       resources,
+		VolumeMounts:    []corev1.VolumeMount{toolMount},
+	}}
+	// each image is pulled by its own container, so that a bad or private image does not block the others
+	containers := make([]corev1.Container, 0, len(images))
+	for index, image := range images {
+		containers = append(containers, corev1.Container{
+			Name:            fmt.Sprintf("image-%d", index),
+			Image:           image,
+			Command:         []string{toolMountPath + "/busybox", "sleep", sleepSeconds},
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Resources:       resources,
+			VolumeMounts:    []corev1.VolumeMount{toolMount},
+		})
+	}
+
+	res := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: i.namespace,
+			Name:      daemonSetName,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+					// pods with a failing image are never ready, which should not block rolling out new images
+					MaxUnavailable: utils.Point(intstr.FromString("100%")),
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					InitContainers:               initContainers,
+					Containers:                   containers,
+					NodeSelector:                 i.opts.NodeSelector,
+					Tolerations:                  i.opts.Tolerations,
+					EnableServiceLinks:           utils.Point(false),
+					AutomountServiceAccountToken: utils.Point(false),
+					Volumes: []corev1.Volume{{
+						Name:         toolVolumeName,
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					}},
+				},
+			},
+		},
+	}
+	for _, secretName := range i.opts.ImagePullSecretNames {
+		res.Spec.Template.Spec.ImagePullSecrets = append(res.Spec.Template.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: secretName})
+	}
+	res.Annotations = map[string]string{consts.AnnoTemplateHash: templateHash(&res.Spec.Template)}
+	return res
+}
+
+// templateHash is compared instead of the template, which is defaulted by apiserver
+func templateHash(template *corev1.PodTemplateSpec) string {
+	data, _ := json.Marshal(template)
+	hasher := fnv.New64a()
+	_, _ = hasher.Write(data)
+	return strconv.FormatUint(hasher.Sum64(), 16)
+}
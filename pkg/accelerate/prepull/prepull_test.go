@@ -0,0 +1,109 @@
+package prepull
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+const fakeNamespace = "vetes"
+
+func TestModifySyncTaskAndExecutor(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	opts := NewOptions()
+	opts.IfNotPresentForDigest = true
+	i := New(nil, nil, fakeNamespace, opts)
+
+	names, err := i.ModifySyncTask(context.Background(), &models.Task{Executors: []*models.Executor{
+		{Image: "gatk:4.4"}, {Image: "gatk:4.4"}, {Image: "deepvariant@sha256:abcd"},
+	}})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(names).To(gomega.Equal([]string{"gatk:4.4", "deepvariant@sha256:abcd"}))
+
+	podTemplate := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+		{Image: "gatk:4.4", ImagePullPolicy: corev1.PullAlways},
+		{Image: "deepvariant@sha256:abcd", ImagePullPolicy: corev1.PullAlways},
+	}}}
+	i.ModifyExecutor(podTemplate, &localstore.Task{})
+	g.Expect(podTemplate.Spec.Containers[0].ImagePullPolicy).To(gomega.Equal(corev1.PullAlways))
+	g.Expect(podTemplate.Spec.Containers[1].ImagePullPolicy).To(gomega.Equal(corev1.PullIfNotPresent))
+}
+
+func TestSync(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	opts := NewOptions()
+	opts.HotImageNum = 2
+	fakeKubeClient := ctrlfake.NewClientBuilder().Build()
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	i := New(fakeKubeClient, fakeLocalStoreHelper, fakeNamespace, opts)
+
+	newTask := func(id string, images ...string) *localstore.TaskInfo {
+		return &localstore.TaskInfo{Task: localstore.Task{ID: id, AccelerateNames: images}}
+	}
+	getImages := func() []string {
+		daemonSet := &appsv1.DaemonSet{}
+		err := fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Namespace: fakeNamespace, Name: daemonSetName}, daemonSet)
+		if k8sapierrors.IsNotFound(err) {
+			return nil
+		}
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		var images []string
+		for _, container := range daemonSet.Spec.Template.Spec.Containers {
+			images = append(images, container.Image)
+		}
+		return images
+	}
+
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{
+		newTask("task-01", "a", "b"), newTask("task-02", "b", "c"), newTask("task-03", "c"),
+	}, nil)
+	_, err := i.OnProcessTask(context.Background(), &newTask("task-03", "c").Task)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(getImages()).To(gomega.Equal([]string{"b", "c"}))
+
+	// considered images do not trigger sync
+	_, err = i.OnProcessTask(context.Background(), &newTask("task-01", "a").Task)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{newTask("task-04", "d")}, nil)
+	fn, _ := i.CronCleanFunc()
+	fn()
+	g.Expect(getImages()).To(gomega.Equal([]string{"d"}))
+
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return(nil, nil)
+	fn()
+	g.Expect(getImages()).To(gomega.BeNil())
+}
+
+func TestNewDaemonSet(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	opts := NewOptions()
+	opts.ImagePullSecretNames = []string{"registry-a", "registry-b"}
+	i := New(nil, nil, fakeNamespace, opts)
+
+	daemonSet := i.newDaemonSet([]string{"gatk:4.4", "private/deepvariant:1.5"})
+	podSpec := daemonSet.Spec.Template.Spec
+	g.Expect(podSpec.InitContainers).To(gomega.HaveLen(1))
+	g.Expect(podSpec.Containers).To(gomega.HaveLen(2))
+	for index, image := range []string{"gatk:4.4", "private/deepvariant:1.5"} {
+		g.Expect(podSpec.Containers[index].Image).To(gomega.Equal(image))
+		g.Expect(podSpec.Containers[index].Command).To(gomega.Equal([]string{toolMountPath + "/busybox", "sleep", sleepSeconds}))
+	}
+	g.Expect(podSpec.ImagePullSecrets).To(gomega.Equal([]corev1.LocalObjectReference{{Name: "registry-a"}, {Name: "registry-b"}}))
+}
@@ -0,0 +1,61 @@
+package refdata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Options ...
+type Options struct {
+	MountPathPrefix string `mapstructure:"mountPathPrefix"`
+	// Volumes map url prefixes to pre-provisioned read-only pvc in namespace of agent
+	Volumes []Volume `mapstructure:"volumes"`
+}
+
+// Volume ...
+type Volume struct {
+	// URLPrefix such as `s3://refs/hg38/`, should end with `/`
+	URLPrefix string `mapstructure:"urlPrefix"`
+	PVCName   string `mapstructure:"pvcName"`
+	// SubPath is the directory in pvc holding files under URLPrefix
+	SubPath string `mapstructure:"subPath"`
+}
+
+// NewOptions ...
+func NewOptions() *Options {
+	return &Options{
+		MountPathPrefix: "/refdata",
+	}
+}
+
+// Validate ...
+func (o *Options) Validate() error {
+	if !strings.HasPrefix(o.MountPathPrefix, "/") {
+		return fmt.Errorf("refdata mount path prefix %s should be absolute", o.MountPathPrefix)
+	}
+	if len(o.Volumes) == 0 {
+		return errors.New("empty refdata volumes")
+	}
+	prefixes := make(map[string]struct{}, len(o.Volumes))
+	for _, volume := range o.Volumes {
+		if !strings.HasSuffix(volume.URLPrefix, "/") {
+			return fmt.Errorf("refdata url prefix %s should end with /", volume.URLPrefix)
+		}
+		if _, ok := prefixes[volume.URLPrefix]; ok {
+			return fmt.Errorf("duplicated refdata url prefix %s", volume.URLPrefix)
+		}
+		prefixes[volume.URLPrefix] = struct{}{}
+		if volume.PVCName == "" {
+			return fmt.Errorf("empty pvc name of refdata url prefix %s", volume.URLPrefix)
+		}
+	}
+	return nil
+}
+
+// AddFlags ...
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.MountPathPrefix, "refdata-mount-path-prefix", o.MountPathPrefix, "path prefix to mount refdata pvc")
+}
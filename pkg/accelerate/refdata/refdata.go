@@ -0,0 +1,179 @@
+package refdata
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/podtemplate"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+// Impl mounts pre-provisioned read-only pvc of reference data, such as hg38 fasta and bwa indexes.
+// Inputs matching url prefixes are rewritten to paths in mounted pvc, so filer links them instead of downloading.
+// Accelerate names are the pvc names.
+type Impl struct {
+	kubeClient ctrlclient.Client
+	namespace  string
+
+	optsLock sync.RWMutex
+	opts     *Options
+}
+
+const volumeNamePrefix = "refdata-"
+
+// New ...
+func New(kubeClient ctrlclient.Client, namespace string, opts *Options) *Impl {
+	return &Impl{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		opts:       opts,
+	}
+}
+
+func (i *Impl) options() *Options {
+	i.optsLock.RLock()
+	defer i.optsLock.RUnlock()
+	return i.opts
+}
+
+// UpdateOptions applies the reloadable subset of opts, volumes only affect tasks synced later.
+// It returns whether some changed options require restart to take effect.
+func (i *Impl) UpdateOptions(opts *Options) (needRestart bool) {
+	i.optsLock.Lock()
+	defer i.optsLock.Unlock()
+	res := *i.opts
+	res.Volumes = opts.Volumes
+	i.opts = &res
+	return res.MountPathPrefix != opts.MountPathPrefix
+}
+
+// CronCleanFunc ...
+func (i *Impl) CronCleanFunc() (func(), *time.Duration) {
+	return nil, nil
+}
+
+// ModifySyncTask rewrites inputs matching the longest url prefix. Volumes whose pvc is not bound are skipped,
+// and their inputs are downloaded as usual.
+func (i *Impl) ModifySyncTask(ctx context.Context, taskFull *models.Task) (accelerateNames []string, err error) {
+	opts := i.options()
+	available := make(map[string]bool)
+	for _, input := range taskFull.Inputs {
+		if input == nil || input.URL == "" {
+			continue
+		}
+		volume := matchVolume(opts.Volumes, input.URL)
+		if volume == nil {
+			continue
+		}
+		ok, checked := available[volume.PVCName]
+		if !checked {
+			if ok, err = i.pvcAvailable(ctx, volume.PVCName); err != nil {
+				return nil, err
+			}
+			available[volume.PVCName] = ok
+			if ok {
+				accelerateNames = append(accelerateNames, volume.PVCName)
+			}
+		}
+		if !ok {
+			continue
+		}
+		input.URL = path.Join(mountPath(opts, volume.PVCName), volume.SubPath, strings.TrimPrefix(input.URL, volume.URLPrefix))
+	}
+	return accelerateNames, nil
+}
+
+// ModifyInputsFiler ...
+func (i *Impl) ModifyInputsFiler(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	i.mountPVC(podTemplate, localTask)
+	podtemplate.AddMountEnv(podTemplate, localTask)
+}
+
+// ModifyExecutor ...
+func (i *Impl) ModifyExecutor(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	i.mountPVC(podTemplate, localTask)
+}
+
+// ModifyOutputsFiler ...
+func (i *Impl) ModifyOutputsFiler(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	i.mountPVC(podTemplate, localTask)
+	podtemplate.AddMountEnv(podTemplate, localTask)
+}
+
+// OnProcessTask ...
+func (i *Impl) OnProcessTask(_ context.Context, _ *localstore.Task) (ctrl.Result, error) {
+	return ctrl.Result{}, nil
+}
+
+// OnFinishTask ...
+func (i *Impl) OnFinishTask(_ context.Context, _ *localstore.Task) error {
+	return nil
+}
+
+func (i *Impl) pvcAvailable(ctx context.Context, pvcName string) (bool, error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := i.kubeClient.Get(ctx, ctrlclient.ObjectKey{Namespace: i.namespace, Name: pvcName}, pvc); err != nil {
+		if ctrlclient.IgnoreNotFound(err) != nil {
+			return false, fmt.Errorf("failed to get refdata pvc %s: %w", pvcName, err)
+		}
+		log.Warnw("refdata pvc not found", "pvc", pvcName)
+		return false, nil
+	}
+	if pvc.Status.Phase != corev1.ClaimBound {
+		log.Warnw("refdata pvc not bound", "pvc", pvcName, "phase", pvc.Status.Phase)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (i *Impl) mountPVC(podTemplate *corev1.PodTemplateSpec, localTask *localstore.Task) {
+	opts := i.options()
+	for index, pvcName := range localTask.AccelerateNames {
+		volumeName := volumeNamePrefix + strconv.Itoa(index)
+		podTemplate.Spec.Volumes = append(podTemplate.Spec.Volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: pvcName,
+					ReadOnly:  true,
+				},
+			},
+		})
+		for containerIndex := range podTemplate.Spec.Containers {
+			podTemplate.Spec.Containers[containerIndex].VolumeMounts = append(podTemplate.Spec.Containers[containerIndex].VolumeMounts, corev1.VolumeMount{
+				Name:      volumeName,
+				ReadOnly:  true,
+				MountPath: mountPath(opts, pvcName),
+			})
+		}
+	}
+}
+
+// matchVolume returns the volume with the longest url prefix of taskURL
+func matchVolume(volumes []Volume, taskURL string) *Volume {
+	var res *Volume
+	for index := range volumes {
+		if !strings.HasPrefix(taskURL, volumes[index].URLPrefix) {
+			continue
+		}
+		if res == nil || len(volumes[index].URLPrefix) > len(res.URLPrefix) {
+			res = &volumes[index]
+		}
+	}
+	return res
+}
+
+func mountPath(opts *Options, pvcName string) string {
+	return path.Join(opts.MountPathPrefix, pvcName)
+}
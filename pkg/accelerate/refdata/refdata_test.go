@@ -0,0 +1,66 @@
+package refdata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+const fakeNamespace = "vetes"
+
+func fakePVC(name string, phase corev1.PersistentVolumeClaimPhase) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: fakeNamespace, Name: name},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: phase},
+	}
+}
+
+func TestModifySyncTask(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(
+		fakePVC("refdata-hg38", corev1.ClaimBound),
+		fakePVC("refdata-vep", corev1.ClaimPending),
+	).Build()
+	opts := NewOptions()
+	opts.Volumes = []Volume{
+		{URLPrefix: "s3://refs/", PVCName: "refdata-all"},
+		{URLPrefix: "s3://refs/hg38/", PVCName: "refdata-hg38", SubPath: "hg38"},
+		{URLPrefix: "s3://refs/vep/", PVCName: "refdata-vep"},
+	}
+	g.Expect(opts.Validate()).To(gomega.Succeed())
+	i := New(fakeKubeClient, fakeNamespace, opts)
+
+	task := &models.Task{Inputs: []*models.Input{
+		{URL: "s3://refs/hg38/hg38.fa"},
+		{URL: "s3://refs/hg38/bwa/"},
+		{URL: "s3://refs/vep/cache.tar"},
+		{URL: "s3://refs/other.txt"},
+		{URL: "s3://bucket/sample.bam"},
+	}}
+	names, err := i.ModifySyncTask(context.Background(), task)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(names).To(gomega.Equal([]string{"refdata-hg38"}))
+	g.Expect(task.Inputs[0].URL).To(gomega.Equal("/refdata/refdata-hg38/hg38/hg38.fa"))
+	g.Expect(task.Inputs[1].URL).To(gomega.Equal("/refdata/refdata-hg38/hg38/bwa"))
+	g.Expect(task.Inputs[2].URL).To(gomega.Equal("s3://refs/vep/cache.tar"))
+	g.Expect(task.Inputs[3].URL).To(gomega.Equal("s3://refs/other.txt"))
+	g.Expect(task.Inputs[4].URL).To(gomega.Equal("s3://bucket/sample.bam"))
+
+	podTemplate := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "filer"}}}}
+	i.ModifyInputsFiler(podTemplate, &localstore.Task{AccelerateNames: names})
+	g.Expect(podTemplate.Spec.Volumes).To(gomega.HaveLen(1))
+	g.Expect(podTemplate.Spec.Volumes[0].PersistentVolumeClaim.ClaimName).To(gomega.Equal("refdata-hg38"))
+	g.Expect(podTemplate.Spec.Containers[0].VolumeMounts).To(gomega.Equal([]corev1.VolumeMount{{
+		Name: "refdata-0", ReadOnly: true, MountPath: "/refdata/refdata-hg38",
+	}}))
+	g.Expect(podTemplate.Spec.Containers[0].Env).To(gomega.Equal([]corev1.EnvVar{{Name: consts.IsMountTOS, Value: "true"}}))
+}
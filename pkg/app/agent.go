@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
@@ -15,12 +16,16 @@ import (
 	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/app/options"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/cluster"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/crontab"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/drain"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/offload"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/reconciler"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/reconciler/runner"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/syncer"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/tracing"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/usage"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/version"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/viper"
@@ -48,12 +53,12 @@ func newAgentCommand(opts *options.Options) *cobra.Command {
 				log.Infow("FLAG", flag.Name, flag.Value)
 			})
 
-			return run(opts)
+			return run(opts, cmd.Flags())
 		},
 	}
 }
 
-func run(opts *options.Options) error {
+func run(opts *options.Options, flags *pflag.FlagSet) error {
 	log.Infow("run veTES k8s agent")
 
 	kubeConfig, err := ctrl.GetConfig()
@@ -81,7 +86,23 @@ func run(opts *options.Options) error {
 		return fmt.Errorf("unable to create healthz check: %w", err)
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), opts.Tracing)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Warnw("failed to shutdown tracing", "err", err)
+		}
+	}()
+
 	vetesClient := vetesclient.NewClient(opts.VeTESClient)
+	caps, err := vetesclient.ProbeCapabilities(context.Background(), vetesClient, opts.VeTESClient)
+	if err != nil {
+		return fmt.Errorf("failed to probe capabilities of vetes-api: %w", err)
+	}
+	log.Infow("probed capabilities of vetes-api", "mode", opts.VeTESClient.Mode, "clusterFilter", caps.ClusterFilter,
+		"claimTask", caps.ClaimTask, "clusterReport", caps.ClusterReport)
 	offloadHelper, err := offload.NewHelper(opts.Offload)
 	if err != nil {
 		return err
@@ -89,19 +110,47 @@ func run(opts *options.Options) error {
 	kubeClientNative := kubernetes.NewForConfigOrDie(kubeConfig)
 	kubeClient := mgr.GetClient()
 	localStoreHelper := localstore.NewHelper(kubeClient, opts.Namespace)
-	accelerator, err := accelerate.NewAccelerator(vetesClient, kubeClient, opts.Namespace, opts.Accelerate)
+	accelerator, err := accelerate.NewAccelerator(vetesClient, kubeClient, localStoreHelper, opts.Namespace, opts.Accelerate)
+	if err != nil {
+		return err
+	}
+	usageSource, err := usage.NewSource(kubeClientNative, opts.Usage)
 	if err != nil {
 		return err
 	}
-	runnerImpl, err := runner.New(vetesClient, localStoreHelper, offloadHelper, accelerator, kubeClientNative, kubeClient, opts.Cluster.ID, opts.Namespace, opts.Runner)
+	usageTracker := usage.NewTracker(usageSource, kubeClient, opts.Namespace, opts.Usage)
+	runnerImpl, err := runner.New(vetesClient, localStoreHelper, offloadHelper, accelerator, kubeClientNative, kubeClient,
+		mgr.GetEventRecorderFor(component), usageTracker, opts.Cluster.ID, opts.Namespace, opts.Runner)
 	if err != nil {
 		return err
 	}
 
-	if err = setupCrontab(mgr, vetesClient, localStoreHelper, offloadHelper, accelerator, runnerImpl, opts); err != nil {
+	if opts.Server.DebugTasks {
+		if err = setupDebugHandlers(mgr, runnerImpl); err != nil {
+			return fmt.Errorf("failed to set up debug handlers: %w", err)
+		}
+	}
+
+	enableFeatures(opts)
+
+	drainHelper := drain.NewHelper(kubeClient, opts.Namespace, opts.Drain)
+	reporter, syncerImpl, err := setupCrontab(mgr, vetesClient, caps, localStoreHelper, offloadHelper, accelerator, runnerImpl, usageTracker,
+		drainHelper, opts)
+	if err != nil {
 		return fmt.Errorf("failed to setup crontab: %w", err)
 	}
 
+	if err = mgr.Add(&reloader{
+		current:     opts,
+		flags:       flags,
+		runner:      runnerImpl,
+		reporter:    reporter,
+		syncer:      syncerImpl,
+		accelerator: accelerator,
+	}); err != nil {
+		return fmt.Errorf("failed to set up config reloader: %w", err)
+	}
+
 	if err = setupReconcilers(mgr, localStoreHelper, runnerImpl, opts); err != nil {
 		return fmt.Errorf("failed to set up reconcilers: %w", err)
 	}
@@ -119,31 +168,84 @@ func NewAgentCommand() (*cobra.Command, error) {
 	opts := options.NewOptions()
 	cmd := newAgentCommand(opts)
 
-	opts.AddFlags(cmd.Flags())
+	// subcommands share the config and flags of agent
+	opts.AddFlags(cmd.PersistentFlags())
 	version.AddFlags(cmd.Flags())
-	cmd.Flags().AddFlag(pflag.Lookup(viper.ConfigFlagName))
+	cmd.PersistentFlags().AddFlag(pflag.Lookup(viper.ConfigFlagName))
+	cmd.AddCommand(newTasksCommand(opts), newRenderCommand(opts), newDrainCommand(opts))
 	if err := viper.LoadConfig(opts); err != nil {
 		return nil, err
 	}
 	return cmd, nil
 }
 
-func setupCrontab(mgr ctrl.Manager, vetesClient vetesclient.Client, localStoreHelper localstore.Helper,
-	offloadHelper offload.Helper, accelerator accelerate.Accelerator, runnerImpl *runner.Runner, opts *options.Options) error {
+func setupCrontab(mgr ctrl.Manager, vetesClient vetesclient.Client, caps *vetesclient.Capabilities, localStoreHelper localstore.Helper,
+	offloadHelper offload.Helper, accelerator accelerate.Accelerator, runnerImpl *runner.Runner, usageTracker *usage.Tracker, drainHelper drain.Helper,
+	opts *options.Options) (
+	reporter *cluster.Reporter, syncerImpl *syncer.Syncer, err error) {
 	cron := crontab.NewCrontab()
-	if err := cluster.RegisterCronjob(cron, vetesClient, opts.Cluster); err != nil {
-		return err
+	if caps.ClusterReport {
+		if reporter, err = cluster.RegisterCronjob(cron, vetesClient, mgr.GetClient(), localStoreHelper, drainHelper, opts.Namespace, opts.Cluster); err != nil {
+			return nil, nil, err
+		}
 	}
-	if err := accelerate.RegisterCrontab(cron, accelerator); err != nil {
-		return err
+	if err = accelerate.RegisterCrontab(cron, accelerator); err != nil {
+		return nil, nil, err
 	}
-	if err := runner.RegisterCrontab(cron, runnerImpl); err != nil {
-		return err
+	if err = runner.RegisterCrontab(cron, runnerImpl); err != nil {
+		return nil, nil, err
+	}
+	if err = usage.RegisterCrontab(cron, usageTracker, opts.Usage); err != nil {
+		return nil, nil, err
+	}
+	if err = runner.RegisterResyncCrontab(cron, runnerImpl, caps); err != nil {
+		return nil, nil, err
+	}
+	if err = runner.RegisterGCCrontab(cron, runnerImpl); err != nil {
+		return nil, nil, err
+	}
+	if err = runner.RegisterDrainCrontab(cron, runnerImpl, drainHelper, opts.Drain); err != nil {
+		return nil, nil, err
+	}
+	if syncerImpl, err = syncer.RegisterCrontab(cron, vetesClient, localStoreHelper, offloadHelper, accelerator, drainHelper, opts.Cluster.ID, caps,
+		opts.Syncer); err != nil {
+		return nil, nil, err
+	}
+	return reporter, syncerImpl, mgr.Add(cron)
+}
+
+// enableFeatures records enabled features, which are reported with cluster
+func enableFeatures(opts *options.Options) {
+	version.EnableFeature(fmt.Sprintf("api-mode/%s", opts.VeTESClient.Mode))
+	version.EnableFeature(fmt.Sprintf("accelerate/%s", opts.Accelerate.Type))
+	if opts.Accelerate.Type == consts.CompositeAccelerateType {
+		for _, accelerateType := range opts.Accelerate.Chain {
+			version.EnableFeature(fmt.Sprintf("accelerate/%s", accelerateType))
+		}
+	}
+	if opts.Cluster.Discovery.Enable {
+		version.EnableFeature("cluster-discovery")
+	}
+	if opts.Runner.S3.Enable {
+		version.EnableFeature(fmt.Sprintf("s3/%s", opts.Runner.S3.Type))
+	}
+	if opts.Runner.Transfer.Enable {
+		version.EnableFeature("transfer")
 	}
-	if err := syncer.RegisterCrontab(cron, vetesClient, localStoreHelper, offloadHelper, accelerator, opts.Cluster.ID, opts.Syncer); err != nil {
+	if opts.Tracing.Endpoint != "" {
+		version.EnableFeature("tracing")
+	}
+	if opts.Usage.Enable {
+		version.EnableFeature(fmt.Sprintf("usage/%s", opts.Usage.Source))
+	}
+}
+
+func setupDebugHandlers(mgr ctrl.Manager, runnerImpl *runner.Runner) error {
+	handler := runnerImpl.DebugHandler()
+	if err := mgr.AddMetricsExtraHandler(runner.DebugTasksPath, handler); err != nil {
 		return err
 	}
-	return mgr.Add(cron)
+	return mgr.AddMetricsExtraHandler(runner.DebugTasksPath+"/", handler)
 }
 
 func setupReconcilers(mgr ctrl.Manager, localStoreHelper localstore.Helper, runnerImpl *runner.Runner, opts *options.Options) error {
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/app/options"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/drain"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+)
+
+func newDrainHelper(opts *options.Options) (drain.Helper, error) {
+	if err := opts.Drain.Validate(); err != nil {
+		return nil, err
+	}
+	_, kubeClient, err := newKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	return drain.NewHelper(kubeClient, opts.Namespace, opts.Drain), nil
+}
+
+func newDrainCommand(opts *options.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drain",
+		Short: "Toggle drain mode for cluster maintenance",
+		Long: "In drain mode, queued tasks are no longer claimed and zero capacity is reported, while running tasks finish as usual. " +
+			"Tasks still running after the optional deadline are failed or requeued by the drain deadline action.",
+	}
+	cmd.AddCommand(newDrainStartCommand(opts), newDrainStopCommand(opts), newDrainStatusCommand(opts))
+	return cmd
+}
+
+func newDrainStartCommand(opts *options.Options) *cobra.Command {
+	var deadline time.Duration
+	cmd := &cobra.Command{
+		Use:          "start",
+		Short:        "Start drain mode",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			drainHelper, err := newDrainHelper(opts)
+			if err != nil {
+				return err
+			}
+			var deadlineTime *time.Time
+			if deadline > 0 {
+				deadlineTime = utils.Point(time.Now().Add(deadline))
+			}
+			if err = drainHelper.Start(cmd.Context(), deadlineTime); err != nil {
+				return err
+			}
+			return printDrainState(cmd, drainHelper)
+		},
+	}
+	cmd.Flags().DurationVar(&deadline, "deadline", deadline, "stop tasks still running after the duration, no deadline if 0")
+	return cmd
+}
+
+func newDrainStopCommand(opts *options.Options) *cobra.Command {
+	return &cobra.Command{
+		Use:          "stop",
+		Short:        "Stop drain mode",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			drainHelper, err := newDrainHelper(opts)
+			if err != nil {
+				return err
+			}
+			if err = drainHelper.Stop(cmd.Context()); err != nil {
+				return err
+			}
+			return printDrainState(cmd, drainHelper)
+		},
+	}
+}
+
+func newDrainStatusCommand(opts *options.Options) *cobra.Command {
+	return &cobra.Command{
+		Use:          "status",
+		Short:        "Print drain mode",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			drainHelper, err := newDrainHelper(opts)
+			if err != nil {
+				return err
+			}
+			return printDrainState(cmd, drainHelper)
+		},
+	}
+}
+
+func printDrainState(cmd *cobra.Command, drainHelper drain.Helper) error {
+	state, err := drainHelper.State(cmd.Context())
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(state)
+}
@@ -6,10 +6,13 @@ import (
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/cluster"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/drain"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/offload"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/reconciler"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/reconciler/runner"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/syncer"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/tracing"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/usage"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
 )
 
@@ -17,6 +20,7 @@ import (
 type Options struct {
 	Log            *log.Options           `mapstructure:"log"`
 	VeTESClient    *vetesclient.Options   `mapstructure:"vetesClient"`
+	Tracing        *tracing.Options       `mapstructure:"tracing"`
 	LeaderElection *LeaderElectionOptions `mapstructure:"leaderElection"`
 	Server         *ServerOptions         `mapstructure:"server"`
 	Cluster        *cluster.Options       `mapstructure:"cluster"`
@@ -25,6 +29,8 @@ type Options struct {
 	Offload        *offload.Options       `mapstructure:"offload"`
 	Runner         *runner.Options        `mapstructure:"runner"`
 	Accelerate     *accelerate.Options    `mapstructure:"accelerate"`
+	Usage          *usage.Options         `mapstructure:"usage"`
+	Drain          *drain.Options         `mapstructure:"drain"`
 	Namespace      string                 `mapstructure:"namespace"`
 }
 
@@ -33,6 +39,7 @@ func NewOptions() *Options {
 	return &Options{
 		Log:            log.NewOptions(),
 		VeTESClient:    vetesclient.NewOptions(),
+		Tracing:        tracing.NewOptions(),
 		LeaderElection: NewLeaderElectionOptions(),
 		Server:         NewServerOptions(),
 		Cluster:        cluster.NewOptions(),
@@ -41,6 +48,8 @@ func NewOptions() *Options {
 		Offload:        offload.NewOptions(),
 		Runner:         runner.NewOptions(),
 		Accelerate:     accelerate.NewOptions(),
+		Usage:          usage.NewOptions(),
+		Drain:          drain.NewOptions(),
 		Namespace:      "vetes-system",
 	}
 }
@@ -53,6 +62,9 @@ func (o *Options) Validate() error {
 	if err := o.VeTESClient.Validate(); err != nil {
 		return err
 	}
+	if err := o.Tracing.Validate(); err != nil {
+		return err
+	}
 	if err := o.LeaderElection.Validate(); err != nil {
 		return err
 	}
@@ -77,6 +89,12 @@ func (o *Options) Validate() error {
 	if err := o.Accelerate.Validate(); err != nil {
 		return err
 	}
+	if err := o.Usage.Validate(); err != nil {
+		return err
+	}
+	if err := o.Drain.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -84,6 +102,7 @@ func (o *Options) Validate() error {
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	o.Log.AddFlags(fs)
 	o.VeTESClient.AddFlags(fs)
+	o.Tracing.AddFlags(fs)
 	o.LeaderElection.AddFlags(fs)
 	o.Server.AddFlags(fs)
 	o.Cluster.AddFlags(fs)
@@ -92,5 +111,7 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	o.Offload.AddFlags(fs)
 	o.Runner.AddFlags(fs)
 	o.Accelerate.AddFlags(fs)
+	o.Usage.AddFlags(fs)
+	o.Drain.AddFlags(fs)
 	fs.StringVarP(&o.Namespace, "namespace", "n", o.Namespace, "namespace for running tasks")
 }
@@ -8,6 +8,9 @@ import (
 type ServerOptions struct {
 	HealthzPort uint16 `mapstructure:"healthzPort"`
 	MetricsPort uint16 `mapstructure:"metricsPort"`
+	// DebugTasks serves the read-only introspection api of in-flight tasks on metrics port.
+	// It exposes task details without auth, so enable it only behind network policy or an auth proxy.
+	DebugTasks bool `mapstructure:"debugTasks"`
 }
 
 // NewServerOptions ...
@@ -27,4 +30,5 @@ func (o *ServerOptions) Validate() error {
 func (o *ServerOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.Uint16Var(&o.HealthzPort, "http-healthz-port", o.HealthzPort, "http port to listen on for healthz")
 	fs.Uint16Var(&o.MetricsPort, "http-metrics-port", o.MetricsPort, "http port to listen on for metrics")
+	fs.BoolVar(&o.DebugTasks, "http-debug-tasks", o.DebugTasks, "serve introspection api of in-flight tasks on metrics port, without auth so it needs network policy or an auth proxy in front")
 }
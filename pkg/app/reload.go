@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+	"github.com/spf13/pflag"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/app/options"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/cluster"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/reconciler/runner"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/syncer"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/viper"
+)
+
+// reloader watches config files, and applies the reloadable subset of config without restart.
+// It runs on all replicas, because runner options are used by non-leader replicas as well.
+type reloader struct {
+	// current is the options the agent started with, which are compared with reloaded options to warn about
+	// changes requiring restart. It is not replaced by reloaded options, because only a subset of them is applied.
+	current *options.Options
+	// flags are the command line flags, which override reloaded config as they do at startup
+	flags       *pflag.FlagSet
+	runner      *runner.Runner
+	reporter    *cluster.Reporter // nil if cluster is not reported
+	syncer      *syncer.Syncer
+	accelerator accelerate.Accelerator
+}
+
+// watchRetryPeriod is the interval of watching config file again after watch fails, e.g. the file is missing
+const watchRetryPeriod = 10 * time.Second
+
+// Start ...
+func (r *reloader) Start(ctx context.Context) error {
+	if r.reporter != nil {
+		go watchConfig(ctx, "cluster", r.reporter.ConfigPath(), r.reloadClusterConfig)
+	}
+	watchConfig(ctx, "agent", viper.ConfigFileUsed(), func() { r.reloadAgentConfig(ctx) })
+	return nil
+}
+
+// watchConfig watches the config file until ctx is done. Failing to watch is retried instead of stopping the manager,
+// and the file is reloaded once watched again, because changes in the meantime are missed.
+func watchConfig(ctx context.Context, component, path string, onChange func()) {
+	for {
+		err := viper.WatchFile(ctx, path, onChange)
+		if err == nil {
+			return
+		}
+		log.Errorw("failed to watch config, retry later", "component", component, "path", path, "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryPeriod):
+		}
+		onChange()
+	}
+}
+
+// NeedLeaderElection ...
+func (r *reloader) NeedLeaderElection() bool {
+	return false
+}
+
+func (r *reloader) reloadAgentConfig(ctx context.Context) {
+	opts, err := reloadOptions(r.flags)
+	if err != nil {
+		log.Errorw("failed to reload config", "err", err)
+		metrics.ConfigReloadTotal.WithLabelValues("agent", metrics.ReloadInvalidConfig).Inc()
+		return
+	}
+	if err = opts.Validate(); err != nil {
+		log.Errorw("reloaded config is invalid", "err", err)
+		metrics.ConfigReloadTotal.WithLabelValues("agent", metrics.ReloadInvalidConfig).Inc()
+		return
+	}
+
+	needRestart, err := r.runner.UpdateOptions(ctx, opts.Runner)
+	recordReload("runner", needRestart, err)
+
+	r.syncer.SetConcurrency(opts.Syncer.Concurrency)
+	recordReload("syncer", opts.Syncer.Period != r.current.Syncer.Period ||
+		opts.Syncer.TagKey != r.current.Syncer.TagKey || opts.Syncer.TagValue != r.current.Syncer.TagValue, nil)
+
+	needRestart, err = accelerate.UpdateOptions(r.accelerator, opts.Accelerate)
+	recordReload("accelerate", needRestart, err)
+
+	if !reflect.DeepEqual(opts.Log, r.current.Log) || !reflect.DeepEqual(opts.VeTESClient, r.current.VeTESClient) ||
+		!reflect.DeepEqual(opts.Tracing, r.current.Tracing) || !reflect.DeepEqual(opts.Usage, r.current.Usage) ||
+		!reflect.DeepEqual(opts.Drain, r.current.Drain) ||
+		!reflect.DeepEqual(opts.LeaderElection, r.current.LeaderElection) || !reflect.DeepEqual(opts.Server, r.current.Server) ||
+		!reflect.DeepEqual(opts.Cluster, r.current.Cluster) || !reflect.DeepEqual(opts.Reconciler, r.current.Reconciler) ||
+		!reflect.DeepEqual(opts.Offload, r.current.Offload) || opts.Namespace != r.current.Namespace {
+		recordReload("agent", true, nil)
+	}
+}
+
+// reloadOptions reads the config file again, and applies command line flags on top of it
+func reloadOptions(flags *pflag.FlagSet) (*options.Options, error) {
+	opts := options.NewOptions()
+	if err := viper.ReloadConfig(opts); err != nil {
+		return nil, err
+	}
+	fs := pflag.NewFlagSet(component, pflag.ContinueOnError)
+	opts.AddFlags(fs)
+	if err := viper.ApplyFlags(fs, flags); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+func (r *reloader) reloadClusterConfig() {
+	recordReload("cluster", false, r.reporter.ReloadConfig())
+}
+
+func recordReload(component string, needRestart bool, err error) {
+	switch {
+	case err != nil:
+		log.Errorw("failed to reload config", "component", component, "err", err)
+		metrics.ConfigReloadTotal.WithLabelValues(component, metrics.ReloadFailed).Inc()
+	case needRestart:
+		log.Warnw("config reloaded, some changes require restart to take effect", "component", component)
+		metrics.ConfigReloadTotal.WithLabelValues(component, metrics.ReloadNeedRestart).Inc()
+	default:
+		log.Infow("config reloaded", "component", component)
+		metrics.ConfigReloadTotal.WithLabelValues(component, metrics.ReloadSucceeded).Inc()
+	}
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/app/options"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/viper"
+)
+
+func TestReloadOptionsKeepsFlags(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	g.Expect(os.WriteFile(configFile, []byte("runner:\n  podPollInterval: 2m\n  filerRetries: 5\n"), 0644)).To(gomega.Succeed())
+	g.Expect(pflag.Set(viper.ConfigFlagName, configFile)).To(gomega.Succeed())
+	opts := options.NewOptions()
+	g.Expect(viper.LoadConfig(opts)).To(gomega.Succeed())
+
+	flags := pflag.NewFlagSet(component, pflag.ContinueOnError)
+	opts.AddFlags(flags)
+	g.Expect(flags.Parse([]string{"-n", "vetes", "--pod-poll-interval", "3m", "--filer-pod-labels", "a=1,b=2"})).To(gomega.Succeed())
+
+	// config file is changed
+	g.Expect(os.WriteFile(configFile, []byte("runner:\n  podPollInterval: 4m\n  filerRetries: 6\n"), 0644)).To(gomega.Succeed())
+	reloaded, err := reloadOptions(flags)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(reloaded.Namespace).To(gomega.Equal("vetes"))
+	g.Expect(reloaded.Runner.PodPollInterval).To(gomega.Equal(time.Minute * 3))
+	g.Expect(reloaded.Runner.FilerPodLabels).To(gomega.Equal(map[string]string{"a": "1", "b": "2"}))
+	g.Expect(reloaded.Runner.FilerRetries).To(gomega.Equal(6))
+}
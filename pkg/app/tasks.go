@@ -0,0 +1,265 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/app/options"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/offload"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/reconciler/runner"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/syncer"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+// admin is the runner and clients built from the agent config for operator subcommands.
+// Processing state and last errors of tasks are in the running agent, see runner.DebugTasksPath.
+type admin struct {
+	runner      *runner.Runner
+	vetesClient vetesclient.Client
+}
+
+func newAdmin(opts *options.Options) (*admin, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	kubeConfig, kubeClient, err := newKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	kubeClientNative, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create native kube client: %w", err)
+	}
+
+	vetesClient := vetesclient.NewClient(opts.VeTESClient)
+	offloadHelper, err := offload.NewHelper(opts.Offload)
+	if err != nil {
+		return nil, err
+	}
+	localStoreHelper := localstore.NewHelper(kubeClient, opts.Namespace)
+	accelerator, err := accelerate.NewAccelerator(vetesClient, kubeClient, localStoreHelper, opts.Namespace, opts.Accelerate)
+	if err != nil {
+		return nil, err
+	}
+	runnerImpl, err := runner.New(vetesClient, localStoreHelper, offloadHelper, accelerator, kubeClientNative, kubeClient,
+		nil, nil, opts.Cluster.ID, opts.Namespace, opts.Runner)
+	if err != nil {
+		return nil, err
+	}
+	return &admin{runner: runnerImpl, vetesClient: vetesClient}, nil
+}
+
+func newKubeClient() (*rest.Config, ctrlclient.Client, error) {
+	kubeConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	kubeClient, err := ctrlclient.New(kubeConfig, ctrlclient.Options{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create kube client: %w", err)
+	}
+	return kubeConfig, kubeClient, nil
+}
+
+func newTasksCommand(opts *options.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tasks",
+		Short: "Inspect and repair tasks in local store",
+	}
+	cmd.AddCommand(
+		newTasksListCommand(opts),
+		newTasksDescribeCommand(opts),
+		newTasksForceStopCommand(opts),
+		newTasksCleanupOrphansCommand(opts),
+	)
+	return cmd
+}
+
+func newTasksListCommand(opts *options.Options) *cobra.Command {
+	return &cobra.Command{
+		Use:          "list",
+		Short:        "List tasks in local store with decoded stages",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := newAdmin(opts)
+			if err != nil {
+				return err
+			}
+			tasks, err := a.runner.ListTasks(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printTasks(cmd.OutOrStdout(), tasks)
+		},
+	}
+}
+
+func printTasks(out io.Writer, tasks []*runner.DebugTask) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTAGE\tEXECUTOR\tSTOP")
+	for _, task := range tasks {
+		executor := "-"
+		if task.ExecutorIndex != nil {
+			executor = fmt.Sprintf("%d/%s", *task.ExecutorIndex, task.ExecutorStatus)
+		}
+		stop := task.Stop
+		if stop == "" {
+			stop = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", task.ID, task.Name, task.Stage, executor, stop)
+	}
+	return w.Flush()
+}
+
+func newTasksDescribeCommand(opts *options.Options) *cobra.Command {
+	return &cobra.Command{
+		Use:          "describe <id>",
+		Short:        "Describe a task with its local store, kubernetes objects and state in vetes-api",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := newAdmin(opts)
+			if err != nil {
+				return err
+			}
+			task, err := a.runner.DescribeTask(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if task.RemoteState, err = a.remoteState(cmd.Context(), task.ID); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "failed to get task from vetes-api: %v\n", err)
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(task)
+		},
+	}
+}
+
+func (a *admin) remoteState(ctx context.Context, taskID string) (string, error) {
+	resp, err := a.vetesClient.GetTask(ctx, &models.GetTaskRequest{ID: taskID, View: consts.MinimalView})
+	if err != nil {
+		return "", err
+	}
+	return resp.State, nil
+}
+
+func newTasksForceStopCommand(opts *options.Options) *cobra.Command {
+	state := consts.TaskSystemError
+	cmd := &cobra.Command{
+		Use:   "force-stop <id>",
+		Short: "Mark a task stopped in local store, the running agent stops and cleans it",
+		Long: "Mark a task stopped in local store instead of editing annotations of its configmap. " +
+			"The running agent deletes its jobs and pvc, and updates it to the state in vetes-api.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := newAdmin(opts)
+			if err != nil {
+				return err
+			}
+			if err = a.runner.ForceStopTask(cmd.Context(), args[0], state); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "task %s is marked stopped with %s\n", args[0], state)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&state, "state", state, fmt.Sprintf("state to stop the task with, one of %s",
+		strings.Join(runner.ForceStopStates, ", ")))
+	return cmd
+}
+
+func newTasksCleanupOrphansCommand(opts *options.Options) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "cleanup-orphans",
+		Short: "Strip finalizers of jobs, pods and pvcs whose task is not in local store, and delete them",
+		Long: "Strip finalizers of jobs, pods and pvcs whose task is not in local store, and delete them like the gc of " +
+			"the running agent. Objects created within --gc-grace-period are skipped.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := newAdmin(opts)
+			if err != nil {
+				return err
+			}
+			orphans, err := a.runner.CleanupOrphans(cmd.Context(), dryRun)
+			action := "deleted"
+			if dryRun {
+				action = "found"
+			}
+			for _, orphan := range orphans {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", action, orphan)
+			}
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", dryRun, "only list orphans")
+	return cmd
+}
+
+func newRenderCommand(opts *options.Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "render <task.json>",
+		Short: "Print the pvc and jobs the runner would create for a full view task",
+		Long: "Print the pvc and jobs the runner would create for a full view task of vetes-api. " +
+			"Inputs and outputs are not offloaded, and inputs are not rewritten by accelerators.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read task: %w", err)
+			}
+			task := &models.Task{}
+			if err = json.Unmarshal(content, task); err != nil {
+				return fmt.Errorf("failed to unmarshal task: %w", err)
+			}
+			localTask, err := syncer.TaskToStore(task)
+			if err != nil {
+				return err
+			}
+			a, err := newAdmin(opts)
+			if err != nil {
+				return err
+			}
+			return printObjects(cmd.OutOrStdout(), a.runner.RenderTask(localTask))
+		},
+	}
+}
+
+func printObjects(out io.Writer, objs []ctrlclient.Object) error {
+	for _, obj := range objs {
+		gvk, err := apiutil.GVKForObject(obj, clientgoscheme.Scheme)
+		if err != nil {
+			return err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		content, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s %s: %w", gvk.Kind, obj.GetName(), err)
+		}
+		fmt.Fprintf(out, "---\n%s", content)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+)
+
+const gigabyte = 1 << 30
+
+// capacityProvider discovers capacity and limits from allocatable of live nodes
+type capacityProvider struct {
+	kubeClient ctrlclient.Client
+	selector   labels.Selector
+	headroom   float64
+}
+
+func newCapacityProvider(kubeClient ctrlclient.Client, opts *DiscoveryOptions) (*capacityProvider, error) {
+	selector, err := labels.Parse(opts.NodeSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node selector %s: %w", opts.NodeSelector, err)
+	}
+	return &capacityProvider{
+		kubeClient: kubeClient,
+		selector:   selector,
+		headroom:   opts.Headroom,
+	}, nil
+}
+
+// discover sums allocatable of schedulable nodes as capacity, and takes maximum of a single node as limits.
+// Headroom is reserved from capacity, which is no less than limits.
+// GPUs are grouped by GPUNameAffinityKey label of nodes.
+func (p *capacityProvider) discover(ctx context.Context) (*Config, error) {
+	nodeList := new(corev1.NodeList)
+	if err := p.kubeClient.List(ctx, nodeList, ctrlclient.MatchingLabelsSelector{Selector: p.selector}); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var totalCPU, totalMemory, maxCPU, maxMemory resource.Quantity
+	totalGPU := make(map[string]int64)
+	maxGPU := make(map[string]int64)
+	for index := range nodeList.Items {
+		node := &nodeList.Items[index]
+		if !nodeSchedulable(node) {
+			continue
+		}
+		cpu := node.Status.Allocatable[corev1.ResourceCPU]
+		memory := node.Status.Allocatable[corev1.ResourceMemory]
+		totalCPU.Add(cpu)
+		totalMemory.Add(memory)
+		if cpu.Cmp(maxCPU) > 0 {
+			maxCPU = cpu
+		}
+		if memory.Cmp(maxMemory) > 0 {
+			maxMemory = memory
+		}
+
+		gpu, ok := node.Status.Allocatable[consts.NvidiaGPUResource]
+		gpuName := node.Labels[consts.GPUNameAffinityKey]
+		if !ok || gpu.IsZero() || gpuName == "" {
+			continue
+		}
+		totalGPU[gpuName] += gpu.Value()
+		if gpu.Value() > maxGPU[gpuName] {
+			maxGPU[gpuName] = gpu.Value()
+		}
+	}
+
+	// headroom is reserved from capacity only, a task fitting the largest node should not be rejected
+	limits := &Limits{
+		CPUCores: cpuCores(maxCPU, 0),
+		RamGB:    ramGB(maxMemory, 0),
+		GPULimit: &GPULimit{GPU: gpus(maxGPU, 0)},
+	}
+	capacity := &Capacity{
+		CPUCores:    cpuCores(totalCPU, p.headroom),
+		RamGB:       utils.Point(math.Max(*ramGB(totalMemory, p.headroom), *limits.RamGB)),
+		GPUCapacity: &GPUCapacity{GPU: gpus(totalGPU, p.headroom)},
+	}
+	if *capacity.CPUCores < *limits.CPUCores {
+		capacity.CPUCores = limits.CPUCores
+	}
+	for gpuName, count := range limits.GPULimit.GPU {
+		capacity.GPUCapacity.GPU[gpuName] = math.Max(capacity.GPUCapacity.GPU[gpuName], count)
+	}
+	return &Config{Capacity: capacity, Limits: limits}, nil
+}
+
+func cpuCores(quantity resource.Quantity, headroom float64) *int {
+	res := int(math.Floor(float64(quantity.MilliValue()) * (1 - headroom) / 1000))
+	return &res
+}
+
+func ramGB(quantity resource.Quantity, headroom float64) *float64 {
+	res := math.Floor(float64(quantity.Value())*(1-headroom)/gigabyte*100) / 100
+	return &res
+}
+
+func gpus(counts map[string]int64, headroom float64) map[string]float64 {
+	res := make(map[string]float64, len(counts))
+	for gpuName, count := range counts {
+		res[gpuName] = math.Floor(float64(count) * (1 - headroom))
+	}
+	return res
+}
+
+func nodeSchedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// overrideConfig overrides discovered config by static config
+func overrideConfig(discovered, static *Config) *Config {
+	if static == nil {
+		return discovered
+	}
+	res := &Config{Capacity: discovered.Capacity, Limits: discovered.Limits}
+	if static.Capacity != nil {
+		capacity := *res.Capacity
+		if static.Capacity.Count != nil {
+			capacity.Count = static.Capacity.Count
+		}
+		if static.Capacity.CPUCores != nil {
+			capacity.CPUCores = static.Capacity.CPUCores
+		}
+		if static.Capacity.RamGB != nil {
+			capacity.RamGB = static.Capacity.RamGB
+		}
+		if static.Capacity.DiskGB != nil {
+			capacity.DiskGB = static.Capacity.DiskGB
+		}
+		if static.Capacity.GPUCapacity != nil {
+			capacity.GPUCapacity = static.Capacity.GPUCapacity
+		}
+		res.Capacity = &capacity
+	}
+	if static.Limits != nil {
+		limits := *res.Limits
+		if static.Limits.CPUCores != nil {
+			limits.CPUCores = static.Limits.CPUCores
+		}
+		if static.Limits.RamGB != nil {
+			limits.RamGB = static.Limits.RamGB
+		}
+		if static.Limits.GPULimit != nil {
+			limits.GPULimit = static.Limits.GPULimit
+		}
+		res.Limits = &limits
+	}
+	return res
+}
@@ -4,42 +4,148 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
 	"gopkg.in/yaml.v3"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/crontab"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/drain"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/version"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
 )
 
-type reporter struct {
+// Reporter reports cluster to vetes-api periodically
+type Reporter struct {
 	vetesClient vetesclient.Client
 	id          string
+	configPath  string
+	cfgLock     sync.RWMutex
 	cfg         *Config
+	provider    *capacityProvider // nil means only report static config
+	usage       *usageCollector   // nil means not report usage
+	drain       drain.Helper      // nil means never drained
+
+	// discovered is the last successful discovery, which is reported if discovery fails,
+	// so that the cluster never misses heartbeat. Only accessed by the cron, which never runs concurrently.
+	discovered *Config
 }
 
 // RegisterCronjob ...
-func RegisterCronjob(cron *crontab.Crontab, vetesClient vetesclient.Client, opts *Options) error {
-	data, err := os.ReadFile(opts.ConfigPath)
+func RegisterCronjob(cron *crontab.Crontab, vetesClient vetesclient.Client, kubeClient ctrlclient.Client,
+	localStoreHelper localstore.Helper, drainHelper drain.Helper, namespace string, opts *Options) (*Reporter, error) {
+	cfg, err := readConfig(opts.ConfigPath)
 	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", opts.ConfigPath, err)
-	}
-	cfg := new(Config)
-	if err = yaml.Unmarshal(data, cfg); err != nil {
-		return fmt.Errorf("failed to unmarshal cluster config: %w", err)
+		return nil, err
 	}
 
-	r := &reporter{
+	r := &Reporter{
 		vetesClient: vetesClient,
 		id:          opts.ID,
+		configPath:  opts.ConfigPath,
 		cfg:         cfg,
+		usage: &usageCollector{
+			kubeClient:       kubeClient,
+			localStoreHelper: localStoreHelper,
+			namespace:        namespace,
+			now:              time.Now,
+		},
+		drain: drainHelper,
+	}
+	if opts.Discovery.Enable {
+		if r.provider, err = newCapacityProvider(kubeClient, &opts.Discovery); err != nil {
+			return nil, err
+		}
+	}
+	if err = cron.RegisterCron(opts.ReportPeriod, r.reportCluster); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ConfigPath returns path of the cluster config file
+func (r *Reporter) ConfigPath() string {
+	return r.configPath
+}
+
+// ReloadConfig reads cluster config file again, and reports it from next period
+func (r *Reporter) ReloadConfig() error {
+	cfg, err := readConfig(r.configPath)
+	if err != nil {
+		return err
+	}
+	r.cfgLock.Lock()
+	defer r.cfgLock.Unlock()
+	r.cfg = cfg
+	return nil
+}
+
+func readConfig(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+	cfg := new(Config)
+	if err = yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cluster config: %w", err)
+	}
+	if err = cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cluster config: %w", err)
 	}
-	return cron.RegisterCron(opts.ReportPeriod, r.reportCluster)
+	return cfg, nil
 }
 
-func (r *reporter) reportCluster() {
+func (r *Reporter) config() *Config {
+	r.cfgLock.RLock()
+	defer r.cfgLock.RUnlock()
+	return r.cfg
+}
+
+func (r *Reporter) reportCluster() {
 	ctx := context.Background()
-	req := convertToClientCluster(r.id, r.cfg)
+	cfg := r.config()
+	if r.provider != nil {
+		discovered, err := r.provider.discover(ctx)
+		if err != nil {
+			// static config is reported if discovery never succeeds
+			log.Errorw("discover cluster capacity failed, report the last discovery", "err", err)
+			discovered = r.discovered
+		}
+		if discovered != nil {
+			r.discovered = discovered
+			cfg = overrideConfig(discovered, cfg)
+		}
+	}
+	req := convertToClientCluster(r.id, cfg)
+	if r.drain != nil {
+		drainState, err := r.drain.State(ctx)
+		if err != nil {
+			log.Errorw("get drain state failed", "err", err)
+			return
+		}
+		if drainState.Draining {
+			// vetes-api schedules no more tasks to the cluster, running tasks are still reported in usage
+			req.Capacity = zeroCapacity(req.Capacity)
+		}
+	}
+	if r.usage != nil {
+		usage, err := r.usage.collect(ctx)
+		if err != nil {
+			// capacity is still worth reporting without usage
+			log.Errorw("collect cluster usage failed", "err", err)
+		}
+		req.Usage = usage
+	}
+	versionInfo := version.Get()
+	req.Agent = &models.Agent{
+		Version:   versionInfo.Version,
+		GitCommit: versionInfo.GitCommit,
+		Features:  version.Features(),
+	}
 	if _, err := r.vetesClient.PutCluster(ctx, req); err != nil {
 		log.Errorw("put cluster failed", "err", err)
 	}
@@ -1,11 +1,24 @@
 package cluster
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/drain"
+	drainfake "github.com/GBA-BI/tes-k8s-agent/pkg/drain/fake"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
 	vetesclientfake "github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/fake"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
@@ -41,6 +54,7 @@ var (
 			RamGB:    utils.Point[float64](10),
 			GPULimit: &models.GPULimit{GPU: map[string]float64{"type-01": 1}},
 		},
+		Agent: &models.Agent{Features: []string{}},
 	}
 )
 
@@ -52,10 +66,195 @@ func TestReportCluster(t *testing.T) {
 	fakeVeTESClient := vetesclientfake.NewFakeClient(ctrl)
 	fakeVeTESClient.EXPECT().PutCluster(gomock.Any(), fakePutClusterReq).Return(&models.PutClusterResponse{}, nil)
 
-	r := &reporter{
+	r := &Reporter{
 		vetesClient: fakeVeTESClient,
 		id:          fakeClusterID,
 		cfg:         fakeConfig,
 	}
 	g.Expect(func() { r.reportCluster() }).NotTo(gomega.Panic())
 }
+
+func TestReportClusterDraining(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeVeTESClient := vetesclientfake.NewFakeClient(ctrl)
+	fakeVeTESClient.EXPECT().PutCluster(gomock.Any(), &models.PutClusterRequest{
+		ID: fakeClusterID,
+		Capacity: &models.Capacity{
+			Count:       utils.Point(0),
+			CPUCores:    utils.Point(0),
+			RamGB:       utils.Point[float64](0),
+			DiskGB:      utils.Point[float64](0),
+			GPUCapacity: &models.GPUCapacity{GPU: map[string]float64{"type-01": 0}},
+		},
+		Limits: fakePutClusterReq.Limits,
+		Agent:  fakePutClusterReq.Agent,
+	}).Return(&models.PutClusterResponse{}, nil)
+	fakeDrainHelper := drainfake.NewFakeHelper(ctrl)
+	fakeDrainHelper.EXPECT().State(gomock.Any()).Return(&drain.State{Draining: true}, nil)
+
+	r := &Reporter{
+		vetesClient: fakeVeTESClient,
+		id:          fakeClusterID,
+		cfg:         fakeConfig,
+		drain:       fakeDrainHelper,
+	}
+	g.Expect(func() { r.reportCluster() }).NotTo(gomega.Panic())
+}
+
+func fakeNode(name string, ready bool, cpu, memory, gpu, gpuName string) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"pool": "vetes"},
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	if !ready {
+		node.Status.Conditions[0].Status = corev1.ConditionFalse
+	}
+	if gpu != "" {
+		node.Labels[consts.GPUNameAffinityKey] = gpuName
+		node.Status.Allocatable[consts.NvidiaGPUResource] = resource.MustParse(gpu)
+	}
+	return node
+}
+
+func TestReportClusterDiscovery(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	otherPoolNode := fakeNode("node-04", true, "64", "256Gi", "", "")
+	otherPoolNode.Labels["pool"] = "other"
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(
+		fakeNode("node-01", true, "8", "32Gi", "", ""),
+		fakeNode("node-02", true, "16", "64Gi", "2", "type-01"),
+		fakeNode("node-03", false, "32", "128Gi", "4", "type-01"),
+		otherPoolNode,
+	).Build()
+	provider, err := newCapacityProvider(fakeKubeClient, &DiscoveryOptions{NodeSelector: "pool=vetes", Headroom: 0.25})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	fakeVeTESClient := vetesclientfake.NewFakeClient(ctrl)
+	fakeVeTESClient.EXPECT().PutCluster(gomock.Any(), &models.PutClusterRequest{
+		ID: fakeClusterID,
+		Capacity: &models.Capacity{
+			Count:       utils.Point(10),
+			CPUCores:    utils.Point(18),
+			RamGB:       utils.Point[float64](72),
+			GPUCapacity: &models.GPUCapacity{GPU: map[string]float64{"type-01": 2}},
+		},
+		Limits: &models.Limits{
+			CPUCores: utils.Point(16),
+			RamGB:    utils.Point[float64](10),
+			GPULimit: &models.GPULimit{GPU: map[string]float64{"type-01": 2}},
+		},
+		Agent: &models.Agent{Features: []string{}},
+	}).Return(&models.PutClusterResponse{}, nil)
+
+	r := &Reporter{
+		vetesClient: fakeVeTESClient,
+		id:          fakeClusterID,
+		cfg: &Config{
+			Capacity: &Capacity{Count: utils.Point(10)},
+			Limits:   &Limits{RamGB: utils.Point[float64](10)},
+		},
+		provider: provider,
+	}
+	g.Expect(func() { r.reportCluster() }).NotTo(gomega.Panic())
+}
+
+func TestReportClusterDiscoveryFailed(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	listFailed := false
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(
+		fakeNode("node-01", true, "16", "64Gi", "", ""),
+	).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, client ctrlclient.WithWatch, list ctrlclient.ObjectList, opts ...ctrlclient.ListOption) error {
+			if listFailed {
+				return errors.New("connection refused")
+			}
+			return client.List(ctx, list, opts...)
+		},
+	}).Build()
+	provider, err := newCapacityProvider(fakeKubeClient, &DiscoveryOptions{NodeSelector: "pool=vetes"})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	fakeVeTESClient := vetesclientfake.NewFakeClient(ctrl)
+	r := &Reporter{
+		vetesClient: fakeVeTESClient,
+		id:          fakeClusterID,
+		cfg:         &Config{Capacity: &Capacity{Count: utils.Point(10)}},
+		provider:    provider,
+	}
+
+	// static config is reported before any discovery succeeds
+	fakeVeTESClient.EXPECT().PutCluster(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, req *models.PutClusterRequest) (*models.PutClusterResponse, error) {
+			g.Expect(*req.Capacity.Count).To(gomega.Equal(10))
+			g.Expect(req.Capacity.CPUCores).To(gomega.BeNil())
+			return &models.PutClusterResponse{}, nil
+		})
+	listFailed = true
+	r.reportCluster()
+
+	// the last discovery is reported once discovery fails
+	var discovered *models.PutClusterRequest
+	fakeVeTESClient.EXPECT().PutCluster(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, req *models.PutClusterRequest) (*models.PutClusterResponse, error) {
+			g.Expect(*req.Capacity.CPUCores).To(gomega.Equal(16))
+			discovered = req
+			return &models.PutClusterResponse{}, nil
+		})
+	listFailed = false
+	r.reportCluster()
+	fakeVeTESClient.EXPECT().PutCluster(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, req *models.PutClusterRequest) (*models.PutClusterResponse, error) {
+			g.Expect(req).To(gomega.Equal(discovered))
+			return &models.PutClusterResponse{}, nil
+		})
+	listFailed = true
+	r.reportCluster()
+}
+
+func TestDiscoverSingleGPUNode(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(fakeNode("node-01", true, "1", "4Gi", "1", "type-01")).Build()
+	provider, err := newCapacityProvider(fakeKubeClient, &DiscoveryOptions{NodeSelector: "pool=vetes", Headroom: 0.1})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	cfg, err := provider.discover(context.Background())
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(cfg.Limits.CPUCores).To(gomega.Equal(utils.Point(1)))
+	g.Expect(cfg.Limits.RamGB).To(gomega.Equal(utils.Point[float64](4)))
+	g.Expect(cfg.Limits.GPULimit.GPU).To(gomega.Equal(map[string]float64{"type-01": 1}))
+	g.Expect(cfg.Capacity.CPUCores).To(gomega.Equal(utils.Point(1)))
+	g.Expect(cfg.Capacity.GPUCapacity.GPU).To(gomega.Equal(map[string]float64{"type-01": 1}))
+}
+
+func TestReloadInvalidConfig(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	configPath := filepath.Join(t.TempDir(), "cluster.yaml")
+	g.Expect(os.WriteFile(configPath, []byte("capacity:\n  cpu_cores: 8\n"), 0644)).To(gomega.Succeed())
+	r := &Reporter{configPath: configPath}
+	g.Expect(r.ReloadConfig()).To(gomega.Succeed())
+	g.Expect(r.config().Capacity.CPUCores).To(gomega.Equal(utils.Point(8)))
+
+	g.Expect(os.WriteFile(configPath, []byte("capacity:\n  cpu_cores: -8\n"), 0644)).To(gomega.Succeed())
+	g.Expect(r.ReloadConfig()).NotTo(gomega.Succeed())
+	g.Expect(r.config().Capacity.CPUCores).To(gomega.Equal(utils.Point(8)))
+}
@@ -1,6 +1,11 @@
 package cluster
 
-import "github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+import (
+	"fmt"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
 
 // Config ...
 type Config struct {
@@ -34,6 +39,69 @@ type GPULimit struct {
 	GPU map[string]float64 `yaml:"gpu,omitempty"`
 }
 
+// Validate ...
+func (c *Config) Validate() error {
+	if c.Capacity != nil {
+		if err := validateCount("capacity.count", c.Capacity.Count); err != nil {
+			return err
+		}
+		if err := validateCount("capacity.cpu_cores", c.Capacity.CPUCores); err != nil {
+			return err
+		}
+		if err := validateAmount("capacity.ram_gb", c.Capacity.RamGB); err != nil {
+			return err
+		}
+		if err := validateAmount("capacity.disk_gb", c.Capacity.DiskGB); err != nil {
+			return err
+		}
+		if c.Capacity.GPUCapacity != nil {
+			if err := validateGPU("capacity.gpu_capacity", c.Capacity.GPUCapacity.GPU); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Limits != nil {
+		if err := validateCount("limits.cpu_cores", c.Limits.CPUCores); err != nil {
+			return err
+		}
+		if err := validateAmount("limits.ram_gb", c.Limits.RamGB); err != nil {
+			return err
+		}
+		if c.Limits.GPULimit != nil {
+			if err := validateGPU("limits.gpu_limit", c.Limits.GPULimit.GPU); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateCount(name string, value *int) error {
+	if value != nil && *value < 0 {
+		return fmt.Errorf("%s %d should not be negative", name, *value)
+	}
+	return nil
+}
+
+func validateAmount(name string, value *float64) error {
+	if value != nil && *value < 0 {
+		return fmt.Errorf("%s %v should not be negative", name, *value)
+	}
+	return nil
+}
+
+func validateGPU(name string, gpus map[string]float64) error {
+	for gpuName, count := range gpus {
+		if gpuName == "" {
+			return fmt.Errorf("%s has empty gpu name", name)
+		}
+		if count < 0 {
+			return fmt.Errorf("%s of %s %v should not be negative", name, gpuName, count)
+		}
+	}
+	return nil
+}
+
 func convertToClientCluster(id string, cfg *Config) *models.PutClusterRequest {
 	res := &models.PutClusterRequest{ID: id}
 	if cfg == nil {
@@ -61,3 +129,20 @@ func convertToClientCluster(id string, cfg *Config) *models.PutClusterRequest {
 	}
 	return res
 }
+
+// zeroCapacity keeps gpu types of capacity with zero count
+func zeroCapacity(capacity *models.Capacity) *models.Capacity {
+	res := &models.Capacity{
+		Count:    utils.Point(0),
+		CPUCores: utils.Point(0),
+		RamGB:    utils.Point[float64](0),
+		DiskGB:   utils.Point[float64](0),
+	}
+	if capacity != nil && capacity.GPUCapacity != nil {
+		res.GPUCapacity = &models.GPUCapacity{GPU: make(map[string]float64, len(capacity.GPUCapacity.GPU))}
+		for gpuType := range capacity.GPUCapacity.GPU {
+			res.GPUCapacity.GPU[gpuType] = 0
+		}
+	}
+	return res
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // Options ...
@@ -14,6 +15,16 @@ type Options struct {
 	ID           string        `mapstructure:"id"`
 	ConfigPath   string        `mapstructure:"configPath"`
 	ReportPeriod time.Duration `mapstructure:"reportPeriod"`
+	// Discovery discovers capacity and limits from live nodes, config in ConfigPath overrides discovered values
+	Discovery DiscoveryOptions `mapstructure:"discovery"`
+}
+
+// DiscoveryOptions ...
+type DiscoveryOptions struct {
+	Enable       bool   `mapstructure:"enable"`
+	NodeSelector string `mapstructure:"nodeSelector"`
+	// Headroom is the fraction of allocatable reserved from reported capacity, limits of a single node are not reduced
+	Headroom float64 `mapstructure:"headroom"`
 }
 
 // NewOptions ...
@@ -35,6 +46,12 @@ func (o *Options) Validate() error {
 	if o.ReportPeriod < time.Second {
 		return fmt.Errorf("cluster report period %s should not less than 1s", o.ReportPeriod.String())
 	}
+	if o.Discovery.Headroom < 0 || o.Discovery.Headroom >= 1 {
+		return fmt.Errorf("cluster discovery headroom %v should be in [0, 1)", o.Discovery.Headroom)
+	}
+	if _, err := labels.Parse(o.Discovery.NodeSelector); err != nil {
+		return fmt.Errorf("invalid cluster discovery node selector %s: %w", o.Discovery.NodeSelector, err)
+	}
 	return nil
 }
 
@@ -43,4 +60,7 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.ID, "cluster-id", o.ID, "cluster id")
 	fs.StringVar(&o.ConfigPath, "cluster-config-path", o.ConfigPath, "cluster config path, should be yaml")
 	fs.DurationVar(&o.ReportPeriod, "cluster-report-period", o.ReportPeriod, "period of reporting cluster")
+	fs.BoolVar(&o.Discovery.Enable, "cluster-discovery-enable", o.Discovery.Enable, "discover cluster capacity from live nodes")
+	fs.StringVar(&o.Discovery.NodeSelector, "cluster-discovery-node-selector", o.Discovery.NodeSelector, "label selector of nodes to discover capacity from")
+	fs.Float64Var(&o.Discovery.Headroom, "cluster-discovery-headroom", o.Discovery.Headroom, "fraction of node allocatable reserved from reported capacity")
 }
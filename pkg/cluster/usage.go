@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+// anyGPUType is the gpu type of pods without gpu type affinity
+const anyGPUType = "any"
+
+// usageCollector collects usage from tasks in local store and their pods and pvcs
+type usageCollector struct {
+	kubeClient       ctrlclient.Client
+	localStoreHelper localstore.Helper
+	namespace        string
+	now              func() time.Time // for test
+}
+
+func (c *usageCollector) collect(ctx context.Context) (*models.Usage, error) {
+	tasks, err := c.localStoreHelper.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pods := new(corev1.PodList)
+	if err = c.kubeClient.List(ctx, pods, ctrlclient.InNamespace(c.namespace), ctrlclient.HasLabels{consts.LabelTaskID}); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	pvcs := new(corev1.PersistentVolumeClaimList)
+	if err = c.kubeClient.List(ctx, pvcs, ctrlclient.InNamespace(c.namespace), ctrlclient.HasLabels{consts.LabelTaskID}); err != nil {
+		return nil, fmt.Errorf("failed to list pvcs: %w", err)
+	}
+
+	res := &models.Usage{GPU: make(map[string]float64)}
+	var cpu, memory, disk resource.Quantity
+	runningTasks := make(map[string]struct{})
+	for index := range pods.Items {
+		pod := &pods.Items[index]
+		switch pod.Status.Phase {
+		case corev1.PodRunning:
+			runningTasks[pod.Labels[consts.LabelTaskID]] = struct{}{}
+		case corev1.PodPending:
+			if age := int64(c.now().Sub(pod.CreationTimestamp.Time).Seconds()); age > res.OldestPendingSeconds {
+				res.OldestPendingSeconds = age
+			}
+		default:
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			cpu.Add(container.Resources.Requests[corev1.ResourceCPU])
+			memory.Add(container.Resources.Requests[corev1.ResourceMemory])
+			if gpu, ok := container.Resources.Requests[consts.NvidiaGPUResource]; ok {
+				res.GPU[podGPUType(pod)] += float64(gpu.Value())
+			}
+		}
+	}
+	for index := range pvcs.Items {
+		pvc := &pvcs.Items[index]
+		if storage, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+			disk.Add(storage)
+		} else {
+			disk.Add(pvc.Spec.Resources.Requests[corev1.ResourceStorage])
+		}
+	}
+
+	for _, task := range tasks {
+		if task.Stop != nil {
+			continue
+		}
+		if _, ok := runningTasks[task.ID]; ok {
+			res.RunningTasks++
+		} else {
+			res.PendingTasks++
+		}
+	}
+	res.CPUCores = roundTwoDecimals(float64(cpu.MilliValue()) / 1000)
+	res.RamGB = roundTwoDecimals(float64(memory.Value()) / gigabyte)
+	res.DiskGB = roundTwoDecimals(float64(disk.Value()) / gigabyte)
+	return res, nil
+}
+
+func podGPUType(pod *corev1.Pod) string {
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return anyGPUType
+	}
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expression := range term.MatchExpressions {
+			if expression.Key == consts.GPUNameAffinityKey && len(expression.Values) > 0 {
+				return expression.Values[0]
+			}
+		}
+	}
+	return anyGPUType
+}
+
+func roundTwoDecimals(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+func fakeTaskPod(name, taskID string, phase corev1.PodPhase, creationTime time.Time, cpu, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "vetes",
+			Name:              name,
+			Labels:            map[string]string{consts.LabelTaskID: taskID},
+			CreationTimestamp: metav1.NewTime(creationTime),
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "main",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			}},
+		}}},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestCollectUsage(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	gpuPod := fakeTaskPod("task-01-ex-00", "task-01", corev1.PodRunning, now.Add(-time.Hour), "4", "16Gi")
+	gpuPod.Spec.Containers[0].Resources.Requests[consts.NvidiaGPUResource] = resource.MustParse("2")
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(
+		gpuPod,
+		fakeTaskPod("task-02-inputs-filer", "task-02", corev1.PodPending, now.Add(-time.Minute), "500m", "1Gi"),
+		fakeTaskPod("task-03-ex-00", "task-03", corev1.PodSucceeded, now.Add(-time.Hour), "8", "32Gi"),
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "vetes", Name: "task-01", Labels: map[string]string{consts.LabelTaskID: "task-01"}},
+			Spec: corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse("20Gi"),
+			}}},
+		},
+	).Build()
+
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{
+		{Task: localstore.Task{ID: "task-01"}},
+		{Task: localstore.Task{ID: "task-02"}},
+		{Task: localstore.Task{ID: "task-03"}},
+		{Task: localstore.Task{ID: "task-04"}, Stop: utils.Point(consts.TaskCanceled)},
+	}, nil)
+
+	c := &usageCollector{
+		kubeClient:       fakeKubeClient,
+		localStoreHelper: fakeLocalStoreHelper,
+		namespace:        "vetes",
+		now:              func() time.Time { return now },
+	}
+	usage, err := c.collect(context.Background())
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(usage).To(gomega.BeEquivalentTo(&models.Usage{
+		RunningTasks:         1,
+		PendingTasks:         2,
+		CPUCores:             4.5,
+		RamGB:                17,
+		DiskGB:               20,
+		GPU:                  map[string]float64{anyGPUType: 2},
+		OldestPendingSeconds: 60,
+	}))
+}
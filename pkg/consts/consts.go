@@ -60,10 +60,39 @@ const (
 	AAIPassport                   = "AAI_PASSPORT"
 )
 
+// TraceParent is the env of W3C traceparent set in filer and executor pods
+const TraceParent = "TRACEPARENT"
+
 // accelerate constants
 const (
 	// NullAccelerateType ...
 	NullAccelerateType = "null"
 	// MountTOSAccelerateType ...
 	MountTOSAccelerateType = "mount-tos"
+	// MountS3AccelerateType ...
+	MountS3AccelerateType = "mount-s3"
+	// PrePullAccelerateType ...
+	PrePullAccelerateType = "prepull"
+	// RefDataAccelerateType ...
+	RefDataAccelerateType = "refdata"
+	// CompositeAccelerateType chains several accelerators
+	CompositeAccelerateType = "composite"
+)
+
+// tes api mode
+const (
+	// VeTESAPIMode talks to veTES api server with all veTES extensions
+	VeTESAPIMode = "vetes"
+	// StandardTESAPIMode talks to a GA4GH TES server, veTES extensions are probed from service-info
+	StandardTESAPIMode = "standard"
+)
+
+// veTES extensions advertised in service-info
+const (
+	// ClusterFilterExtension means tasks are scheduled to clusters by server, and can be listed by cluster_id
+	ClusterFilterExtension = "cluster-filter"
+	// ClaimTaskExtension means tasks can be claimed, and their state and logs can be updated by agent
+	ClaimTaskExtension = "claim-task"
+	// ClusterReportExtension means server accepts cluster capacity report
+	ClusterReportExtension = "cluster-report"
 )
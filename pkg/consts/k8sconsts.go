@@ -15,13 +15,26 @@ const AnnoStage = "vetes.bioos.volcengine.com/stage"
 // AnnoExecutorStage is annotation key of executor stage on configmap
 const AnnoExecutorStage = "vetes.bioos.volcengine.com/executor-stage"
 
+// AnnoLogOffset is annotation key of the offset of task log file shipped to vetes-api on configmap
+const AnnoLogOffset = "vetes.bioos.volcengine.com/log-offset"
+
+// AnnoStorageClass is annotation key of the storage class of task pvc on configmap, set once pvc falls back to another class
+const AnnoStorageClass = "vetes.bioos.volcengine.com/storage-class"
+
+// AnnoDrain is annotation key of drain mode on drain configmap, cluster is drained if it is "true"
+const AnnoDrain = "vetes.bioos.volcengine.com/drain"
+
+// AnnoDrainDeadline is annotation key of RFC3339 deadline of drain mode on drain configmap
+const AnnoDrainDeadline = "vetes.bioos.volcengine.com/drain-deadline"
+
 // LabelType is label key of the job/pod type
 const LabelType = "vetes.bioos.volcengine.com/type"
 
 // types of job/pod
 const (
-	FilerTypeSuffix = "-filer"
-	ExecutorType    = "executor"
+	FilerTypeSuffix  = "-filer"
+	ExecutorType     = "executor"
+	ImagePrePullType = "image-prepull"
 )
 
 // LabelExecutorNo is label key of the executor number on job/pod
@@ -75,4 +88,10 @@ const (
 const (
 	// LabelBucketName is label key of bucket name on tos pv/pvc
 	LabelBucketName = "vetes.bioos.volcengine.com/bucket-name"
+	// LabelAccelerator is label key of accelerate type owning the resource, so that cleaners of
+	// accelerators in composite mode do not touch resources of each other
+	LabelAccelerator = "vetes.bioos.volcengine.com/accelerator"
 )
+
+// AnnoTemplateHash is annotation key of pod template hash on workloads managed by vetes-k8s-agent
+const AnnoTemplateHash = "vetes.bioos.volcengine.com/template-hash"
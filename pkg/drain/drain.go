@@ -0,0 +1,120 @@
+package drain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+)
+
+// State is drain mode of the cluster. In drain mode, syncer stops claiming queued tasks and cluster reporter
+// advertises zero capacity, while running tasks finish as usual until the optional deadline.
+type State struct {
+	Draining bool       `json:"draining"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// DeadlinePassed ...
+func (s *State) DeadlinePassed(now time.Time) bool {
+	return s.Draining && s.Deadline != nil && now.After(*s.Deadline)
+}
+
+// Helper reads and toggles drain mode by annotations of the drain configmap
+type Helper interface {
+	State(ctx context.Context) (*State, error)
+	Start(ctx context.Context, deadline *time.Time) error
+	Stop(ctx context.Context) error
+}
+
+type impl struct {
+	kubeClient ctrlclient.Client
+	namespace  string
+	name       string
+}
+
+// NewHelper ...
+func NewHelper(kubeClient ctrlclient.Client, namespace string, opts *Options) Helper {
+	return &impl{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		name:       opts.ConfigMapName,
+	}
+}
+
+// State returns not draining if the configmap does not exist
+func (i *impl) State(ctx context.Context) (*State, error) {
+	configmap := &corev1.ConfigMap{}
+	if err := i.kubeClient.Get(ctx, ctrlclient.ObjectKey{Namespace: i.namespace, Name: i.name}, configmap); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return &State{}, nil
+		}
+		return nil, fmt.Errorf("failed to get drain configmap: %w", err)
+	}
+	res := &State{Draining: configmap.Annotations[consts.AnnoDrain] == "true"}
+	if deadlineStr, ok := configmap.Annotations[consts.AnnoDrainDeadline]; ok && res.Draining {
+		deadline, err := time.Parse(time.RFC3339, deadlineStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid drain deadline %s: %w", deadlineStr, err)
+		}
+		res.Deadline = &deadline
+	}
+	return res, nil
+}
+
+// Start creates the configmap if not exist
+func (i *impl) Start(ctx context.Context, deadline *time.Time) error {
+	return i.patch(ctx, func(annotations map[string]string) {
+		annotations[consts.AnnoDrain] = "true"
+		delete(annotations, consts.AnnoDrainDeadline)
+		if deadline != nil {
+			annotations[consts.AnnoDrainDeadline] = deadline.Format(time.RFC3339)
+		}
+	})
+}
+
+// Stop ...
+func (i *impl) Stop(ctx context.Context) error {
+	return i.patch(ctx, func(annotations map[string]string) {
+		delete(annotations, consts.AnnoDrain)
+		delete(annotations, consts.AnnoDrainDeadline)
+	})
+}
+
+func (i *impl) patch(ctx context.Context, mutate func(annotations map[string]string)) error {
+	configmap := &corev1.ConfigMap{}
+	if err := i.kubeClient.Get(ctx, ctrlclient.ObjectKey{Namespace: i.namespace, Name: i.name}, configmap); err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get drain configmap: %w", err)
+		}
+		configmap = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   i.namespace,
+			Name:        i.name,
+			Annotations: make(map[string]string),
+		}}
+		mutate(configmap.Annotations)
+		if err = i.kubeClient.Create(ctx, configmap); err != nil {
+			return fmt.Errorf("failed to create drain configmap: %w", err)
+		}
+		return nil
+	}
+
+	patchHelper, err := patch.NewHelper(configmap, i.kubeClient)
+	if err != nil {
+		return fmt.Errorf("failed to create configmap patchHelper: %w", err)
+	}
+	if configmap.Annotations == nil {
+		configmap.Annotations = make(map[string]string)
+	}
+	mutate(configmap.Annotations)
+	if err = patchHelper.Patch(ctx, configmap); err != nil {
+		return fmt.Errorf("failed to patch drain configmap: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package drain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHelper(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctx := context.Background()
+	h := NewHelper(ctrlfake.NewClientBuilder().Build(), "vetes", NewOptions())
+
+	state, err := h.State(ctx)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(state).To(gomega.Equal(&State{}))
+
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	g.Expect(h.Start(ctx, &deadline)).To(gomega.Succeed())
+	state, err = h.State(ctx)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(state.Draining).To(gomega.BeTrue())
+	g.Expect(state.Deadline.Equal(deadline)).To(gomega.BeTrue())
+	g.Expect(state.DeadlinePassed(time.Now())).To(gomega.BeFalse())
+	g.Expect(state.DeadlinePassed(deadline.Add(time.Second))).To(gomega.BeTrue())
+
+	// start again without deadline
+	g.Expect(h.Start(ctx, nil)).To(gomega.Succeed())
+	state, err = h.State(ctx)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(state).To(gomega.Equal(&State{Draining: true}))
+
+	g.Expect(h.Stop(ctx)).To(gomega.Succeed())
+	state, err = h.State(ctx)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(state).To(gomega.Equal(&State{}))
+}
@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/drain/drain.go
+
+// Package fake is a generated GoMock package.
+package fake
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	drain "github.com/GBA-BI/tes-k8s-agent/pkg/drain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// FakeHelper is a mock of Helper interface.
+type FakeHelper struct {
+	ctrl     *gomock.Controller
+	recorder *FakeHelperMockRecorder
+}
+
+// FakeHelperMockRecorder is the mock recorder for FakeHelper.
+type FakeHelperMockRecorder struct {
+	mock *FakeHelper
+}
+
+// NewFakeHelper creates a new mock instance.
+func NewFakeHelper(ctrl *gomock.Controller) *FakeHelper {
+	mock := &FakeHelper{ctrl: ctrl}
+	mock.recorder = &FakeHelperMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *FakeHelper) EXPECT() *FakeHelperMockRecorder {
+	return m.recorder
+}
+
+// Start mocks base method.
+func (m *FakeHelper) Start(ctx context.Context, deadline *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", ctx, deadline)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Start indicates an expected call of Start.
+func (mr *FakeHelperMockRecorder) Start(ctx, deadline interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*FakeHelper)(nil).Start), ctx, deadline)
+}
+
+// State mocks base method.
+func (m *FakeHelper) State(ctx context.Context) (*drain.State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "State", ctx)
+	ret0, _ := ret[0].(*drain.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// State indicates an expected call of State.
+func (mr *FakeHelperMockRecorder) State(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*FakeHelper)(nil).State), ctx)
+}
+
+// Stop mocks base method.
+func (m *FakeHelper) Stop(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stop", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stop indicates an expected call of Stop.
+func (mr *FakeHelperMockRecorder) Stop(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*FakeHelper)(nil).Stop), ctx)
+}
@@ -0,0 +1,54 @@
+package drain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// actions on tasks still running after drain deadline
+const (
+	DeadlineActionFail    = "fail"
+	DeadlineActionRequeue = "requeue"
+)
+
+// Options ...
+type Options struct {
+	// ConfigMapName is the configmap in agent namespace whose annotations toggle drain mode
+	ConfigMapName string `mapstructure:"configmapName"`
+	// DeadlineAction fails remaining tasks with SYSTEM_ERROR, or requeues them for other clusters after deadline
+	DeadlineAction string        `mapstructure:"deadlineAction"`
+	CheckPeriod    time.Duration `mapstructure:"checkPeriod"`
+}
+
+// NewOptions ...
+func NewOptions() *Options {
+	return &Options{
+		ConfigMapName:  "vetes-k8s-agent-drain",
+		DeadlineAction: DeadlineActionFail,
+		CheckPeriod:    time.Minute,
+	}
+}
+
+// Validate ...
+func (o *Options) Validate() error {
+	if o.ConfigMapName == "" {
+		return errors.New("empty drain configmap name")
+	}
+	if o.DeadlineAction != DeadlineActionFail && o.DeadlineAction != DeadlineActionRequeue {
+		return fmt.Errorf("invalid drain deadline action: %s", o.DeadlineAction)
+	}
+	if o.CheckPeriod < time.Second {
+		return fmt.Errorf("drain check period %s should not less than 1s", o.CheckPeriod)
+	}
+	return nil
+}
+
+// AddFlags ...
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.ConfigMapName, "drain-configmap-name", o.ConfigMapName, "configmap whose annotations toggle drain mode")
+	fs.StringVar(&o.DeadlineAction, "drain-deadline-action", o.DeadlineAction, "action on tasks still running after drain deadline, fail or requeue")
+	fs.DurationVar(&o.CheckPeriod, "drain-check-period", o.CheckPeriod, "period of checking drain deadline")
+}
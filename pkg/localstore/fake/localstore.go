@@ -65,6 +65,21 @@ func (mr *FakeHelperMockRecorder) GetTask(ctx, taskID interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTask", reflect.TypeOf((*FakeHelper)(nil).GetTask), ctx, taskID)
 }
 
+// ListTasks mocks base method.
+func (m *FakeHelper) ListTasks(ctx context.Context) ([]*localstore.TaskInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTasks", ctx)
+	ret0, _ := ret[0].([]*localstore.TaskInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTasks indicates an expected call of ListTasks.
+func (mr *FakeHelperMockRecorder) ListTasks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTasks", reflect.TypeOf((*FakeHelper)(nil).ListTasks), ctx)
+}
+
 // RecordTaskExecutorStage mocks base method.
 func (m *FakeHelper) RecordTaskExecutorStage(ctx context.Context, taskID string, stage int) error {
 	m.ctrl.T.Helper()
@@ -79,6 +94,20 @@ func (mr *FakeHelperMockRecorder) RecordTaskExecutorStage(ctx, taskID, stage int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTaskExecutorStage", reflect.TypeOf((*FakeHelper)(nil).RecordTaskExecutorStage), ctx, taskID, stage)
 }
 
+// RecordTaskLogOffset mocks base method.
+func (m *FakeHelper) RecordTaskLogOffset(ctx context.Context, taskID string, offset int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordTaskLogOffset", ctx, taskID, offset)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordTaskLogOffset indicates an expected call of RecordTaskLogOffset.
+func (mr *FakeHelperMockRecorder) RecordTaskLogOffset(ctx, taskID, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTaskLogOffset", reflect.TypeOf((*FakeHelper)(nil).RecordTaskLogOffset), ctx, taskID, offset)
+}
+
 // RecordTaskStage mocks base method.
 func (m *FakeHelper) RecordTaskStage(ctx context.Context, taskID string, stage int) error {
 	m.ctrl.T.Helper()
@@ -93,6 +122,20 @@ func (mr *FakeHelperMockRecorder) RecordTaskStage(ctx, taskID, stage interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTaskStage", reflect.TypeOf((*FakeHelper)(nil).RecordTaskStage), ctx, taskID, stage)
 }
 
+// RecordTaskStorageClass mocks base method.
+func (m *FakeHelper) RecordTaskStorageClass(ctx context.Context, taskID, storageClass string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordTaskStorageClass", ctx, taskID, storageClass)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordTaskStorageClass indicates an expected call of RecordTaskStorageClass.
+func (mr *FakeHelperMockRecorder) RecordTaskStorageClass(ctx, taskID, storageClass interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTaskStorageClass", reflect.TypeOf((*FakeHelper)(nil).RecordTaskStorageClass), ctx, taskID, storageClass)
+}
+
 // StopTask mocks base method.
 func (m *FakeHelper) StopTask(ctx context.Context, taskID, state string) error {
 	m.ctrl.T.Helper()
@@ -134,3 +177,18 @@ func (mr *FakeHelperMockRecorder) StoreType() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreType", reflect.TypeOf((*FakeHelper)(nil).StoreType))
 }
+
+// TaskObject mocks base method.
+func (m *FakeHelper) TaskObject(ctx context.Context, taskID string) (client.Object, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TaskObject", ctx, taskID)
+	ret0, _ := ret[0].(client.Object)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TaskObject indicates an expected call of TaskObject.
+func (mr *FakeHelperMockRecorder) TaskObject(ctx, taskID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TaskObject", reflect.TypeOf((*FakeHelper)(nil).TaskObject), ctx, taskID)
+}
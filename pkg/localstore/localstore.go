@@ -26,11 +26,16 @@ var ErrNotFound = errors.New("not found")
 type Helper interface {
 	StoreTask(ctx context.Context, task *Task) error
 	GetTask(ctx context.Context, taskID string) (*TaskInfo, error)
+	ListTasks(ctx context.Context) ([]*TaskInfo, error)
 	StopTask(ctx context.Context, taskID, state string) error
 	DeleteTask(ctx context.Context, taskID string) error
 	RecordTaskStage(ctx context.Context, taskID string, stage int) error
 	RecordTaskExecutorStage(ctx context.Context, taskID string, stage int) error
+	RecordTaskLogOffset(ctx context.Context, taskID string, offset int64) error
+	RecordTaskStorageClass(ctx context.Context, taskID string, storageClass string) error
 	StoreType() ctrlclient.Object
+	// TaskObject returns the object storing the task, e.g. for recording events
+	TaskObject(ctx context.Context, taskID string) (ctrlclient.Object, error)
 }
 
 type impl struct {
@@ -74,21 +79,44 @@ func (i *impl) StoreTask(ctx context.Context, task *Task) error {
 }
 
 // GetTask ...
-func (i *impl) GetTask(ctx context.Context, taskID string) (taskInfo *TaskInfo, err error) {
+func (i *impl) GetTask(ctx context.Context, taskID string) (*TaskInfo, error) {
 	configmapKey := ctrlclient.ObjectKey{Namespace: i.namespace, Name: configmapName(taskID)}
 	configmap := &corev1.ConfigMap{}
-	if err = i.kubeClient.Get(ctx, configmapKey, configmap); err != nil {
+	if err := i.kubeClient.Get(ctx, configmapKey, configmap); err != nil {
 		if k8sapierrors.IsNotFound(err) {
 			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to get configmap: %w", err)
 	}
+	return configmapToTaskInfo(configmap)
+}
+
+// ListTasks ...
+func (i *impl) ListTasks(ctx context.Context) ([]*TaskInfo, error) {
+	configmaps := &corev1.ConfigMapList{}
+	if err := i.kubeClient.List(ctx, configmaps, ctrlclient.InNamespace(i.namespace), ctrlclient.HasLabels{consts.LabelTaskID}); err != nil {
+		return nil, fmt.Errorf("failed to list configmaps: %w", err)
+	}
+	res := make([]*TaskInfo, 0, len(configmaps.Items))
+	for index := range configmaps.Items {
+		taskInfo, err := configmapToTaskInfo(&configmaps.Items[index])
+		if err != nil {
+			log.Warnw("invalid task configmap", "configmap", configmaps.Items[index].Name, "err", err)
+			continue
+		}
+		res = append(res, taskInfo)
+	}
+	return res, nil
+}
+
+func configmapToTaskInfo(configmap *corev1.ConfigMap) (*TaskInfo, error) {
+	taskID := configmap.Labels[consts.LabelTaskID]
 	taskYaml, ok := configmap.Data[taskID]
 	if !ok {
 		return nil, errors.New("empty configmap data")
 	}
-	taskInfo = new(TaskInfo)
-	if err = yaml.Unmarshal([]byte(taskYaml), &taskInfo.Task); err != nil {
+	taskInfo := new(TaskInfo)
+	if err := yaml.Unmarshal([]byte(taskYaml), &taskInfo.Task); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal task: %w", err)
 	}
 	if configmap.Annotations != nil {
@@ -111,6 +139,17 @@ func (i *impl) GetTask(ctx context.Context, taskID string) (taskInfo *TaskInfo,
 				taskInfo.ExecutorStage = utils.Point(executorStage)
 			}
 		}
+		if logOffsetStr, ok := configmap.Annotations[consts.AnnoLogOffset]; ok {
+			logOffset, err := strconv.ParseInt(logOffsetStr, 10, 64)
+			if err != nil {
+				log.Warnw("invalid log offset annotation", "offset", logOffsetStr, "err", err)
+			} else {
+				taskInfo.LogOffset = utils.Point(logOffset)
+			}
+		}
+		if storageClass, ok := configmap.Annotations[consts.AnnoStorageClass]; ok {
+			taskInfo.StorageClass = utils.Point(storageClass)
+		}
 	}
 	return taskInfo, nil
 }
@@ -217,11 +256,73 @@ func (i *impl) RecordTaskExecutorStage(ctx context.Context, taskID string, stage
 	return nil
 }
 
+// RecordTaskLogOffset ...
+func (i *impl) RecordTaskLogOffset(ctx context.Context, taskID string, offset int64) error {
+	configmapKey := ctrlclient.ObjectKey{Namespace: i.namespace, Name: configmapName(taskID)}
+	configmap := &corev1.ConfigMap{}
+	if err := i.kubeClient.Get(ctx, configmapKey, configmap); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get configmap: %w", err)
+	}
+
+	patchHelper, err := patch.NewHelper(configmap, i.kubeClient)
+	if err != nil {
+		return fmt.Errorf("failed to create configmap patchHelper: %w", err)
+	}
+	if configmap.Annotations == nil {
+		configmap.Annotations = make(map[string]string)
+	}
+	configmap.Annotations[consts.AnnoLogOffset] = strconv.FormatInt(offset, 10)
+	if err = patchHelper.Patch(ctx, configmap); err != nil {
+		return fmt.Errorf("failed to record log offset on configmap: %w", err)
+	}
+	return nil
+}
+
+// RecordTaskStorageClass ...
+func (i *impl) RecordTaskStorageClass(ctx context.Context, taskID string, storageClass string) error {
+	configmapKey := ctrlclient.ObjectKey{Namespace: i.namespace, Name: configmapName(taskID)}
+	configmap := &corev1.ConfigMap{}
+	if err := i.kubeClient.Get(ctx, configmapKey, configmap); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get configmap: %w", err)
+	}
+
+	patchHelper, err := patch.NewHelper(configmap, i.kubeClient)
+	if err != nil {
+		return fmt.Errorf("failed to create configmap patchHelper: %w", err)
+	}
+	if configmap.Annotations == nil {
+		configmap.Annotations = make(map[string]string)
+	}
+	configmap.Annotations[consts.AnnoStorageClass] = storageClass
+	if err = patchHelper.Patch(ctx, configmap); err != nil {
+		return fmt.Errorf("failed to record storage class on configmap: %w", err)
+	}
+	return nil
+}
+
 // StoreType ...
 func (i *impl) StoreType() ctrlclient.Object {
 	return &corev1.ConfigMap{}
 }
 
+// TaskObject ...
+func (i *impl) TaskObject(ctx context.Context, taskID string) (ctrlclient.Object, error) {
+	configmap := &corev1.ConfigMap{}
+	if err := i.kubeClient.Get(ctx, ctrlclient.ObjectKey{Namespace: i.namespace, Name: configmapName(taskID)}, configmap); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get configmap: %w", err)
+	}
+	return configmap, nil
+}
+
 func configmapName(taskID string) string {
 	return taskID
 }
@@ -0,0 +1,49 @@
+package localstore
+
+import (
+	"context"
+)
+
+// namespacedHelper shows accelerate names of one accelerator type only, without the type prefix,
+// so that an accelerator in a composite accelerator sees the same local store as it runs alone.
+type namespacedHelper struct {
+	Helper
+	accelerateType string
+}
+
+// NewNamespacedHelper ...
+func NewNamespacedHelper(helper Helper, accelerateType string) Helper {
+	return &namespacedHelper{
+		Helper:         helper,
+		accelerateType: accelerateType,
+	}
+}
+
+// StoreTask ...
+func (h *namespacedHelper) StoreTask(ctx context.Context, task *Task) error {
+	taskCopy := *task
+	taskCopy.AccelerateNames = NamespacedAccelerateNames(h.accelerateType, task.AccelerateNames)
+	return h.Helper.StoreTask(ctx, &taskCopy)
+}
+
+// GetTask ...
+func (h *namespacedHelper) GetTask(ctx context.Context, taskID string) (*TaskInfo, error) {
+	taskInfo, err := h.Helper.GetTask(ctx, taskID)
+	if err != nil || taskInfo == nil {
+		return taskInfo, err
+	}
+	taskInfo.AccelerateNames = taskInfo.AccelerateNamesOf(h.accelerateType)
+	return taskInfo, nil
+}
+
+// ListTasks ...
+func (h *namespacedHelper) ListTasks(ctx context.Context) ([]*TaskInfo, error) {
+	taskInfos, err := h.Helper.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, taskInfo := range taskInfos {
+		taskInfo.AccelerateNames = taskInfo.AccelerateNamesOf(h.accelerateType)
+	}
+	return taskInfos, nil
+}
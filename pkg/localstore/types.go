@@ -1,11 +1,15 @@
 package localstore
 
+import "strings"
+
 // TaskInfo ...
 type TaskInfo struct {
 	Task
 	Stop          *string
 	Stage         *int
 	ExecutorStage *int
+	LogOffset     *int64
+	StorageClass  *string
 }
 
 // Task ...
@@ -21,6 +25,8 @@ type Task struct {
 	InputsRef       string      `yaml:"inputs_ref,omitempty"`
 	OutputsRef      string      `yaml:"outputs_ref,omitempty"`
 	AccelerateNames []string    `yaml:"accelerate_names,omitempty"`
+	// TraceParent is W3C traceparent of the root span of task, empty if tracing is disabled
+	TraceParent string `yaml:"trace_parent,omitempty"`
 }
 
 // Resources ...
@@ -76,3 +82,27 @@ type ExternalBucketAuthInfo struct {
 	AK     string `yaml:"ak,omitempty"`
 	SK     string `yaml:"sk,omitempty"`
 }
+
+// AccelerateNameSeparator separates accelerator type and name in namespaced accelerate names, such as `mount-tos/bucket`
+const AccelerateNameSeparator = "/"
+
+// NamespacedAccelerateNames prefixes names with accelerateType
+func NamespacedAccelerateNames(accelerateType string, names []string) []string {
+	res := make([]string, 0, len(names))
+	for _, name := range names {
+		res = append(res, accelerateType+AccelerateNameSeparator+name)
+	}
+	return res
+}
+
+// AccelerateNamesOf returns names of accelerateType in namespaced accelerate names, without the prefix
+func (t *Task) AccelerateNamesOf(accelerateType string) []string {
+	prefix := accelerateType + AccelerateNameSeparator
+	var res []string
+	for _, name := range t.AccelerateNames {
+		if strings.HasPrefix(name, prefix) {
+			res = append(res, strings.TrimPrefix(name, prefix))
+		}
+	}
+	return res
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "vetes_k8s_agent"
+
+// reload result
+const (
+	ReloadSucceeded     = "succeeded"
+	ReloadFailed        = "failed"
+	ReloadNeedRestart   = "need_restart"
+	ReloadInvalidConfig = "invalid_config"
+)
+
+// ConfigReloadTotal counts config reloads by component and result
+var ConfigReloadTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "config_reload_total",
+	Help:      "Total number of config reloads by component and result.",
+}, []string{"component", "result"})
+
+// runner stages observed by StageDurationSeconds, executors of all indexes share one label value
+const (
+	StagePVC          = "pvc"
+	StageInputsFiler  = "inputs_filer"
+	StageExecutor     = "executor"
+	StageOutputsFiler = "outputs_filer"
+)
+
+// stage result
+const (
+	StageSucceeded = "succeeded"
+	StageFailed    = "failed"
+)
+
+// TasksAdmittedTotal counts tasks synced from vetes-api into local store
+var TasksAdmittedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "tasks_admitted_total",
+	Help:      "Total number of tasks admitted into local store.",
+})
+
+// TasksFinishedTotal counts tasks by terminal state
+var TasksFinishedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "tasks_finished_total",
+	Help:      "Total number of finished tasks by terminal state.",
+}, []string{"state"})
+
+// StageDurationSeconds observes time spent in each runner stage
+var StageDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "stage_duration_seconds",
+	Help:      "Time spent in runner stages.",
+	Buckets:   prometheus.ExponentialBuckets(1, 4, 10), // 1s ~ 3d
+}, []string{"stage", "result"})
+
+// ExecutorRetriesTotal counts failed executor pods retried by job
+var ExecutorRetriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "executor_retries_total",
+	Help:      "Total number of executor pod retries.",
+})
+
+// ImagePullBackoffStopsTotal counts jobs stopped because of ImagePullBackOff timeout
+var ImagePullBackoffStopsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "image_pull_backoff_stops_total",
+	Help:      "Total number of jobs stopped because of ImagePullBackOff timeout.",
+})
+
+// ContainerStartFailuresTotal counts jobs stopped because of non-retryable container start errors
+var ContainerStartFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "container_start_failures_total",
+	Help:      "Total number of jobs stopped because of non-retryable container start errors by reason.",
+}, []string{"reason"})
+
+// PVCBindTimeoutsTotal counts task pvcs not bound in time by storage class
+var PVCBindTimeoutsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "pvc_bind_timeouts_total",
+	Help:      "Total number of task pvcs not bound in time by storage class.",
+}, []string{"storage_class"})
+
+// UnschedulableStopsTotal counts tasks stopped because of unschedulable pod timeout
+var UnschedulableStopsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "unschedulable_stops_total",
+	Help:      "Total number of tasks stopped because of unschedulable pod timeout.",
+})
+
+// ResyncDivergentTasks is the number of tasks in local store diverging from vetes-api found by the last resync
+var ResyncDivergentTasks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "resync_divergent_tasks",
+	Help:      "Number of tasks in local store diverging from vetes-api found by the last resync by reason.",
+}, []string{"reason"})
+
+// GCOrphanObjects is the number of orphaned jobs, pods and pvcs found by the last gc
+var GCOrphanObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "gc_orphan_objects",
+	Help:      "Number of orphaned jobs, pods and pvcs found by the last gc by kind.",
+}, []string{"kind"})
+
+// GCDeletedObjectsTotal counts orphaned jobs, pods and pvcs deleted by gc
+var GCDeletedObjectsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "gc_deleted_objects_total",
+	Help:      "Total number of orphaned jobs, pods and pvcs deleted by gc by kind.",
+}, []string{"kind"})
+
+// SyncerCycleDurationSeconds observes time of each sync cycle
+var SyncerCycleDurationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "syncer_cycle_duration_seconds",
+	Help:      "Time of each cycle syncing tasks from vetes-api.",
+	Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12), // 0.1s ~ 200s
+})
+
+// SyncerBacklog is the number of QUEUED and CANCELING tasks listed in the last sync cycle
+var SyncerBacklog = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "syncer_backlog",
+	Help:      "Number of QUEUED and CANCELING tasks listed in the last sync cycle.",
+})
+
+// Draining is 1 if the cluster is in drain mode in the last sync cycle
+var Draining = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "draining",
+	Help:      "Whether the cluster is in drain mode in the last sync cycle.",
+})
+
+// VeTESClientRequestDurationSeconds observes latency of vetes-api requests by endpoint
+var VeTESClientRequestDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "vetes_client_request_duration_seconds",
+	Help:      "Latency of vetes-api requests by endpoint.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"endpoint"})
+
+// VeTESClientErrorsTotal counts failed vetes-api requests by endpoint and http status code,
+// code is "error" if no response is received
+var VeTESClientErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "vetes_client_errors_total",
+	Help:      "Total number of failed vetes-api requests by endpoint and code.",
+}, []string{"endpoint", "code"})
+
+func init() {
+	// metrics are served on the metrics port of controller-runtime manager
+	ctrlmetrics.Registry.MustRegister(
+		ConfigReloadTotal,
+		TasksAdmittedTotal,
+		TasksFinishedTotal,
+		StageDurationSeconds,
+		ExecutorRetriesTotal,
+		ImagePullBackoffStopsTotal,
+		ContainerStartFailuresTotal,
+		UnschedulableStopsTotal,
+		PVCBindTimeoutsTotal,
+		ResyncDivergentTasks,
+		GCOrphanObjects,
+		GCDeletedObjectsTotal,
+		SyncerCycleDurationSeconds,
+		SyncerBacklog,
+		Draining,
+		VeTESClientRequestDurationSeconds,
+		VeTESClientErrorsTotal,
+	)
+}
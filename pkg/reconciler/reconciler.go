@@ -45,6 +45,7 @@ func RegisterReconciler(mgr ctrl.Manager, localStoreHelper localstore.Helper, ru
 		WithEventFilter(predicate.ResourceVersionChangedPredicate{}).
 		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(r.enqueueTaskObject)).
 		Watches(localStoreHelper.StoreType(), handler.EnqueueRequestsFromMapFunc(r.enqueueTaskObject)).
+		WatchesRawSource(runnerImpl.ResyncSource(), handler.EnqueueRequestsFromMapFunc(r.enqueueTaskObject)).
 		Named("task-reconciler").
 		Build(r); err != nil {
 		return fmt.Errorf("failed to set up with manager: %w", err)
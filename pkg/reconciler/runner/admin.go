@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+)
+
+// ForceStopStates are the states which a task can be force stopped with
+var ForceStopStates = []string{consts.TaskSystemError, consts.TaskExecutorError, consts.TaskCanceled}
+
+// ForceStopTask marks the task stopped in local store, then the running agent stops and cleans it,
+// and updates it to state in vetes-api.
+func (r *Runner) ForceStopTask(ctx context.Context, taskID, state string) error {
+	valid := false
+	for _, s := range ForceStopStates {
+		if s == state {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid state %s, should be one of %v", state, ForceStopStates)
+	}
+	return r.localStoreHelper.StopTask(ctx, taskID, state)
+}
+
+// CleanupOrphans strips finalizers of jobs, pods and pvcs whose task is not in local store, and deletes them,
+// same as the gc crontab. Without the gc history of when objects are orphaned, objects created within
+// the gc grace period are skipped, in case their task is just added to local store.
+// It returns the orphans as `<kind>/<name>`, which are only listed if dryRun.
+func (r *Runner) CleanupOrphans(ctx context.Context, dryRun bool) ([]string, error) {
+	objs, err := r.listOrphans(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	gracePeriod := r.options().GC.GracePeriod
+	res := make([]string, 0)
+	for _, obj := range objs {
+		if time.Since(obj.GetCreationTimestamp().Time) < gracePeriod {
+			continue
+		}
+		name := fmt.Sprintf("%s/%s", orphanKind(obj), obj.GetName())
+		res = append(res, name)
+		if dryRun {
+			continue
+		}
+		if err = r.deleteOrphan(ctx, obj); err != nil {
+			return res, fmt.Errorf("failed to delete %s: %w", name, err)
+		}
+	}
+	return res, nil
+}
+
+// listTaskObjects lists jobs, pods and pvcs labeled with task id
+func (r *Runner) listTaskObjects(ctx context.Context) ([]ctrlclient.Object, error) {
+	listOpts := []ctrlclient.ListOption{ctrlclient.InNamespace(r.namespace), ctrlclient.HasLabels{consts.LabelTaskID}}
+	objs := make([]ctrlclient.Object, 0)
+	jobs := &batchv1.JobList{}
+	if err := r.kubeClient.List(ctx, jobs, listOpts...); err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	for index := range jobs.Items {
+		objs = append(objs, &jobs.Items[index])
+	}
+	pods := &corev1.PodList{}
+	if err := r.kubeClient.List(ctx, pods, listOpts...); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for index := range pods.Items {
+		objs = append(objs, &pods.Items[index])
+	}
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := r.kubeClient.List(ctx, pvcs, listOpts...); err != nil {
+		return nil, fmt.Errorf("failed to list pvcs: %w", err)
+	}
+	for index := range pvcs.Items {
+		objs = append(objs, &pvcs.Items[index])
+	}
+	return objs, nil
+}
+
+func (r *Runner) deleteOrphan(ctx context.Context, obj ctrlclient.Object) error {
+	for _, finalizer := range []string{consts.ProcessTaskFinalizer, consts.ProcessExecutorTimeFinalizer} {
+		if err := utils.RemoveObjectFinalizer(ctx, r.kubeClient, obj, finalizer); err != nil {
+			return err
+		}
+	}
+	if err := r.kubeClient.Delete(ctx, obj, ctrlclient.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8sapierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func orphanKind(obj ctrlclient.Object) string {
+	switch obj.(type) {
+	case *batchv1.Job:
+		return "job"
+	case *corev1.Pod:
+		return "pod"
+	default:
+		return "pvc"
+	}
+}
+
+// RenderTask returns the pvc and jobs which would be created for the task, in the order of creation
+func (r *Runner) RenderTask(localTask *localstore.Task) []ctrlclient.Object {
+	res := make([]ctrlclient.Object, 0)
+	if shouldCreatePVC(localTask) {
+		res = append(res, r.newPVC(localTask.ID, localTask.Resources.DiskGB, r.options().StorageClass))
+	}
+	jobs := make([]*batchv1.Job, 0)
+	if shouldCreateInputsFiler(localTask) {
+		jobs = append(jobs, r.initFiler(localTask, consts.InputsMode, r.s3SecretName()))
+	}
+	for index := range localTask.Executors {
+		job := r.initExecutorBase(localTask, index, r.options().ExecutorImagePullSecret.StaticName)
+		r.addECSInfo(job, localTask.Resources)
+		jobs = append(jobs, job)
+	}
+	if shouldCreateOutputsFiler(localTask) {
+		jobs = append(jobs, r.initFiler(localTask, consts.OutputsMode, r.s3SecretName()))
+	}
+	for _, job := range jobs {
+		controllerutil.AddFinalizer(job, consts.ProcessTaskFinalizer)
+		res = append(res, job)
+	}
+	return res
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+)
+
+func TestCleanupOrphans(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	created := metav1.NewTime(time.Now().Add(-time.Hour))
+	objectMeta := func(name, taskID string, finalizers ...string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Namespace:         fakeNamespace,
+			Name:              name,
+			Labels:            map[string]string{consts.LabelTaskID: taskID},
+			Finalizers:        finalizers,
+			CreationTimestamp: created,
+		}
+	}
+	youngMeta := objectMeta("young-job", "task-young", consts.ProcessTaskFinalizer)
+	youngMeta.CreationTimestamp = metav1.Now()
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(
+		&batchv1.Job{ObjectMeta: objectMeta("live-job", fakeTaskID, consts.ProcessTaskFinalizer)},
+		&batchv1.Job{ObjectMeta: objectMeta("orphan-job", "task-orphan", consts.ProcessTaskFinalizer)},
+		&corev1.Pod{ObjectMeta: objectMeta("orphan-pod", "task-orphan", consts.ProcessExecutorTimeFinalizer)},
+		&corev1.PersistentVolumeClaim{ObjectMeta: objectMeta("orphan-pvc", "task-orphan", consts.ProcessTaskFinalizer)},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: fakeNamespace, Name: "unlabeled-pod"}},
+		// created within the grace period
+		&batchv1.Job{ObjectMeta: youngMeta},
+	).Build()
+
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{{
+		Task: localstore.Task{ID: fakeTaskID},
+	}}, nil).Times(2)
+
+	r := &Runner{
+		kubeClient:       fakeKubeClient,
+		localStoreHelper: fakeLocalStoreHelper,
+		namespace:        fakeNamespace,
+		opts:             &Options{GC: GCOptions{GracePeriod: time.Minute * 30}},
+	}
+	orphans := []string{"job/orphan-job", "pod/orphan-pod", "pvc/orphan-pvc"}
+
+	res, err := r.CleanupOrphans(context.Background(), true)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(res).To(gomega.ConsistOf(orphans))
+	g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Namespace: fakeNamespace, Name: "orphan-job"}, &batchv1.Job{})).To(gomega.Succeed())
+
+	res, err = r.CleanupOrphans(context.Background(), false)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(res).To(gomega.ConsistOf(orphans))
+	jobs := &batchv1.JobList{}
+	g.Expect(fakeKubeClient.List(context.Background(), jobs)).To(gomega.Succeed())
+	g.Expect(jobs.Items).To(gomega.HaveLen(2))
+	g.Expect([]string{jobs.Items[0].Name, jobs.Items[1].Name}).To(gomega.ConsistOf("live-job", "young-job"))
+	pods := &corev1.PodList{}
+	g.Expect(fakeKubeClient.List(context.Background(), pods)).To(gomega.Succeed())
+	g.Expect(pods.Items).To(gomega.HaveLen(1))
+	g.Expect(pods.Items[0].Name).To(gomega.Equal("unlabeled-pod"))
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	g.Expect(fakeKubeClient.List(context.Background(), pvcs)).To(gomega.Succeed())
+	g.Expect(pvcs.Items).To(gomega.BeEmpty())
+}
+
+func TestForceStopTask(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	fakeLocalStoreHelper.EXPECT().StopTask(gomock.Any(), fakeTaskID, consts.TaskSystemError).Return(nil)
+	r := &Runner{localStoreHelper: fakeLocalStoreHelper}
+
+	g.Expect(r.ForceStopTask(context.Background(), fakeTaskID, consts.TaskComplete)).NotTo(gomega.Succeed())
+	g.Expect(r.ForceStopTask(context.Background(), fakeTaskID, consts.TaskSystemError)).To(gomega.Succeed())
+}
@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/filelog"
+)
+
+// waiting reasons of containers
+const (
+	waitingReasonErrImagePull               = "ErrImagePull"
+	waitingReasonImagePullBackOff           = "ImagePullBackOff"
+	waitingReasonInvalidImageName           = "InvalidImageName"
+	waitingReasonCreateContainerConfigError = "CreateContainerConfigError"
+	waitingReasonCreateContainerError       = "CreateContainerError"
+)
+
+// nonRetryableWaitingReasons never recover by retrying, e.g. a malformed image name
+var nonRetryableWaitingReasons = map[string]struct{}{
+	waitingReasonInvalidImageName: {},
+}
+
+// createContainerWaitingReasons are often transient, e.g. a secret created after the pod or a busy container runtime,
+// so they are regarded as non-retryable only after the pod is started for PodCreateContainerErrorTimeout.
+var createContainerWaitingReasons = map[string]struct{}{
+	waitingReasonCreateContainerConfigError: {},
+	waitingReasonCreateContainerError:       {},
+}
+
+// nonRetryableImagePullErrors are returned by registries when the image does not exist,
+// other pull errors such as timeout or rate limit are regarded as transient.
+var nonRetryableImagePullErrors = []string{
+	"manifest unknown",
+	"name unknown",
+	"repository does not exist",
+	"invalid reference format",
+}
+
+// authImagePullErrors mean the image may exist but needs credentials, which can be fixed by pull secrets,
+// e.g. `pull access denied for xxx, repository does not exist or may require 'docker login'`.
+// They are left to PodImagePullBackoffTimeout even if some non-retryable error is matched.
+var authImagePullErrors = []string{
+	"may require 'docker login'",
+	"pull access denied",
+	"unauthorized",
+	"authentication required",
+}
+
+// nonRetryableContainerError classifies waiting containers and events of the pod,
+// and returns the reason and message if some container can never start.
+func (r *Runner) nonRetryableContainerError(ctx context.Context, logger filelog.Logger, pod *corev1.Pod) (reason, message string) {
+	pullingImage := false
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			waiting := status.State.Waiting
+			if waiting == nil {
+				continue
+			}
+			if _, ok := nonRetryableWaitingReasons[waiting.Reason]; ok {
+				return waiting.Reason, waiting.Message
+			}
+			if _, ok := createContainerWaitingReasons[waiting.Reason]; ok {
+				if pod.Status.StartTime != nil && time.Since(pod.Status.StartTime.Time) > r.options().PodCreateContainerErrorTimeout {
+					return waiting.Reason, waiting.Message
+				}
+				continue
+			}
+			if waiting.Reason != waitingReasonErrImagePull && waiting.Reason != waitingReasonImagePullBackOff {
+				continue
+			}
+			if nonRetryableImagePullError(waiting.Message) {
+				return waitingReasonErrImagePull, waiting.Message
+			}
+			pullingImage = true
+		}
+	}
+	if !pullingImage {
+		return "", ""
+	}
+
+	// message of ImagePullBackOff is only `Back-off pulling image`, the pull error is in events
+	events, err := r.listPodEvents(ctx, pod)
+	if err != nil {
+		logger.Warnf("failed to list events of pod %s: %s", pod.Name, err.Error())
+		return "", ""
+	}
+	for _, event := range events.Items {
+		if event.Reason == "Failed" && nonRetryableImagePullError(event.Message) {
+			return waitingReasonErrImagePull, event.Message
+		}
+	}
+	return "", ""
+}
+
+func nonRetryableImagePullError(message string) bool {
+	message = strings.ToLower(message)
+	for _, msg := range authImagePullErrors {
+		if strings.Contains(message, msg) {
+			return false
+		}
+	}
+	for _, msg := range nonRetryableImagePullErrors {
+		if strings.Contains(message, msg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+)
+
+// DebugTasksPath is the path of introspection api of in-flight tasks,
+// `<DebugTasksPath>` lists tasks and `<DebugTasksPath>/<id>` describes a task with its kubernetes objects.
+const DebugTasksPath = "/debug/tasks"
+
+type taskError struct {
+	message string
+	time    time.Time
+}
+
+// DebugTask is the decoded local store of a task
+type DebugTask struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name,omitempty"`
+	Stage          string     `json:"stage"`
+	ExecutorIndex  *int       `json:"executorIndex,omitempty"`
+	ExecutorStatus string     `json:"executorStatus,omitempty"`
+	Stop           string     `json:"stop,omitempty"`
+	Processing     bool       `json:"processing,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	LastErrorTime  *time.Time `json:"lastErrorTime,omitempty"`
+	// RemoteState is the state in vetes-api, which is only filled by admin cli
+	RemoteState string        `json:"remoteState,omitempty"`
+	Jobs        []DebugObject `json:"jobs,omitempty"`
+	Pods        []DebugObject `json:"pods,omitempty"`
+	PVCs        []DebugObject `json:"pvcs,omitempty"`
+}
+
+// DebugObject is a kubernetes object owned by a task
+type DebugObject struct {
+	Name  string `json:"name"`
+	Phase string `json:"phase"`
+}
+
+// DebugHandler returns the read-only handler of DebugTasksPath, which should be registered on both
+// `<DebugTasksPath>` and `<DebugTasksPath>/`
+func (r *Runner) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		taskID := strings.Trim(strings.TrimPrefix(req.URL.Path, DebugTasksPath), "/")
+		var res interface{}
+		var err error
+		if taskID == "" {
+			res, err = r.ListTasks(req.Context())
+		} else {
+			res, err = r.DescribeTask(req.Context(), taskID)
+		}
+		if err != nil {
+			if errors.Is(err, localstore.ErrNotFound) {
+				http.Error(w, "task not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(res); err != nil {
+			log.Warnw("failed to write debug response", "err", err)
+		}
+	})
+}
+
+// ListTasks decodes all tasks in local store, sorted by id
+func (r *Runner) ListTasks(ctx context.Context) ([]*DebugTask, error) {
+	taskInfos, err := r.localStoreHelper.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*DebugTask, 0, len(taskInfos))
+	for _, taskInfo := range taskInfos {
+		res = append(res, r.newDebugTask(taskInfo))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+// DescribeTask decodes the task in local store with its jobs, pods and pvcs
+func (r *Runner) DescribeTask(ctx context.Context, taskID string) (*DebugTask, error) {
+	taskInfo, err := r.localStoreHelper.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	res := r.newDebugTask(taskInfo)
+	matchTask := []ctrlclient.ListOption{ctrlclient.InNamespace(r.namespace), ctrlclient.MatchingLabels{consts.LabelTaskID: taskID}}
+
+	jobs := &batchv1.JobList{}
+	if err = r.kubeClient.List(ctx, jobs, matchTask...); err != nil {
+		return nil, err
+	}
+	for _, job := range jobs.Items {
+		res.Jobs = append(res.Jobs, DebugObject{Name: job.Name, Phase: jobPhase(&job)})
+	}
+	pods := &corev1.PodList{}
+	if err = r.kubeClient.List(ctx, pods, matchTask...); err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		res.Pods = append(res.Pods, DebugObject{Name: pod.Name, Phase: string(pod.Status.Phase)})
+	}
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err = r.kubeClient.List(ctx, pvcs, matchTask...); err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs.Items {
+		res.PVCs = append(res.PVCs, DebugObject{Name: pvc.Name, Phase: string(pvc.Status.Phase)})
+	}
+	return res, nil
+}
+
+func (r *Runner) newDebugTask(taskInfo *localstore.TaskInfo) *DebugTask {
+	res := &DebugTask{
+		ID:    taskInfo.ID,
+		Name:  taskInfo.Name,
+		Stage: "<none>",
+	}
+	if taskInfo.Stage != nil {
+		res.Stage = taskStageName(*taskInfo.Stage)
+	}
+	if taskInfo.ExecutorStage != nil {
+		eStage := parseExecutorStageValue(*taskInfo.ExecutorStage)
+		res.ExecutorIndex = &eStage.index
+		res.ExecutorStatus = executorStatusName(eStage.status)
+	}
+	if taskInfo.Stop != nil {
+		res.Stop = *taskInfo.Stop
+	}
+
+	r.taskProcessingLock.Lock()
+	defer r.taskProcessingLock.Unlock()
+	_, res.Processing = r.taskProcessing[taskInfo.ID]
+	if lastError, ok := r.taskErrors[taskInfo.ID]; ok {
+		res.LastError = lastError.message
+		res.LastErrorTime = &lastError.time
+	}
+	return res
+}
+
+func (r *Runner) recordTaskError(taskID string, err error) {
+	if err == nil {
+		return
+	}
+	r.taskProcessingLock.Lock()
+	defer r.taskProcessingLock.Unlock()
+	if r.taskErrors == nil {
+		r.taskErrors = make(map[string]taskError)
+	}
+	r.taskErrors[taskID] = taskError{message: err.Error(), time: time.Now()}
+}
+
+func (r *Runner) forgetTaskError(taskID string) {
+	r.taskProcessingLock.Lock()
+	defer r.taskProcessingLock.Unlock()
+	delete(r.taskErrors, taskID)
+}
+
+// forgetUntrackedTaskErrors removes errors of tasks which left local store without forgetTask,
+// such as those deleted by hand or dropped before processing.
+func (r *Runner) forgetUntrackedTaskErrors(taskInfos []*localstore.TaskInfo) {
+	tracked := make(map[string]struct{}, len(taskInfos))
+	for _, taskInfo := range taskInfos {
+		tracked[taskInfo.ID] = struct{}{}
+	}
+	r.taskProcessingLock.Lock()
+	defer r.taskProcessingLock.Unlock()
+	for taskID := range r.taskErrors {
+		if _, ok := tracked[taskID]; !ok {
+			delete(r.taskErrors, taskID)
+		}
+	}
+}
+
+func jobPhase(job *batchv1.Job) string {
+	switch getJobStatus(job) {
+	case jobComplete:
+		return "Complete"
+	case jobFailed:
+		return "Failed"
+	default:
+		return "Running"
+	}
+}
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+)
+
+func TestDebugHandler(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	taskInfo := &localstore.TaskInfo{
+		Task:  localstore.Task{ID: fakeTaskID, Name: "task"},
+		Stage: utils.Point(taskStageRunning),
+	}
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{
+		{Task: localstore.Task{ID: "task-zzzz"}}, taskInfo,
+	}, nil)
+	fakeLocalStoreHelper.EXPECT().GetTask(gomock.Any(), fakeTaskID).Return(taskInfo, nil)
+	fakeLocalStoreHelper.EXPECT().GetTask(gomock.Any(), "task-none").Return(nil, localstore.ErrNotFound)
+
+	labels := map[string]string{consts.LabelTaskID: fakeTaskID}
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: fakeNamespace, Name: "job", Labels: labels}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: fakeNamespace, Name: "pod", Labels: labels},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: fakeNamespace, Name: "other"}},
+	).Build()
+
+	r := &Runner{
+		kubeClient:       fakeKubeClient,
+		localStoreHelper: fakeLocalStoreHelper,
+		namespace:        fakeNamespace,
+		taskProcessing:   map[string]struct{}{fakeTaskID: {}},
+	}
+	r.recordTaskError(fakeTaskID, errors.New("failed to create job"))
+	handler := r.DebugHandler()
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := serve(http.MethodGet, DebugTasksPath)
+	g.Expect(w.Code).To(gomega.Equal(http.StatusOK))
+	var tasks []*DebugTask
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &tasks)).To(gomega.Succeed())
+	g.Expect(tasks).To(gomega.HaveLen(2))
+	g.Expect(tasks[0].ID).To(gomega.Equal(fakeTaskID))
+	g.Expect(tasks[0].Stage).To(gomega.Equal("Running"))
+	g.Expect(tasks[0].Processing).To(gomega.BeTrue())
+	g.Expect(tasks[0].LastError).To(gomega.Equal("failed to create job"))
+	g.Expect(tasks[1].Stage).To(gomega.Equal("<none>"))
+
+	w = serve(http.MethodGet, DebugTasksPath+"/"+fakeTaskID)
+	g.Expect(w.Code).To(gomega.Equal(http.StatusOK))
+	task := &DebugTask{}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), task)).To(gomega.Succeed())
+	g.Expect(task.Jobs).To(gomega.Equal([]DebugObject{{Name: "job", Phase: "Running"}}))
+	g.Expect(task.Pods).To(gomega.Equal([]DebugObject{{Name: "pod", Phase: "Running"}}))
+	g.Expect(task.PVCs).To(gomega.BeEmpty())
+
+	g.Expect(serve(http.MethodGet, DebugTasksPath+"/task-none").Code).To(gomega.Equal(http.StatusNotFound))
+	g.Expect(serve(http.MethodPost, DebugTasksPath).Code).To(gomega.Equal(http.StatusMethodNotAllowed))
+
+	r.forgetTaskError(fakeTaskID)
+	g.Expect(r.taskErrors).To(gomega.BeEmpty())
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/crontab"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/drain"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+)
+
+// RegisterDrainCrontab stops tasks still running after the drain deadline
+func RegisterDrainCrontab(cron *crontab.Crontab, runner *Runner, drainHelper drain.Helper, opts *drain.Options) error {
+	return cron.RegisterCron(opts.CheckPeriod, func() {
+		runner.enforceDrainDeadline(context.Background(), drainHelper, opts.DeadlineAction)
+	})
+}
+
+// enforceDrainDeadline marks remaining tasks stopped with a system log, then they are cleaned by ProcessTask.
+// Requeued tasks are updated to QUEUED with empty cluster id, so that other clusters can run them.
+func (r *Runner) enforceDrainDeadline(ctx context.Context, drainHelper drain.Helper, action string) {
+	state, err := drainHelper.State(ctx)
+	if err != nil {
+		log.Warnw("failed to get drain state", "err", err)
+		return
+	}
+	if !state.DeadlinePassed(time.Now()) {
+		return
+	}
+	taskInfos, err := r.localStoreHelper.ListTasks(ctx)
+	if err != nil {
+		log.Warnw("failed to list tasks for drain deadline", "err", err)
+		return
+	}
+
+	stopState, result := consts.TaskSystemError, "failed"
+	if action == drain.DeadlineActionRequeue {
+		stopState, result = consts.TaskQueued, "requeued for other clusters"
+	}
+	for _, taskInfo := range taskInfos {
+		if taskInfo.Stop != nil {
+			continue
+		}
+		logger := r.taskLogger(taskInfo.ID)
+		logger.Warnf("cluster %s is drained for maintenance and the drain deadline %s has passed, the task is %s",
+			r.clusterID, state.Deadline.Format(time.RFC3339), result)
+		logger.Sync()
+		if err = r.localStoreHelper.StopTask(ctx, taskInfo.ID, stopState); err != nil && !errors.Is(err, localstore.ErrNotFound) {
+			log.Warnw("failed to stop task after drain deadline", "task", taskInfo.ID, "err", err)
+			continue
+		}
+		log.Infow("stopped task after drain deadline", "task", taskInfo.ID, "state", stopState)
+	}
+}
@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/drain"
+	drainfake "github.com/GBA-BI/tes-k8s-agent/pkg/drain/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+)
+
+func TestEnforceDrainDeadline(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	outputDir := t.TempDir()
+	fakeDrainHelper := drainfake.NewFakeHelper(mockctrl)
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(mockctrl)
+	r := &Runner{
+		opts:             &Options{TaskLog: TaskLogOptions{OutputDir: outputDir}},
+		localStoreHelper: fakeLocalStoreHelper,
+		clusterID:        fakeClusterID,
+	}
+
+	// deadline not passed
+	fakeDrainHelper.EXPECT().State(gomock.Any()).Return(&drain.State{Draining: true, Deadline: utils.Point(time.Now().Add(time.Hour))}, nil)
+	r.enforceDrainDeadline(context.Background(), fakeDrainHelper, drain.DeadlineActionRequeue)
+
+	fakeDrainHelper.EXPECT().State(gomock.Any()).Return(&drain.State{Draining: true, Deadline: utils.Point(time.Now().Add(-time.Hour))}, nil)
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{
+		{Task: localstore.Task{ID: fakeTaskID}},
+		{Task: localstore.Task{ID: "task-stopped"}, Stop: utils.Point(consts.TaskCanceled)},
+	}, nil)
+	fakeLocalStoreHelper.EXPECT().StopTask(gomock.Any(), fakeTaskID, consts.TaskQueued).Return(nil)
+	r.enforceDrainDeadline(context.Background(), fakeDrainHelper, drain.DeadlineActionRequeue)
+
+	content, err := os.ReadFile(filepath.Join(outputDir, fakeTaskID, taskLogFileName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(content)).To(gomega.ContainSubstring("the task is requeued for other clusters"))
+}
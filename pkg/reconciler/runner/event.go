@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// event reasons
+const (
+	eventReasonStageChanged            = "StageChanged"
+	eventReasonExecutorStageChanged    = "ExecutorStageChanged"
+	eventReasonTaskStopped             = "TaskStopped"
+	eventReasonJobCreated              = "JobCreated"
+	eventReasonJobFailed               = "JobFailed"
+	eventReasonImagePullBackOffTimeout = "ImagePullBackOffTimeout"
+	eventReasonUnschedulableTimeout    = "UnschedulableTimeout"
+	eventReasonContainerStartFailed    = "ContainerStartFailed"
+	eventReasonPVCBindTimeout          = "PVCBindTimeout"
+	eventReasonCleanupFailed           = "CleanupFailed"
+)
+
+// eventRecorder records kubernetes events of tasks. Normal events exceeding the rate limit are dropped,
+// while warning events of failures are always recorded, and similar events of the same object are
+// aggregated by client-go. A nil eventRecorder records nothing.
+type eventRecorder struct {
+	recorder record.EventRecorder
+	limiter  *rate.Limiter
+}
+
+func newEventRecorder(recorder record.EventRecorder, opts EventsOptions) *eventRecorder {
+	if recorder == nil || !opts.Enable {
+		return nil
+	}
+	return &eventRecorder{
+		recorder: recorder,
+		limiter:  rate.NewLimiter(rate.Limit(opts.QPS), opts.Burst),
+	}
+}
+
+func (e *eventRecorder) eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if e == nil || object == nil {
+		return
+	}
+	if eventType != corev1.EventTypeWarning && !e.limiter.Allow() {
+		log.Debugw("event dropped by rate limit", "reason", reason, "message", fmt.Sprintf(messageFmt, args...))
+		return
+	}
+	e.recorder.Eventf(object, eventType, reason, messageFmt, args...)
+}
+
+// taskEventf records event on the local store object of task
+func (r *Runner) taskEventf(ctx context.Context, taskID, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.events == nil {
+		return
+	}
+	object, err := r.localStoreHelper.TaskObject(ctx, taskID)
+	if err != nil {
+		log.Debugw("failed to get local store object for event", "task", taskID, "err", err)
+		return
+	}
+	r.events.eventf(object, eventType, reason, messageFmt, args...)
+}
+
+func (r *Runner) recordTaskStage(ctx context.Context, taskID string, stage int) error {
+	if err := r.localStoreHelper.RecordTaskStage(ctx, taskID, stage); err != nil {
+		return err
+	}
+	r.taskEventf(ctx, taskID, corev1.EventTypeNormal, eventReasonStageChanged, "task stage changed to %s", taskStageName(stage))
+	return nil
+}
+
+func (r *Runner) recordTaskExecutorStage(ctx context.Context, taskID string, stage int) error {
+	if err := r.localStoreHelper.RecordTaskExecutorStage(ctx, taskID, stage); err != nil {
+		return err
+	}
+	eStage := parseExecutorStageValue(stage)
+	r.taskEventf(ctx, taskID, corev1.EventTypeNormal, eventReasonExecutorStageChanged, "executor %d stage changed to %s",
+		eStage.index, executorStatusName(eStage.status))
+	return nil
+}
@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+)
+
+func TestRecordTaskStageEvent(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	fakeLocalStoreHelper.EXPECT().RecordTaskStage(gomock.Any(), fakeTaskID, taskStageRunning).Return(nil).Times(3)
+	fakeLocalStoreHelper.EXPECT().TaskObject(gomock.Any(), fakeTaskID).Return(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: fakeNamespace, Name: fakeTaskID},
+	}, nil).Times(3)
+
+	fakeRecorder := record.NewFakeRecorder(10)
+	r := &Runner{
+		localStoreHelper: fakeLocalStoreHelper,
+		events:           newEventRecorder(fakeRecorder, EventsOptions{Enable: true, QPS: 0.001, Burst: 1}),
+	}
+
+	g.Expect(r.recordTaskStage(context.Background(), fakeTaskID, taskStageRunning)).To(gomega.Succeed())
+	g.Expect(fakeRecorder.Events).To(gomega.Receive(gomega.Equal("Normal StageChanged task stage changed to Running")))
+
+	// dropped by rate limit
+	g.Expect(r.recordTaskStage(context.Background(), fakeTaskID, taskStageRunning)).To(gomega.Succeed())
+	g.Expect(fakeRecorder.Events).NotTo(gomega.Receive())
+
+	// warning events are not limited
+	r.taskEventf(context.Background(), fakeTaskID, corev1.EventTypeWarning, eventReasonJobFailed, "job %s failed", "job-0")
+	g.Expect(fakeRecorder.Events).To(gomega.Receive(gomega.Equal("Warning JobFailed job job-0 failed")))
+
+	// disabled
+	r.events = newEventRecorder(fakeRecorder, EventsOptions{Enable: false})
+	g.Expect(r.events).To(gomega.BeNil())
+	g.Expect(r.recordTaskStage(context.Background(), fakeTaskID, taskStageRunning)).To(gomega.Succeed())
+	g.Expect(fakeRecorder.Events).NotTo(gomega.Receive())
+}
@@ -28,7 +28,7 @@ func (r *Runner) doCreateExecutor(ctx context.Context, logger filelog.Logger, lo
 	if err := r.createJob(ctx, logger, job); err != nil {
 		return ctrl.Result{}, err
 	}
-	return ctrl.Result{}, r.localStoreHelper.RecordTaskExecutorStage(ctx, localTask.ID, newExecutorStageValue(index, executorStatusCreated))
+	return ctrl.Result{}, r.recordTaskExecutorStage(ctx, localTask.ID, newExecutorStageValue(index, executorStatusCreated))
 }
 
 // initExecutorBase ...
@@ -48,7 +48,8 @@ func (r *Runner) initExecutorBase(localTask *localstore.Task, index int, executo
 			},
 		},
 		Spec: batchv1.JobSpec{
-			BackoffLimit: utils.Point(int32(r.opts.ExecutorRetries)),
+			BackoffLimit:     utils.Point(int32(r.options().ExecutorRetries)),
+			PodFailurePolicy: r.podFailurePolicy(),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
@@ -89,7 +90,7 @@ func (r *Runner) initExecutorBase(localTask *localstore.Task, index int, executo
 	for k, v := range localTask.Executors[index].Env {
 		res.Spec.Template.Spec.Containers[0].Env = append(res.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: k, Value: v})
 	}
-	for k, v := range r.opts.ExecutorPodEnv {
+	for k, v := range r.options().ExecutorPodEnv {
 		res.Spec.Template.Spec.Containers[0].Env = append(res.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: strings.ToUpper(k), Value: v})
 	}
 	addBioosInfo(res, localTask.BioosInfo)
@@ -98,7 +99,7 @@ func (r *Runner) initExecutorBase(localTask *localstore.Task, index int, executo
 		r.addTaskVolumeMount(res, localTask)
 		r.accelerator.ModifyExecutor(&res.Spec.Template, localTask)
 	}
-	if r.opts.Transfer.Enable {
+	if r.options().Transfer.Enable {
 		r.addTransferMount(res, true)
 	}
 	return res
@@ -162,10 +163,10 @@ func addMeteringInfo(job *batchv1.Job, localTask *localstore.Task) {
 }
 
 func (r *Runner) addECSInfo(job *batchv1.Job, resources *localstore.Resources) {
-	for k, v := range r.opts.ExecutorECSPodLabels {
+	for k, v := range r.options().ExecutorECSPodLabels {
 		job.Spec.Template.Labels[k] = v
 	}
-	for k, v := range r.opts.ExecutorECSPodAnnotations {
+	for k, v := range r.options().ExecutorECSPodAnnotations {
 		job.Spec.Template.Annotations[k] = v
 	}
 
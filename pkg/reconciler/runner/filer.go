@@ -32,7 +32,8 @@ func (r *Runner) initFiler(localTask *localstore.Task, mode, s3SecretName string
 			},
 		},
 		Spec: batchv1.JobSpec{
-			BackoffLimit: utils.Point(int32(r.opts.FilerRetries)),
+			BackoffLimit:     utils.Point(int32(r.options().FilerRetries)),
+			PodFailurePolicy: r.podFailurePolicy(),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
@@ -44,9 +45,9 @@ func (r *Runner) initFiler(localTask *localstore.Task, mode, s3SecretName string
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{{
 						Name:            name,
-						Image:           r.opts.FilerImage.Image,
+						Image:           r.options().FilerImage.Image,
 						Args:            []string{mode},
-						Resources:       r.filerResources,
+						Resources:       r.filerResourceRequirements(),
 						ImagePullPolicy: corev1.PullAlways,
 						Env:             make([]corev1.EnvVar, 0),
 						VolumeMounts:    make([]corev1.VolumeMount, 0),
@@ -57,19 +58,19 @@ func (r *Runner) initFiler(localTask *localstore.Task, mode, s3SecretName string
 			},
 		},
 	}
-	if r.opts.FilerImage.ImagePullSecretName != "" {
-		res.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: r.opts.FilerImage.ImagePullSecretName}}
+	if r.options().FilerImage.ImagePullSecretName != "" {
+		res.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: r.options().FilerImage.ImagePullSecretName}}
 	}
 
 	r.addTaskVolumeMount(res, localTask)
 	r.addFilerInputsOutputsAnnotation(res, localTask, mode)
 	r.addFilerAccelerateMount(res, localTask, mode)
 	r.addFilerLogMount(res, localTask.ID)
-	if r.opts.Transfer.Enable {
+	if r.options().Transfer.Enable {
 		r.addTransferEnv(res)
 		r.addTransferMount(res, false)
 	}
-	if r.opts.S3.Enable {
+	if r.options().S3.Enable {
 		r.addFilerS3Mount(res, s3SecretName)
 	}
 
@@ -80,13 +81,13 @@ func (r *Runner) initFiler(localTask *localstore.Task, mode, s3SecretName string
 		})
 	}
 
-	for k, v := range r.opts.FilerPodLabels {
+	for k, v := range r.options().FilerPodLabels {
 		res.Spec.Template.Labels[k] = v
 	}
-	for k, v := range r.opts.FilerPodAnnotations {
+	for k, v := range r.options().FilerPodAnnotations {
 		res.Spec.Template.Annotations[k] = v
 	}
-	for k, v := range r.opts.FilerPodEnv {
+	for k, v := range r.options().FilerPodEnv {
 		res.Spec.Template.Spec.Containers[0].Env = append(res.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: strings.ToUpper(k), Value: v})
 	}
 	return res
@@ -95,7 +96,7 @@ func (r *Runner) initFiler(localTask *localstore.Task, mode, s3SecretName string
 func (r *Runner) addTaskVolumeMount(job *batchv1.Job, localTask *localstore.Task) {
 	job.Spec.Template.Spec.Containers[0].VolumeMounts = append(job.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
 		Name:      "task-volume",
-		MountPath: strings.TrimSuffix(r.opts.ExecutorBasePath, "/"),
+		MountPath: strings.TrimSuffix(r.options().ExecutorBasePath, "/"),
 		SubPath:   "dir-base",
 	})
 	job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, corev1.Volume{
@@ -163,17 +164,17 @@ func (r *Runner) addFilerAccelerateMount(job *batchv1.Job, localTask *localstore
 
 func (r *Runner) addFilerLogMount(job *batchv1.Job, taskID string) {
 	job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args,
-		[]string{"--log-level", r.opts.TaskLog.FilerLogLevel, "--log-file", filepath.Join(r.opts.TaskLog.OutputDir, taskID, taskLogFileName)}...)
+		[]string{"--log-level", r.options().TaskLog.FilerLogLevel, "--log-file", filepath.Join(r.options().TaskLog.OutputDir, taskID, taskLogFileName)}...)
 	job.Spec.Template.Spec.Containers[0].VolumeMounts = append(job.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
 		Name:      "log-volume",
-		MountPath: filepath.Join(r.opts.TaskLog.OutputDir, taskID),
+		MountPath: filepath.Join(r.options().TaskLog.OutputDir, taskID),
 		SubPath:   taskID,
 	})
 	job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, corev1.Volume{
 		Name: "log-volume",
 		VolumeSource: corev1.VolumeSource{
 			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-				ClaimName: r.opts.TaskLog.PVCName,
+				ClaimName: r.options().TaskLog.PVCName,
 			},
 		},
 	})
@@ -182,23 +183,23 @@ func (r *Runner) addFilerLogMount(job *batchv1.Job, taskID string) {
 func (r *Runner) addTransferEnv(job *batchv1.Job) {
 	job.Spec.Template.Spec.Containers[0].Env = append(job.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{
 		Name:  consts.HostBasePath,
-		Value: r.opts.Transfer.WESBasePath,
+		Value: r.options().Transfer.WESBasePath,
 	})
 	job.Spec.Template.Spec.Containers[0].Env = append(job.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{
 		Name:  consts.ContainerBasePath,
-		Value: r.opts.Transfer.TESBasePath,
+		Value: r.options().Transfer.TESBasePath,
 	})
 }
 func (r *Runner) addTransferMount(job *batchv1.Job, readOnly bool) {
 	job.Spec.Template.Spec.Containers[0].VolumeMounts = append(job.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
 		Name:      "transfer-volume",
-		MountPath: r.opts.Transfer.TESBasePath,
+		MountPath: r.options().Transfer.TESBasePath,
 	})
 	job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, corev1.Volume{
 		Name: "transfer-volume",
 		VolumeSource: corev1.VolumeSource{
 			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-				ClaimName: r.opts.Transfer.PVCName,
+				ClaimName: r.options().Transfer.PVCName,
 				ReadOnly:  readOnly,
 			},
 		},
@@ -247,7 +248,7 @@ func (r *Runner) addFilerS3Mount(job *batchv1.Job, s3SecretName string) {
 		VolumeSource: corev1.VolumeSource{
 			ConfigMap: &corev1.ConfigMapVolumeSource{
 				LocalObjectReference: corev1.LocalObjectReference{
-					Name: r.opts.S3.SDKConfigmapName,
+					Name: r.options().S3.SDKConfigmapName,
 				},
 				Items: []corev1.KeyToPath{
 					{Key: "config", Path: "config"},
@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/crontab"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
+)
+
+var orphanKinds = []string{"job", "pod", "pvc"}
+
+// RegisterGCCrontab deletes jobs, pods and pvcs of tasks not in local store periodically,
+// which are left with finalizers if local store is deleted by hand or cleaning tasks fails partway.
+// Tasks still in local store are cleaned by ProcessTask, after resync finds them terminal, missing or reassigned.
+func RegisterGCCrontab(cron *crontab.Crontab, runner *Runner) error {
+	opts := runner.options().GC
+	if opts.Period <= 0 {
+		return nil
+	}
+	return cron.RegisterCron(opts.Period, func() {
+		if err := runner.collectOrphans(context.Background(), opts); err != nil {
+			log.Errorw("collect orphans failed", "err", err)
+		}
+	})
+}
+
+// collectOrphans strips finalizers of objects whose task is not in local store,
+// and deletes them after they are orphaned for the grace period.
+func (r *Runner) collectOrphans(ctx context.Context, opts GCOptions) error {
+	objs, err := r.listOrphans(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	found := make(map[string]int, len(orphanKinds))
+	orphans := make(map[types.UID]time.Time, len(r.gcOrphans))
+	for _, obj := range objs {
+		taskID := obj.GetLabels()[consts.LabelTaskID]
+		kind := orphanKind(obj)
+		found[kind]++
+		name := fmt.Sprintf("%s/%s", kind, obj.GetName())
+		since, ok := r.gcOrphans[obj.GetUID()]
+		if !ok {
+			since = now
+			log.Infow("found orphan", "object", name, "task", taskID, "dryRun", opts.DryRun)
+		}
+		if opts.DryRun || now.Sub(since) < opts.GracePeriod {
+			orphans[obj.GetUID()] = since
+			continue
+		}
+		if err = r.deleteOrphan(ctx, obj); err != nil {
+			log.Warnw("failed to delete orphan", "object", name, "task", taskID, "err", err)
+			orphans[obj.GetUID()] = since
+			continue
+		}
+		log.Infow("deleted orphan", "object", name, "task", taskID)
+		metrics.GCDeletedObjectsTotal.WithLabelValues(kind).Inc()
+	}
+	// objects no longer orphaned or already gone are forgotten
+	r.gcOrphans = orphans
+	for _, kind := range orphanKinds {
+		metrics.GCOrphanObjects.WithLabelValues(kind).Set(float64(found[kind]))
+	}
+	return nil
+}
+
+// listOrphans returns jobs, pods and pvcs whose task is not in local store. Tasks in local store are
+// never orphans, even if they are terminal in vetes-api, because ProcessTask may be cleaning them.
+func (r *Runner) listOrphans(ctx context.Context) ([]ctrlclient.Object, error) {
+	taskInfos, err := r.localStoreHelper.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.forgetUntrackedTaskErrors(taskInfos)
+	liveTasks := make(map[string]struct{}, len(taskInfos))
+	for _, taskInfo := range taskInfos {
+		liveTasks[taskInfo.ID] = struct{}{}
+	}
+
+	objs, err := r.listTaskObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]ctrlclient.Object, 0)
+	for _, obj := range objs {
+		if _, ok := liveTasks[obj.GetLabels()[consts.LabelTaskID]]; ok {
+			continue
+		}
+		res = append(res, obj)
+	}
+	return res, nil
+}
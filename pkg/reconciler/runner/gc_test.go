@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
+)
+
+func TestCollectOrphans(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	objectMeta := func(name, taskID string, finalizers ...string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Namespace:  fakeNamespace,
+			Name:       name,
+			UID:        types.UID(name),
+			Labels:     map[string]string{consts.LabelTaskID: taskID},
+			Finalizers: finalizers,
+		}
+	}
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(
+		&batchv1.Job{ObjectMeta: objectMeta("live-job", fakeTaskID, consts.ProcessTaskFinalizer)},
+		&batchv1.Job{ObjectMeta: objectMeta("untracked-job", "task-untracked", consts.ProcessTaskFinalizer)},
+		&corev1.Pod{ObjectMeta: objectMeta("untracked-pod", "task-untracked", consts.ProcessExecutorTimeFinalizer)},
+		&corev1.PersistentVolumeClaim{ObjectMeta: objectMeta("terminal-pvc", "task-terminal", consts.ProcessTaskFinalizer)},
+	).Build()
+
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(mockctrl)
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return([]*localstore.TaskInfo{
+		{Task: localstore.Task{ID: fakeTaskID}},
+		{Task: localstore.Task{ID: "task-terminal"}},
+	}, nil).Times(3)
+
+	r := &Runner{
+		kubeClient:       fakeKubeClient,
+		localStoreHelper: fakeLocalStoreHelper,
+		namespace:        fakeNamespace,
+		gcOrphans:        make(map[types.UID]time.Time),
+	}
+	opts := GCOptions{GracePeriod: time.Minute * 30}
+	countObjects := func() int {
+		jobs, pods, pvcs := &batchv1.JobList{}, &corev1.PodList{}, &corev1.PersistentVolumeClaimList{}
+		g.Expect(fakeKubeClient.List(context.Background(), jobs)).To(gomega.Succeed())
+		g.Expect(fakeKubeClient.List(context.Background(), pods)).To(gomega.Succeed())
+		g.Expect(fakeKubeClient.List(context.Background(), pvcs)).To(gomega.Succeed())
+		return len(jobs.Items) + len(pods.Items) + len(pvcs.Items)
+	}
+
+	// orphans are kept in the grace period
+	g.Expect(r.collectOrphans(context.Background(), opts)).To(gomega.Succeed())
+	g.Expect(countObjects()).To(gomega.Equal(4))
+	g.Expect(r.gcOrphans).To(gomega.HaveLen(2))
+	g.Expect(testutil.ToFloat64(metrics.GCOrphanObjects.WithLabelValues("job"))).To(gomega.Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(metrics.GCOrphanObjects.WithLabelValues("pod"))).To(gomega.Equal(float64(1)))
+	// objects of tasks in local store are not orphans even if the task is terminal in vetes-api
+	g.Expect(testutil.ToFloat64(metrics.GCOrphanObjects.WithLabelValues("pvc"))).To(gomega.Equal(float64(0)))
+
+	// orphans are only counted in dry run
+	for uid := range r.gcOrphans {
+		r.gcOrphans[uid] = time.Now().Add(-time.Hour)
+	}
+	g.Expect(r.collectOrphans(context.Background(), GCOptions{GracePeriod: opts.GracePeriod, DryRun: true})).To(gomega.Succeed())
+	g.Expect(countObjects()).To(gomega.Equal(4))
+
+	g.Expect(r.collectOrphans(context.Background(), opts)).To(gomega.Succeed())
+	g.Expect(countObjects()).To(gomega.Equal(2))
+	g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Namespace: fakeNamespace, Name: "live-job"}, &batchv1.Job{})).To(gomega.Succeed())
+	g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKey{Namespace: fakeNamespace, Name: "terminal-pvc"}, &corev1.PersistentVolumeClaim{})).To(gomega.Succeed())
+	g.Expect(r.gcOrphans).To(gomega.BeEmpty())
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -15,6 +17,8 @@ import (
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/filelog"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/tracing"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
 )
 
@@ -54,7 +58,55 @@ func jobFinished(job *batchv1.Job) bool {
 	return getJobStatus(job) != jobRunning
 }
 
-func (r *Runner) createJob(ctx context.Context, logger filelog.Logger, job *batchv1.Job) error {
+// jobFinishTime returns the transition time of Complete or Failed condition, or now if not found
+func jobFinishTime(job *batchv1.Job) time.Time {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobFailed || condition.Type == batchv1.JobComplete) && condition.Status == corev1.ConditionTrue {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return time.Now()
+}
+
+// observeJobStage observes the duration from creation to finish of a finished job,
+// and retries of executor job, which are the failed pods except the last one of a failed job.
+func observeJobStage(stage string, job *batchv1.Job) {
+	result := metrics.StageSucceeded
+	retries := job.Status.Failed
+	if getJobStatus(job) == jobFailed {
+		result = metrics.StageFailed
+		retries--
+	}
+	metrics.StageDurationSeconds.WithLabelValues(stage, result).Observe(jobFinishTime(job).Sub(job.CreationTimestamp.Time).Seconds())
+	if stage == metrics.StageExecutor && retries > 0 {
+		metrics.ExecutorRetriesTotal.Add(float64(retries))
+	}
+}
+
+// podFailurePolicy makes pods failed by eviction, preemption or node failure not count towards backoffLimit,
+// which needs JobPodFailurePolicy and PodDisruptionConditions feature gates, enabled by default since kubernetes 1.26.
+func (r *Runner) podFailurePolicy() *batchv1.PodFailurePolicy {
+	if !r.options().IgnorePodDisruptions {
+		return nil
+	}
+	return &batchv1.PodFailurePolicy{
+		Rules: []batchv1.PodFailurePolicyRule{{
+			Action: batchv1.PodFailurePolicyActionIgnore,
+			OnPodConditions: []batchv1.PodFailurePolicyOnPodConditionsPattern{{
+				Type:   corev1.DisruptionTarget,
+				Status: corev1.ConditionTrue,
+			}},
+		}},
+	}
+}
+
+func (r *Runner) createJob(ctx context.Context, logger filelog.Logger, job *batchv1.Job) (reterr error) {
+	ctx, span := tracing.Start(ctx, "create job", trace.WithAttributes(attribute.String("job", job.Name)))
+	defer func() {
+		tracing.EndSpan(span, reterr)
+	}()
+	addTraceParentEnv(&job.Spec.Template, tracing.TraceParent(ctx))
+
 	controllerutil.AddFinalizer(job, consts.ProcessTaskFinalizer)
 	if err := r.kubeClient.Create(ctx, job); err != nil {
 		if k8sapierrors.IsAlreadyExists(err) {
@@ -63,9 +115,23 @@ func (r *Runner) createJob(ctx context.Context, logger filelog.Logger, job *batc
 		return fmt.Errorf("failed to create job: %w", err)
 	}
 	logger.Infof("created job %s", job.Name)
+	r.events.eventf(job, corev1.EventTypeNormal, eventReasonJobCreated, "created job of task %s", job.Labels[consts.LabelTaskID])
 	return nil
 }
 
+// addTraceParentEnv propagates trace context to filer and executor, so that their spans are children of the job
+func addTraceParentEnv(podTemplate *corev1.PodTemplateSpec, traceParent string) {
+	if traceParent == "" {
+		return
+	}
+	for index := range podTemplate.Spec.Containers {
+		podTemplate.Spec.Containers[index].Env = append(podTemplate.Spec.Containers[index].Env, corev1.EnvVar{
+			Name:  consts.TraceParent,
+			Value: traceParent,
+		})
+	}
+}
+
 func (r *Runner) deleteJob(ctx context.Context, logger filelog.Logger, jobName string) error {
 	job := &batchv1.Job{}
 	if err := r.kubeClient.Get(ctx, ctrlclient.ObjectKey{Namespace: r.namespace, Name: jobName}, job); err != nil {
@@ -28,14 +28,31 @@ type Options struct {
 	FilerRetries               int                            `mapstructure:"filerRetries"`
 	PodPollInterval            time.Duration                  `mapstructure:"podPollInterval"`
 	PodImagePullBackoffTimeout time.Duration                  `mapstructure:"podImagePullBackoffTimeout"`
-	FilerPodLabels             map[string]string              `mapstructure:"filerPodLabels"`
-	FilerPodAnnotations        map[string]string              `mapstructure:"filerPodAnnotations"`
-	ExecutorECSPodLabels       map[string]string              `mapstructure:"executorECSPodLabels"`
-	ExecutorECSPodAnnotations  map[string]string              `mapstructure:"executorECSPodAnnotations"`
-	ExecutorPodEnv             map[string]string              `mapstructure:"executorPodEnv"`
-	FilerPodEnv                map[string]string              `mapstructure:"filerPodEnv"`
-	TaskLog                    TaskLogOptions                 `mapstructure:"taskLog"`
-	Transfer                   TransferOptions                `mapstructure:"transfer"`
+	// PodCreateContainerErrorTimeout is how long containers of a pod can fail to be created before its job stops.
+	// CreateContainerConfigError and CreateContainerError are not failed immediately, because they are often transient,
+	// e.g. a secret or configmap referred by the pod is created a bit later, or the container runtime is busy.
+	// PodUnschedulableTimeout is how long a pod can be unschedulable before its task fails,
+	// PodScaleUpUnschedulableTimeout applies instead once cluster-autoscaler triggered scale up for the pod, which should not be shorter.
+	PodCreateContainerErrorTimeout time.Duration     `mapstructure:"podCreateContainerErrorTimeout"`
+	PodUnschedulableTimeout        time.Duration     `mapstructure:"podUnschedulableTimeout"`
+	PodScaleUpUnschedulableTimeout time.Duration     `mapstructure:"podScaleUpUnschedulableTimeout"`
+	FilerPodLabels                 map[string]string `mapstructure:"filerPodLabels"`
+	FilerPodAnnotations            map[string]string `mapstructure:"filerPodAnnotations"`
+	ExecutorECSPodLabels           map[string]string `mapstructure:"executorECSPodLabels"`
+	ExecutorECSPodAnnotations      map[string]string `mapstructure:"executorECSPodAnnotations"`
+	ExecutorPodEnv                 map[string]string `mapstructure:"executorPodEnv"`
+	FilerPodEnv                    map[string]string `mapstructure:"filerPodEnv"`
+	TaskLog                        TaskLogOptions    `mapstructure:"taskLog"`
+	Transfer                       TransferOptions   `mapstructure:"transfer"`
+	Events                         EventsOptions     `mapstructure:"events"`
+	// FallbackStorageClass is used to recreate task pvc which is not bound in PVCBindTimeout,
+	// task fails with SYSTEM_ERROR instead if it is empty.
+	FallbackStorageClass string        `mapstructure:"fallbackStorageClass"`
+	PVCBindTimeout       time.Duration `mapstructure:"pvcBindTimeout"`
+	// IgnorePodDisruptions makes pods disrupted by eviction, preemption or node failure not count towards retries
+	IgnorePodDisruptions bool          `mapstructure:"ignorePodDisruptions"`
+	Resync               ResyncOptions `mapstructure:"resync"`
+	GC                   GCOptions     `mapstructure:"gc"`
 }
 
 // S3Options ...
@@ -68,6 +85,12 @@ type TaskLogOptions struct {
 	OutputDir     string `mapstructure:"outputDir"`
 	PVCName       string `mapstructure:"pvcName"`
 	FilerLogLevel string `mapstructure:"filerLogLevel"`
+	// ShipInterval is the interval to ship new task logs to vetes-api while task running, 0 to ship only on finish
+	ShipInterval time.Duration `mapstructure:"shipInterval"`
+	// MaxSystemLogBytes caps task logs shipped to vetes-api, the middle part of bigger logs is truncated,
+	// and the full log is archived in OutputDir for ArchiveRetention.
+	MaxSystemLogBytes int64         `mapstructure:"maxSystemLogBytes"`
+	ArchiveRetention  time.Duration `mapstructure:"archiveRetention"`
 }
 
 // TransferOptions ...
@@ -78,6 +101,30 @@ type TransferOptions struct {
 	PVCName     string `mapstructure:"pvcName"`
 }
 
+// EventsOptions ...
+type EventsOptions struct {
+	Enable bool `mapstructure:"enable"`
+	// QPS and Burst limit normal kubernetes events recorded by runner, warning events are not limited
+	QPS   float64 `mapstructure:"qps"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// ResyncOptions ...
+type ResyncOptions struct {
+	// Period of comparing tasks in local store with vetes-api, 0 to disable
+	Period time.Duration `mapstructure:"period"`
+}
+
+// GCOptions ...
+type GCOptions struct {
+	// Period of collecting jobs, pods and pvcs of tasks not in local store, 0 to disable
+	Period time.Duration `mapstructure:"period"`
+	// GracePeriod is how long an object stays orphaned before it is deleted
+	GracePeriod time.Duration `mapstructure:"gracePeriod"`
+	// DryRun only logs and counts orphans
+	DryRun bool `mapstructure:"dryRun"`
+}
+
 // NewOptions ...
 func NewOptions() *Options {
 	return &Options{
@@ -96,20 +143,38 @@ func NewOptions() *Options {
 				string(corev1.ResourceMemory): "1Gi",
 			},
 		},
-		StorageClass:               "ebs-ssd",
-		ExecutorRetries:            2,
-		FilerRetries:               2,
-		PodPollInterval:            time.Minute,
-		PodImagePullBackoffTimeout: 10 * time.Minute,
+		StorageClass:                   "ebs-ssd",
+		PVCBindTimeout:                 10 * time.Minute,
+		ExecutorRetries:                2,
+		FilerRetries:                   2,
+		IgnorePodDisruptions:           true,
+		PodPollInterval:                time.Minute,
+		PodImagePullBackoffTimeout:     10 * time.Minute,
+		PodCreateContainerErrorTimeout: 5 * time.Minute,
+		PodUnschedulableTimeout:        15 * time.Minute,
+		PodScaleUpUnschedulableTimeout: 45 * time.Minute,
+		Resync:                         ResyncOptions{Period: 10 * time.Minute},
+		GC: GCOptions{
+			Period:      10 * time.Minute,
+			GracePeriod: 30 * time.Minute,
+		},
 		TaskLog: TaskLogOptions{
-			OutputDir:     "/app/log",
-			FilerLogLevel: "info",
+			OutputDir:         "/app/log",
+			FilerLogLevel:     "info",
+			ShipInterval:      30 * time.Second,
+			MaxSystemLogBytes: 1 << 20,
+			ArchiveRetention:  7 * 24 * time.Hour,
 		},
 		Transfer: TransferOptions{
 			Enable:      false,
 			WESBasePath: "/data",
 			TESBasePath: "/transfer",
 		},
+		Events: EventsOptions{
+			Enable: true,
+			QPS:    5,
+			Burst:  25,
+		},
 	}
 }
 
@@ -154,6 +219,12 @@ func (o *Options) Validate() error {
 	if o.StorageClass == "" {
 		return errors.New("empty storageClass")
 	}
+	if o.FallbackStorageClass == o.StorageClass {
+		return errors.New("fallbackStorageClass must not be the same as storageClass")
+	}
+	if o.PVCBindTimeout <= 0 {
+		return errors.New("pvcBindTimeout must be greater than 0")
+	}
 	if o.ExecutorRetries < 0 {
 		return errors.New("executorRetries must be greater than or equal to 0")
 	}
@@ -166,6 +237,22 @@ func (o *Options) Validate() error {
 	if o.PodImagePullBackoffTimeout <= 0 {
 		return errors.New("imagePullBackoffTimeout must be greater than 0")
 	}
+	if o.PodCreateContainerErrorTimeout <= 0 {
+		return errors.New("podCreateContainerErrorTimeout must be greater than 0")
+	}
+	if o.PodUnschedulableTimeout <= 0 || o.PodScaleUpUnschedulableTimeout <= 0 {
+		return errors.New("podUnschedulableTimeout and podScaleUpUnschedulableTimeout must be greater than 0")
+	}
+	// scale up timeout only extends the unschedulable timeout, a shorter one never takes effect
+	if o.PodScaleUpUnschedulableTimeout < o.PodUnschedulableTimeout {
+		return errors.New("podScaleUpUnschedulableTimeout must be greater than or equal to podUnschedulableTimeout")
+	}
+	if o.Resync.Period < 0 {
+		return errors.New("resync.period must be greater than or equal to 0")
+	}
+	if o.GC.Period < 0 || o.GC.GracePeriod < 0 {
+		return errors.New("gc.period and gc.gracePeriod must be greater than or equal to 0")
+	}
 
 	s, err := os.Stat(o.TaskLog.OutputDir)
 	if err != nil {
@@ -182,6 +269,15 @@ func (o *Options) Validate() error {
 	default:
 		return errors.New("invalid filer log level")
 	}
+	if o.TaskLog.ShipInterval < 0 {
+		return errors.New("taskLog shipInterval must be greater than or equal to 0")
+	}
+	if o.TaskLog.MaxSystemLogBytes < minSystemLogBytes {
+		return fmt.Errorf("taskLog maxSystemLogBytes must be greater than or equal to %d", minSystemLogBytes)
+	}
+	if o.TaskLog.ArchiveRetention <= 0 {
+		return errors.New("taskLog archiveRetention must be greater than 0")
+	}
 
 	if o.Transfer.Enable {
 		if !path.IsAbs(o.Transfer.WESBasePath) {
@@ -198,6 +294,10 @@ func (o *Options) Validate() error {
 		}
 	}
 
+	if o.Events.Enable && (o.Events.QPS <= 0 || o.Events.Burst <= 0) {
+		return errors.New("events.qps and events.burst must be greater than 0")
+	}
+
 	return nil
 }
 
@@ -214,10 +314,20 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringToStringVar(&o.FilerResources.Limits, "filer-resources-limits", o.FilerResources.Limits, "filer resources limits")
 	fs.StringToStringVar(&o.FilerResources.Requests, "filer-resources-requests", o.FilerResources.Requests, "filer resources requests")
 	fs.StringVar(&o.StorageClass, "storage-class", o.StorageClass, "storageClass name")
+	fs.StringVar(&o.FallbackStorageClass, "fallback-storage-class", o.FallbackStorageClass, "storageClass name to recreate pvc not bound in time, empty to fail the task")
+	fs.DurationVar(&o.PVCBindTimeout, "pvc-bind-timeout", o.PVCBindTimeout, "pvc bind timeout")
 	fs.IntVar(&o.ExecutorRetries, "executor-retries", o.ExecutorRetries, "executor retries times")
 	fs.IntVar(&o.FilerRetries, "filer-retries", o.FilerRetries, "filer retries times")
+	fs.BoolVar(&o.IgnorePodDisruptions, "ignore-pod-disruptions", o.IgnorePodDisruptions, "not count pods disrupted by eviction, preemption or node failure towards retries")
 	fs.DurationVar(&o.PodPollInterval, "pod-poll-interval", o.PodPollInterval, "pod poll interval")
 	fs.DurationVar(&o.PodImagePullBackoffTimeout, "pod-image-pull-backoff-timeout", o.PodImagePullBackoffTimeout, "pod ImagePullBackOff timeout")
+	fs.DurationVar(&o.PodCreateContainerErrorTimeout, "pod-create-container-error-timeout", o.PodCreateContainerErrorTimeout, "pod CreateContainerError and CreateContainerConfigError timeout, not failed immediately because they are often transient, e.g. a secret created later")
+	fs.DurationVar(&o.PodUnschedulableTimeout, "pod-unschedulable-timeout", o.PodUnschedulableTimeout, "pod unschedulable timeout")
+	fs.DurationVar(&o.PodScaleUpUnschedulableTimeout, "pod-scale-up-unschedulable-timeout", o.PodScaleUpUnschedulableTimeout, "pod unschedulable timeout after cluster-autoscaler triggered scale up")
+	fs.DurationVar(&o.Resync.Period, "resync-period", o.Resync.Period, "period of resyncing tasks in local store with vetes-api, 0 to disable")
+	fs.DurationVar(&o.GC.Period, "gc-period", o.GC.Period, "period of collecting orphaned jobs, pods and pvcs, 0 to disable")
+	fs.DurationVar(&o.GC.GracePeriod, "gc-grace-period", o.GC.GracePeriod, "how long an object stays orphaned before it is deleted")
+	fs.BoolVar(&o.GC.DryRun, "gc-dry-run", o.GC.DryRun, "only log and count orphaned jobs, pods and pvcs")
 	fs.StringToStringVar(&o.FilerPodLabels, "filer-pod-labels", o.FilerPodLabels, "filer pod labels")
 	fs.StringToStringVar(&o.FilerPodAnnotations, "filer-pod-annotations", o.FilerPodAnnotations, "filer pod annotations")
 	fs.StringToStringVar(&o.ExecutorECSPodLabels, "executor-ecs-pod-labels", o.ExecutorECSPodLabels, "executor ECS pod labels")
@@ -227,8 +337,14 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.TaskLog.OutputDir, "task-log-output-dir", o.TaskLog.OutputDir, "taskLog outputDir")
 	fs.StringVar(&o.TaskLog.PVCName, "task-log-pvc-name", o.TaskLog.PVCName, "taskLog pvcName")
 	fs.StringVar(&o.TaskLog.FilerLogLevel, "task-log-filer-log-level", o.TaskLog.FilerLogLevel, "taskLog filerLogLevel")
+	fs.DurationVar(&o.TaskLog.ShipInterval, "task-log-ship-interval", o.TaskLog.ShipInterval, "interval to ship task logs while running, 0 to ship only on finish")
+	fs.Int64Var(&o.TaskLog.MaxSystemLogBytes, "task-log-max-system-log-bytes", o.TaskLog.MaxSystemLogBytes, "max bytes of task logs shipped to vetes-api")
+	fs.DurationVar(&o.TaskLog.ArchiveRetention, "task-log-archive-retention", o.TaskLog.ArchiveRetention, "retention of archived full task logs")
 	fs.BoolVar(&o.Transfer.Enable, "transfer-enable", o.Transfer.Enable, "enable transfer")
 	fs.StringVar(&o.Transfer.WESBasePath, "transfer-wes-base-path", o.Transfer.WESBasePath, "transfer wes base path")
 	fs.StringVar(&o.Transfer.TESBasePath, "transfer-tes-base-path", o.Transfer.TESBasePath, "transfer tes base path")
 	fs.StringVar(&o.Transfer.PVCName, "transfer-pvc-name", o.Transfer.PVCName, "transfer pvc name")
+	fs.BoolVar(&o.Events.Enable, "events-enable", o.Events.Enable, "enable kubernetes events of tasks")
+	fs.Float64Var(&o.Events.QPS, "events-qps", o.Events.QPS, "qps of normal kubernetes events of tasks, warning events are not limited")
+	fs.IntVar(&o.Events.Burst, "events-burst", o.Events.Burst, "burst of normal kubernetes events of tasks")
 }
@@ -0,0 +1,24 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+func TestValidateUnschedulableTimeout(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	opts := NewOptions()
+	opts.S3.Enable = false
+	opts.FilerImage.Image = "filer:latest"
+	opts.StorageClass = "ebs-ssd"
+	opts.TaskLog.OutputDir = t.TempDir()
+	opts.TaskLog.PVCName = "task-log"
+	g.Expect(opts.Validate()).To(gomega.Succeed())
+
+	opts.PodUnschedulableTimeout = time.Minute * 15
+	opts.PodScaleUpUnschedulableTimeout = time.Minute * 10
+	g.Expect(opts.Validate()).NotTo(gomega.Succeed())
+}
@@ -14,11 +14,14 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/filelog"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
@@ -44,17 +47,24 @@ func (r *Runner) ProcessPod(ctx context.Context, podName string) (ctrl.Result, e
 	newLogger := r.taskLogger(taskID)
 
 	r.recordExecutorLog(ctx, newLogger, pod)
+	r.recordPodDisruption(newLogger, taskID, pod)
 
 	result1, err := r.processExecutorTime(ctx, newLogger, taskID, pod)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	r.reportExecutorUsage(newLogger, pod)
 
 	result2, err := r.processImagePullBackoff(ctx, newLogger, pod)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-	return utils.MergeCtrlResults(result1, result2), nil
+
+	result3, err := r.processUnschedulable(ctx, newLogger, taskID, pod)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return utils.MergeCtrlResults(result1, result2, result3), nil
 }
 
 // Normally executor don't have any logs. In case pod failed unexpectedly, we record executor pod logs when it finished.
@@ -87,6 +97,66 @@ func (r *Runner) recordExecutorLog(ctx context.Context, newLogger filelog.Logger
 	}
 }
 
+// recordPodDisruption records why a non-executor pod is disrupted by eviction, preemption or node failure,
+// which is retried without counting towards retries if IgnorePodDisruptions. Each pod is recorded once by uid,
+// disruptions of executor pods are shipped with their executor logs by processExecutorTime instead.
+func (r *Runner) recordPodDisruption(newLogger filelog.Logger, taskID string, pod *corev1.Pod) {
+	if pod.Labels[consts.LabelType] == consts.ExecutorType {
+		return
+	}
+	message := podDisruptionMessage(pod)
+	if message == "" || !r.markPodDisruption(taskID, pod.UID) {
+		return
+	}
+	newLogger.Warnf("%s", message)
+}
+
+// markPodDisruption returns false if the disruption of pod is already recorded
+func (r *Runner) markPodDisruption(taskID string, uid types.UID) bool {
+	r.taskProcessingLock.Lock()
+	defer r.taskProcessingLock.Unlock()
+	if r.podDisruptions == nil {
+		r.podDisruptions = make(map[string]map[types.UID]struct{})
+	}
+	if _, ok := r.podDisruptions[taskID][uid]; ok {
+		return false
+	}
+	if r.podDisruptions[taskID] == nil {
+		r.podDisruptions[taskID] = make(map[types.UID]struct{})
+	}
+	r.podDisruptions[taskID][uid] = struct{}{}
+	return true
+}
+
+func (r *Runner) forgetPodDisruptions(taskID string) {
+	r.taskProcessingLock.Lock()
+	defer r.taskProcessingLock.Unlock()
+	delete(r.podDisruptions, taskID)
+}
+
+// podDisruptionMessage returns empty if pod is not failed by disruption
+func podDisruptionMessage(pod *corev1.Pod) string {
+	if pod.Status.Phase != corev1.PodFailed {
+		return ""
+	}
+	disruption := podDisruptionCondition(pod)
+	if disruption == nil {
+		return ""
+	}
+	return fmt.Sprintf("pod %s is disrupted, reason[%s] and message: %s", pod.Name, disruption.Reason, disruption.Message)
+}
+
+// podDisruptionCondition returns DisruptionTarget condition of pod, which is set by kubernetes 1.26+
+func podDisruptionCondition(pod *corev1.Pod) *corev1.PodCondition {
+	for index := range pod.Status.Conditions {
+		condition := &pod.Status.Conditions[index]
+		if condition.Type == corev1.DisruptionTarget && condition.Status == corev1.ConditionTrue {
+			return condition
+		}
+	}
+	return nil
+}
+
 func (r *Runner) processExecutorTime(ctx context.Context, newLogger filelog.Logger, taskID string, pod *corev1.Pod) (ctrl.Result, error) {
 	if pod.Labels[consts.LabelType] != consts.ExecutorType {
 		return ctrl.Result{}, nil
@@ -124,6 +194,12 @@ func (r *Runner) processExecutorTime(ctx context.Context, newLogger filelog.Logg
 	}
 
 	taskLogs := r.genUpdateTaskLogsExecutor(task.Logs, executorNo, pod.Name, startTime, endTime)
+	if taskLogs[0].Logs[executorNo][0].EndTime != nil {
+		// the end time of an executor attempt is only set once, so is its disruption
+		if message := podDisruptionMessage(pod); message != "" {
+			taskLogs[0].SystemLogs = []string{message}
+		}
+	}
 	updateTaskReq := &models.UpdateTaskRequest{ID: taskID, Logs: taskLogs}
 	if _, err = r.vetesClient.UpdateTask(ctx, updateTaskReq); err != nil {
 		if errors.Is(err, vetesclient.ErrBadRequest) {
@@ -142,6 +218,31 @@ func (r *Runner) processExecutorTime(ctx context.Context, newLogger filelog.Logg
 	return ctrl.Result{}, nil
 }
 
+// reportExecutorUsage writes sampled usage of a finished executor pod to task system logs for right-sizing
+func (r *Runner) reportExecutorUsage(newLogger filelog.Logger, pod *corev1.Pod) {
+	if pod.Labels[consts.LabelType] != consts.ExecutorType {
+		return
+	}
+	if pod.Status.Phase != corev1.PodFailed && pod.Status.Phase != corev1.PodSucceeded {
+		return
+	}
+	podUsage := r.usage.Pop(pod.Name)
+	if podUsage == nil {
+		return
+	}
+	var wallTime time.Duration
+	if startTime, endTime := getExecutorTime(pod); startTime != nil && endTime != nil {
+		start, _ := time.Parse(time.RFC3339, *startTime)
+		end, _ := time.Parse(time.RFC3339, *endTime)
+		wallTime = end.Sub(start)
+	}
+	var requests corev1.ResourceList
+	if len(pod.Spec.Containers) > 0 {
+		requests = pod.Spec.Containers[0].Resources.Requests
+	}
+	newLogger.Infof("executor %s pod %s usage: %s", pod.Labels[consts.LabelExecutorNo], pod.Name, podUsage.Summary(wallTime, requests))
+}
+
 func (r *Runner) genUpdateTaskLogsExecutor(taskLogs []*models.TaskLog, executorNo int, executorID string, startTime, endTime *string) []*models.TaskLog {
 	res := []*models.TaskLog{{
 		ClusterID: r.clusterID,
@@ -192,7 +293,12 @@ func getExecutorTime(pod *corev1.Pod) (startTime, endTime *string) {
 				}
 			}
 			if endTime == nil {
-				endTime = utils.Point(time.Now().Format(time.RFC3339))
+				if disruption := podDisruptionCondition(pod); disruption != nil && !disruption.LastTransitionTime.IsZero() {
+					// e.g. pod on lost node, whose container is still running in status
+					endTime = utils.Point(disruption.LastTransitionTime.Format(time.RFC3339))
+				} else {
+					endTime = utils.Point(time.Now().Format(time.RFC3339))
+				}
 			}
 		} else if pod.Status.Phase == corev1.PodRunning {
 			// if pod started and being deleted and stuck for a long time, set endTime to DeletionTimestamp
@@ -264,13 +370,25 @@ func (r *Runner) processImagePullBackoff(ctx context.Context, newLogger filelog.
 	if pod.Status.Phase != corev1.PodPending {
 		return ctrl.Result{}, nil
 	}
+	if reason, message := r.nonRetryableContainerError(ctx, newLogger, pod); reason != "" {
+		newLogger.Errorf("%s: pod %s can not start: %s", reason, pod.Name, message)
+		r.events.eventf(job, corev1.EventTypeWarning, eventReasonContainerStartFailed, "stop job because pod %s can not start: %s", pod.Name, reason)
+		if err := r.stopJob(ctx, newLogger, job); err != nil {
+			return ctrl.Result{}, err
+		}
+		metrics.ContainerStartFailuresTotal.WithLabelValues(reason).Inc()
+		return ctrl.Result{}, nil
+	}
 	if !r.podImagePullBackoffTimeout(pod) {
-		return ctrl.Result{RequeueAfter: r.opts.PodPollInterval}, nil
+		return ctrl.Result{RequeueAfter: r.options().PodPollInterval}, nil
 	}
 	r.printImagePullBackOffReason(ctx, newLogger, pod)
+	r.events.eventf(job, corev1.EventTypeWarning, eventReasonImagePullBackOffTimeout, "stop job because pod %s is ImagePullBackOff for more than %s",
+		pod.Name, r.options().PodImagePullBackoffTimeout)
 	if err := r.stopJob(ctx, newLogger, job); err != nil {
 		return ctrl.Result{}, err
 	}
+	metrics.ImagePullBackoffStopsTotal.Inc()
 	return ctrl.Result{}, nil
 }
 
@@ -279,7 +397,7 @@ func (r *Runner) podImagePullBackoffTimeout(pod *corev1.Pod) bool {
 		return false
 	}
 	delta := time.Since(pod.Status.StartTime.Time)
-	if delta <= r.opts.PodImagePullBackoffTimeout || !podImagePullBackoff(pod) {
+	if delta <= r.options().PodImagePullBackoffTimeout || !podImagePullBackoff(pod) {
 		return false
 	}
 	return true
@@ -287,7 +405,7 @@ func (r *Runner) podImagePullBackoffTimeout(pod *corev1.Pod) bool {
 
 func podImagePullBackoff(pod *corev1.Pod) bool {
 	for _, containerStatus := range pod.Status.ContainerStatuses {
-		if containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason == "ImagePullBackOff" {
+		if containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason == waitingReasonImagePullBackOff {
 			return true
 		}
 	}
@@ -295,9 +413,7 @@ func podImagePullBackoff(pod *corev1.Pod) bool {
 }
 
 func (r *Runner) printImagePullBackOffReason(ctx context.Context, logger filelog.Logger, pod *corev1.Pod) {
-	events, err := r.kubeClientNative.CoreV1().Events(r.namespace).List(ctx, metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("involvedObject.name=%s,involvedObject.namespace=%s,involvedObject.uid=%s", pod.Name, pod.Namespace, string(pod.UID)),
-	})
+	events, err := r.listPodEvents(ctx, pod)
 	if err != nil {
 		logger.Errorf("ImagePullBackOff: failed to list events of pod: %s", err.Error())
 		return
@@ -310,3 +426,92 @@ func (r *Runner) printImagePullBackOffReason(ctx context.Context, logger filelog
 	}
 	logger.Errorf("ImagePullBackOff: no related events")
 }
+
+func (r *Runner) listPodEvents(ctx context.Context, pod *corev1.Pod) (*corev1.EventList, error) {
+	return r.kubeClientNative.CoreV1().Events(r.namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("involvedObject.name=%s,involvedObject.namespace=%s,involvedObject.uid=%s", pod.Name, pod.Namespace, string(pod.UID)),
+	})
+}
+
+// events of scheduler and cluster-autoscaler about unschedulable pods
+const (
+	eventReasonFailedScheduling  = "FailedScheduling"
+	eventReasonNotTriggerScaleUp = "NotTriggerScaleUp"
+	eventReasonTriggeredScaleUp  = "TriggeredScaleUp"
+)
+
+// processUnschedulable fails the task with SYSTEM_ERROR if the pod is unschedulable for too long,
+// e.g. it requests more resources than any node has.
+func (r *Runner) processUnschedulable(ctx context.Context, newLogger filelog.Logger, taskID string, pod *corev1.Pod) (ctrl.Result, error) {
+	if pod.Status.Phase != corev1.PodPending || !pod.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	condition := podUnschedulableCondition(pod)
+	if condition == nil {
+		return ctrl.Result{}, nil
+	}
+	jobName, ok := pod.Labels[consts.LabelJobName]
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+	job := &batchv1.Job{}
+	if err := r.kubeClient.Get(ctx, ctrlclient.ObjectKey{Namespace: r.namespace, Name: jobName}, job); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get job: %w", err)
+	}
+	if jobFinished(job) {
+		return ctrl.Result{}, nil
+	}
+
+	opts := r.options()
+	unschedulableTime := time.Since(condition.LastTransitionTime.Time)
+	if unschedulableTime <= opts.PodUnschedulableTimeout {
+		return ctrl.Result{RequeueAfter: opts.PodPollInterval}, nil
+	}
+	events, err := r.listPodEvents(ctx, pod)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list events of pod %s: %w", pod.Name, err)
+	}
+	timeout := opts.PodUnschedulableTimeout
+	for _, event := range events.Items {
+		if event.Reason == eventReasonTriggeredScaleUp {
+			timeout = opts.PodScaleUpUnschedulableTimeout
+			break
+		}
+	}
+	if unschedulableTime <= timeout {
+		return ctrl.Result{RequeueAfter: opts.PodPollInterval}, nil
+	}
+
+	newLogger.Errorf("Unschedulable: pod %s is unschedulable for more than %s: %s", pod.Name, timeout, condition.Message)
+	for _, event := range events.Items {
+		switch event.Reason {
+		case eventReasonFailedScheduling, eventReasonNotTriggerScaleUp, eventReasonTriggeredScaleUp:
+			newLogger.Errorf("Unschedulable: %s: %s", event.Reason, event.Message)
+		}
+	}
+	r.events.eventf(job, corev1.EventTypeWarning, eventReasonUnschedulableTimeout, "stop task because pod %s is unschedulable for more than %s",
+		pod.Name, timeout)
+	// mark the task stopped before the job fails, otherwise the failed executor would be taken as EXECUTOR_ERROR
+	if err = r.localStoreHelper.StopTask(ctx, taskID, consts.TaskSystemError); err != nil && !errors.Is(err, localstore.ErrNotFound) {
+		return ctrl.Result{}, err
+	}
+	if err = r.stopJob(ctx, newLogger, job); err != nil {
+		return ctrl.Result{}, err
+	}
+	metrics.UnschedulableStopsTotal.Inc()
+	return ctrl.Result{}, nil
+}
+
+func podUnschedulableCondition(pod *corev1.Pod) *corev1.PodCondition {
+	for index := range pod.Status.Conditions {
+		condition := &pod.Status.Conditions[index]
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == corev1.PodReasonUnschedulable {
+			return condition
+		}
+	}
+	return nil
+}
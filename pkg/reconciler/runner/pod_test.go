@@ -3,6 +3,9 @@ package runner
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,6 +21,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/filelog"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
 	vetesclientfake "github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/fake"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
@@ -306,6 +311,144 @@ func TestProcessPodProcessExecutorTimeTerminated(t *testing.T) {
 	g.Expect(controllerutil.ContainsFinalizer(gotPod, consts.ProcessExecutorTimeFinalizer)).To(gomega.BeFalse())
 }
 
+func TestGetExecutorTimeDisrupted(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	startTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	disruptionTime := time.Now().Add(-time.Minute * 30).Truncate(time.Second)
+
+	// pod on lost node is failed by pod gc, and its container is still running in status
+	executorPod := fakeExecutorPod.DeepCopy()
+	executorPod.Status = corev1.PodStatus{
+		Phase: corev1.PodFailed,
+		Conditions: []corev1.PodCondition{{
+			Type:               corev1.DisruptionTarget,
+			Status:             corev1.ConditionTrue,
+			Reason:             "DeletionByPodGC",
+			LastTransitionTime: metav1.NewTime(disruptionTime),
+		}},
+		ContainerStatuses: []corev1.ContainerStatus{{
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{
+				StartedAt: metav1.NewTime(startTime),
+			}},
+		}},
+	}
+
+	gotStartTime, gotEndTime := getExecutorTime(executorPod)
+	g.Expect(gotStartTime).To(gomega.Equal(utils.Point(startTime.Format(time.RFC3339))))
+	g.Expect(gotEndTime).To(gomega.Equal(utils.Point(disruptionTime.Format(time.RFC3339))))
+}
+
+func TestProcessExecutorTimeDisrupted(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	startTime := utils.Point(time.Now().Add(-time.Hour).Truncate(time.Second).Format(time.RFC3339))
+	disruptionTime := time.Now().Add(-time.Minute * 30).Truncate(time.Second)
+	endTime := utils.Point(disruptionTime.Format(time.RFC3339))
+
+	executorPod := fakeExecutorPod.DeepCopy()
+	controllerutil.AddFinalizer(executorPod, consts.ProcessExecutorTimeFinalizer)
+	executorPod.Status = corev1.PodStatus{
+		Phase: corev1.PodFailed,
+		Conditions: []corev1.PodCondition{{
+			Type:               corev1.DisruptionTarget,
+			Status:             corev1.ConditionTrue,
+			Reason:             "PreemptionByScheduler",
+			Message:            "Preempted in order to admit critical pod",
+			LastTransitionTime: metav1.NewTime(disruptionTime),
+		}},
+		ContainerStatuses: []corev1.ContainerStatus{{
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{
+				StartedAt: metav1.NewTime(disruptionTime.Add(-time.Minute * 30)),
+			}},
+		}},
+	}
+
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(executorPod).Build()
+	fakeVeTESClient := vetesclientfake.NewFakeClient(mockctrl)
+	r := &Runner{
+		vetesClient: fakeVeTESClient,
+		kubeClient:  fakeKubeClient,
+		clusterID:   fakeClusterID,
+		namespace:   fakeNamespace,
+		opts:        &Options{},
+	}
+	newLogger := filelog.NewLoggerWithWriteToFile(filepath.Join(t.TempDir(), taskLogFileName))
+
+	// disruption is shipped with the end time of the executor attempt
+	fakeVeTESClient.EXPECT().GetTask(gomock.Any(), &models.GetTaskRequest{ID: fakeTaskID, View: consts.BasicView}).
+		Return(&models.GetTaskResponse{Task: &models.Task{
+			ID:        fakeTaskID,
+			ClusterID: fakeClusterID,
+			Logs: []*models.TaskLog{{
+				ClusterID: fakeClusterID,
+				StartTime: startTime,
+				Logs:      [][]*models.ExecutorLog{{{ExecutorID: fakeExecutorPodName, StartTime: startTime}}},
+			}},
+		}}, nil)
+	fakeVeTESClient.EXPECT().UpdateTask(gomock.Any(), &models.UpdateTaskRequest{
+		ID: fakeTaskID,
+		Logs: []*models.TaskLog{{
+			ClusterID:  fakeClusterID,
+			Logs:       [][]*models.ExecutorLog{{{ExecutorID: fakeExecutorPodName, EndTime: endTime}}},
+			SystemLogs: []string{fmt.Sprintf("pod %s is disrupted, reason[PreemptionByScheduler] and message: Preempted in order to admit critical pod", fakeExecutorPodName)},
+		}},
+	}).Return(&models.UpdateTaskResponse{}, nil)
+	resp, err := r.processExecutorTime(context.Background(), newLogger, fakeTaskID, executorPod)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(resp).To(gomega.Equal(ctrl.Result{}))
+
+	// reconciled again after the end time is recorded, disruption is not shipped twice
+	fakeVeTESClient.EXPECT().GetTask(gomock.Any(), &models.GetTaskRequest{ID: fakeTaskID, View: consts.BasicView}).
+		Return(&models.GetTaskResponse{Task: &models.Task{
+			ID:        fakeTaskID,
+			ClusterID: fakeClusterID,
+			Logs: []*models.TaskLog{{
+				ClusterID: fakeClusterID,
+				StartTime: startTime,
+				Logs:      [][]*models.ExecutorLog{{{ExecutorID: fakeExecutorPodName, StartTime: startTime, EndTime: endTime}}},
+			}},
+		}}, nil)
+	fakeVeTESClient.EXPECT().UpdateTask(gomock.Any(), &models.UpdateTaskRequest{
+		ID: fakeTaskID,
+		Logs: []*models.TaskLog{{
+			ClusterID: fakeClusterID,
+			Logs:      [][]*models.ExecutorLog{{{ExecutorID: fakeExecutorPodName}}},
+		}},
+	}).Return(&models.UpdateTaskResponse{}, nil)
+	resp, err = r.processExecutorTime(context.Background(), newLogger, fakeTaskID, executorPod)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(resp).To(gomega.Equal(ctrl.Result{}))
+}
+
+func TestRecordPodDisruptionOnce(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	filerPod := fakeInputsFilerPod.DeepCopy()
+	filerPod.Status = corev1.PodStatus{
+		Phase: corev1.PodFailed,
+		Conditions: []corev1.PodCondition{{
+			Type:   corev1.DisruptionTarget,
+			Status: corev1.ConditionTrue,
+			Reason: "EvictionByEvictionAPI",
+		}},
+	}
+
+	outputDir := t.TempDir()
+	r := &Runner{opts: &Options{TaskLog: TaskLogOptions{OutputDir: outputDir}}}
+	r.recordPodDisruption(r.taskLogger(fakeTaskID), fakeTaskID, filerPod)
+	r.recordPodDisruption(r.taskLogger(fakeTaskID), fakeTaskID, filerPod)
+
+	content, err := os.ReadFile(filepath.Join(outputDir, fakeTaskID, taskLogFileName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(strings.Count(string(content), "reason[EvictionByEvictionAPI]")).To(gomega.Equal(1))
+
+	r.forgetPodDisruptions(fakeTaskID)
+	g.Expect(r.podDisruptions).To(gomega.BeEmpty())
+}
+
 func TestProcessPodImagePullBackoffTimeout(t *testing.T) {
 	g := gomega.NewWithT(t)
 	mockctrl := gomock.NewController(t)
@@ -351,6 +494,7 @@ func TestProcessPodImagePullBackoffTimeout(t *testing.T) {
 		taskProcessing:   map[string]struct{}{},
 		opts: &Options{
 			PodImagePullBackoffTimeout: time.Minute * 10,
+			TaskLog:                    TaskLogOptions{OutputDir: t.TempDir()},
 		},
 	}
 
@@ -398,13 +542,14 @@ func TestProcessPodImagePullBackoffNotTimeout(t *testing.T) {
 		Type:    corev1.EventTypeWarning,
 	}
 
-	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(inputsFilerPod, fakeInputsFilerJob, fakeEvent).Build()
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(inputsFilerPod, fakeInputsFilerJob).Build()
 
 	r := &Runner{
-		kubeClient:     fakeKubeClient,
-		clusterID:      fakeClusterID,
-		namespace:      fakeNamespace,
-		taskProcessing: map[string]struct{}{},
+		kubeClient:       fakeKubeClient,
+		kubeClientNative: kubernetesfake.NewSimpleClientset(fakeEvent),
+		clusterID:        fakeClusterID,
+		namespace:        fakeNamespace,
+		taskProcessing:   map[string]struct{}{},
 		opts: &Options{
 			PodPollInterval:            time.Minute,
 			PodImagePullBackoffTimeout: time.Minute * 10,
@@ -420,3 +565,248 @@ func TestProcessPodImagePullBackoffNotTimeout(t *testing.T) {
 	g.Expect(err).NotTo(gomega.HaveOccurred())
 	g.Expect(gotJob.Spec.ActiveDeadlineSeconds).To(gomega.BeNil())
 }
+
+func TestProcessPodInvalidImageName(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	gotJob := processPendingInputsFilerPod(t, time.Minute, corev1.ContainerStateWaiting{
+		Reason:  "InvalidImageName",
+		Message: `Failed to apply default image tag "xxx:": couldn't parse image reference "xxx:": invalid reference format`,
+	}, nil)
+	g.Expect(*gotJob.Spec.ActiveDeadlineSeconds).To(gomega.Equal(int64(0)))
+}
+
+func TestProcessPodCreateContainerConfigError(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	waiting := corev1.ContainerStateWaiting{
+		Reason:  "CreateContainerConfigError",
+		Message: `secret "xxx" not found`,
+	}
+	// the secret may be created later
+	gotJob := processPendingInputsFilerPod(t, time.Minute, waiting, nil)
+	g.Expect(gotJob.Spec.ActiveDeadlineSeconds).To(gomega.BeNil())
+
+	gotJob = processPendingInputsFilerPod(t, time.Minute*10, waiting, nil)
+	g.Expect(*gotJob.Spec.ActiveDeadlineSeconds).To(gomega.Equal(int64(0)))
+}
+
+func TestProcessPodImagePullManifestUnknown(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	gotJob := processPendingInputsFilerPod(t, time.Minute, corev1.ContainerStateWaiting{
+		Reason:  "ImagePullBackOff",
+		Message: "Back-off pulling image xxx",
+	}, &corev1.Event{Reason: "Failed", Message: "Failed to pull image xxx: rpc error: code = NotFound desc = manifest unknown"})
+	g.Expect(*gotJob.Spec.ActiveDeadlineSeconds).To(gomega.Equal(int64(0)))
+}
+
+func TestProcessPodImagePullAccessDenied(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	// pull secret may be added, which is left to the pull backoff timeout
+	gotJob := processPendingInputsFilerPod(t, time.Minute, corev1.ContainerStateWaiting{
+		Reason:  "ImagePullBackOff",
+		Message: "Back-off pulling image xxx",
+	}, &corev1.Event{Reason: "Failed", Message: "Failed to pull image xxx: Error response from daemon: pull access denied for xxx, " +
+		"repository does not exist or may require 'docker login': denied: requested access to the resource is denied"})
+	g.Expect(gotJob.Spec.ActiveDeadlineSeconds).To(gomega.BeNil())
+}
+
+func TestProcessPodImagePullTransientError(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	gotJob := processPendingInputsFilerPod(t, time.Minute, corev1.ContainerStateWaiting{
+		Reason:  "ImagePullBackOff",
+		Message: "Back-off pulling image xxx",
+	}, &corev1.Event{Reason: "Failed", Message: "Failed to pull image xxx: dial tcp: i/o timeout"})
+	g.Expect(gotJob.Spec.ActiveDeadlineSeconds).To(gomega.BeNil())
+}
+
+// processPendingInputsFilerPod processes an inputs filer pod started for a while with the container waiting, and returns its job
+func processPendingInputsFilerPod(t *testing.T, started time.Duration, waiting corev1.ContainerStateWaiting, event *corev1.Event) *batchv1.Job {
+	g := gomega.NewWithT(t)
+
+	inputsFilerPod := fakeInputsFilerPod.DeepCopy()
+	inputsFilerPod.Status = corev1.PodStatus{
+		Phase:     corev1.PodPending,
+		StartTime: utils.Point(metav1.NewTime(time.Now().Add(-started))),
+		ContainerStatuses: []corev1.ContainerStatus{{
+			State: corev1.ContainerState{Waiting: &waiting},
+		}},
+	}
+	fakeKubeClientNative := kubernetesfake.NewSimpleClientset()
+	if event != nil {
+		event.Namespace = fakeNamespace
+		event.Name = "inputs-filer-pod-event"
+		event.InvolvedObject = corev1.ObjectReference{
+			Kind:       "Pod",
+			Namespace:  fakeNamespace,
+			Name:       fakeInputsFilerPodName,
+			UID:        "inputs-filer-pod-uid",
+			APIVersion: "v1",
+		}
+		fakeKubeClientNative = kubernetesfake.NewSimpleClientset(event)
+	}
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(inputsFilerPod, fakeInputsFilerJob).Build()
+
+	r := &Runner{
+		kubeClient:       fakeKubeClient,
+		kubeClientNative: fakeKubeClientNative,
+		clusterID:        fakeClusterID,
+		namespace:        fakeNamespace,
+		taskProcessing:   map[string]struct{}{},
+		opts: &Options{
+			PodPollInterval:                time.Minute,
+			PodImagePullBackoffTimeout:     time.Minute * 10,
+			PodCreateContainerErrorTimeout: time.Minute * 5,
+			TaskLog:                        TaskLogOptions{OutputDir: t.TempDir()},
+		},
+	}
+
+	_, err := r.ProcessPod(context.Background(), fakeInputsFilerPodName)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	gotJob := &batchv1.Job{}
+	err = fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(fakeInputsFilerJob), gotJob)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	return gotJob
+}
+
+func TestProcessPodUnschedulableTimeout(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	now := time.Now()
+
+	executorPod := fakeExecutorPod.DeepCopy()
+	executorPod.Status = corev1.PodStatus{
+		Phase: corev1.PodPending,
+		Conditions: []corev1.PodCondition{{
+			Type:               corev1.PodScheduled,
+			Status:             corev1.ConditionFalse,
+			Reason:             corev1.PodReasonUnschedulable,
+			Message:            "0/3 nodes are available: 3 Insufficient cpu.",
+			LastTransitionTime: metav1.NewTime(now.Add(-time.Minute * 20)),
+		}},
+	}
+	executorJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: fakeNamespace,
+			Name:      fakeExecutorJobName,
+		},
+	}
+	fakeEvent := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: fakeNamespace,
+			Name:      "executor-pod-event",
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:       "Pod",
+			Namespace:  fakeNamespace,
+			Name:       fakeExecutorPodName,
+			UID:        "executor-pod-uid",
+			APIVersion: "v1",
+		},
+		Reason:  eventReasonFailedScheduling,
+		Message: "0/3 nodes are available: 3 Insufficient cpu.",
+		Type:    corev1.EventTypeWarning,
+	}
+
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(executorPod, executorJob).Build()
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(mockctrl)
+	outputDir := t.TempDir()
+	r := &Runner{
+		kubeClient:       fakeKubeClient,
+		kubeClientNative: kubernetesfake.NewSimpleClientset(fakeEvent),
+		localStoreHelper: fakeLocalStoreHelper,
+		clusterID:        fakeClusterID,
+		namespace:        fakeNamespace,
+		taskProcessing:   map[string]struct{}{},
+		opts: &Options{
+			PodPollInterval:                time.Minute,
+			PodUnschedulableTimeout:        time.Minute * 15,
+			PodScaleUpUnschedulableTimeout: time.Minute * 45,
+			TaskLog:                        TaskLogOptions{OutputDir: outputDir},
+		},
+	}
+
+	fakeLocalStoreHelper.EXPECT().StopTask(gomock.Any(), fakeTaskID, consts.TaskSystemError).Return(nil)
+	resp, err := r.ProcessPod(context.Background(), fakeExecutorPodName)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	// requeued by processImagePullBackoff for pending pods
+	g.Expect(resp).To(gomega.Equal(ctrl.Result{RequeueAfter: time.Minute}))
+
+	gotJob := &batchv1.Job{}
+	err = fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(executorJob), gotJob)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(*gotJob.Spec.ActiveDeadlineSeconds).To(gomega.Equal(int64(0)))
+
+	content, err := os.ReadFile(filepath.Join(outputDir, fakeTaskID, taskLogFileName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(content)).To(gomega.ContainSubstring("FailedScheduling: 0/3 nodes are available: 3 Insufficient cpu."))
+}
+
+func TestProcessPodUnschedulableScaleUpNotTimeout(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	now := time.Now()
+
+	executorPod := fakeExecutorPod.DeepCopy()
+	executorPod.Status = corev1.PodStatus{
+		Phase: corev1.PodPending,
+		Conditions: []corev1.PodCondition{{
+			Type:               corev1.PodScheduled,
+			Status:             corev1.ConditionFalse,
+			Reason:             corev1.PodReasonUnschedulable,
+			LastTransitionTime: metav1.NewTime(now.Add(-time.Minute * 20)),
+		}},
+	}
+	executorJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: fakeNamespace,
+			Name:      fakeExecutorJobName,
+		},
+	}
+	fakeEvent := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: fakeNamespace,
+			Name:      "executor-pod-event",
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:       "Pod",
+			Namespace:  fakeNamespace,
+			Name:       fakeExecutorPodName,
+			UID:        "executor-pod-uid",
+			APIVersion: "v1",
+		},
+		Reason:  eventReasonTriggeredScaleUp,
+		Message: "pod triggered scale-up",
+		Type:    corev1.EventTypeNormal,
+	}
+
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(executorPod, executorJob).Build()
+	r := &Runner{
+		kubeClient:       fakeKubeClient,
+		kubeClientNative: kubernetesfake.NewSimpleClientset(fakeEvent),
+		clusterID:        fakeClusterID,
+		namespace:        fakeNamespace,
+		taskProcessing:   map[string]struct{}{},
+		opts: &Options{
+			PodPollInterval:                time.Minute,
+			PodUnschedulableTimeout:        time.Minute * 15,
+			PodScaleUpUnschedulableTimeout: time.Minute * 45,
+			TaskLog:                        TaskLogOptions{OutputDir: t.TempDir()},
+		},
+	}
+
+	resp, err := r.ProcessPod(context.Background(), fakeExecutorPodName)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(resp).To(gomega.Equal(ctrl.Result{RequeueAfter: time.Minute}))
+
+	gotJob := &batchv1.Job{}
+	err = fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(executorJob), gotJob)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(gotJob.Spec.ActiveDeadlineSeconds).To(gomega.BeNil())
+}
@@ -3,24 +3,50 @@ package runner
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/filelog"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/tracing"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
 )
 
 func pvcName(taskID string) string {
 	return fmt.Sprintf("%s-pvc", taskID)
 }
 
-func (r *Runner) createPVC(ctx context.Context, logger filelog.Logger, taskID string, diskGB float64) error {
-	pvc := &corev1.PersistentVolumeClaim{
+func (r *Runner) createPVC(ctx context.Context, logger filelog.Logger, taskID string, diskGB float64, storageClass string) (reterr error) {
+	ctx, span := tracing.Start(ctx, "create pvc", trace.WithAttributes(attribute.String("pvc", pvcName(taskID))))
+	defer func() {
+		tracing.EndSpan(span, reterr)
+	}()
+
+	pvc := r.newPVC(taskID, diskGB, storageClass)
+	if err := r.kubeClient.Create(ctx, pvc); err != nil {
+		if k8sapierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to create pvc: %w", err)
+	}
+	logger.Infof("created pvc %s with storageClass %s", pvc.Name, storageClass)
+	return nil
+}
+
+func (r *Runner) newPVC(taskID string, diskGB float64, storageClass string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: r.namespace,
 			Name:      pvcName(taskID),
@@ -36,17 +62,168 @@ func (r *Runner) createPVC(ctx context.Context, logger filelog.Logger, taskID st
 					corev1.ResourceStorage: resource.MustParse(fmt.Sprintf("%fGi", diskGB)),
 				},
 			},
-			StorageClassName: utils.Point(r.opts.StorageClass),
+			StorageClassName: utils.Point(storageClass),
 		},
 	}
-	if err := r.kubeClient.Create(ctx, pvc); err != nil {
-		if k8sapierrors.IsAlreadyExists(err) {
-			return nil
+}
+
+// taskStorageClass returns the storage class of task pvc, which is the fallback one once pvc is recreated
+func (r *Runner) taskStorageClass(taskInfo *localstore.TaskInfo) string {
+	if taskInfo.StorageClass != nil {
+		return *taskInfo.StorageClass
+	}
+	return r.options().StorageClass
+}
+
+// annSelectedNode is set on pvc by scheduler when the consumer pod of WaitForFirstConsumer pvc is scheduled
+const annSelectedNode = "volume.kubernetes.io/selected-node"
+
+// watchPVCBinding checks whether task pvc is bound in PVCBindTimeout. Otherwise it recreates pvc with FallbackStorageClass,
+// or stops task with SYSTEM_ERROR. If done, task stages should not go on and the result should be returned.
+func (r *Runner) watchPVCBinding(ctx context.Context, logger filelog.Logger, task *models.Task, taskInfo *localstore.TaskInfo) (_ ctrl.Result, done bool, _ error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.kubeClient.Get(ctx, ctrlclient.ObjectKey{Namespace: r.namespace, Name: pvcName(task.ID)}, pvc); err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			return ctrl.Result{}, false, fmt.Errorf("failed to get pvc: %w", err)
 		}
-		return fmt.Errorf("failed to create pvc: %w", err)
+		if taskInfo.StorageClass == nil {
+			return ctrl.Result{}, false, nil
+		}
+		// pvc of previous storage class is deleted
+		result, err := r.recreatePVC(ctx, logger, taskInfo, nil)
+		return result, true, err
 	}
-	logger.Infof("created pvc %s", pvc.Name)
-	return nil
+	// bound pvc is in use, even if it is not of the recorded storage class
+	if pvc.Status.Phase != corev1.ClaimPending {
+		return ctrl.Result{}, false, nil
+	}
+	// only the recorded fallback storage class is compared, changing StorageClass in config does not affect created pvc
+	storageClass := utils.Value(pvc.Spec.StorageClassName)
+	if taskInfo.StorageClass != nil && storageClass != *taskInfo.StorageClass {
+		result, err := r.recreatePVC(ctx, logger, taskInfo, pvc)
+		return result, true, err
+	}
+
+	opts := r.options()
+	requeue := ctrl.Result{RequeueAfter: opts.PodPollInterval}
+	if time.Since(pvc.CreationTimestamp.Time) <= opts.PVCBindTimeout {
+		return requeue, false, nil
+	}
+	events, err := r.kubeClientNative.CoreV1().Events(r.namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("involvedObject.name=%s,involvedObject.namespace=%s,involvedObject.uid=%s", pvc.Name, pvc.Namespace, string(pvc.UID)),
+	})
+	if err != nil {
+		return ctrl.Result{}, false, fmt.Errorf("failed to list events of pvc %s: %w", pvc.Name, err)
+	}
+	provisionStart, err := r.pvcProvisionStart(ctx, pvc, events.Items)
+	if err != nil {
+		return ctrl.Result{}, false, err
+	}
+	if provisionStart == nil || time.Since(*provisionStart) <= opts.PVCBindTimeout {
+		return requeue, false, nil
+	}
+
+	logger.Errorf("PVCNotBound: pvc %s with storageClass %s is not bound for more than %s", pvc.Name, storageClass, opts.PVCBindTimeout)
+	for _, event := range events.Items {
+		logger.Errorf("PVCNotBound: %s: %s", event.Reason, event.Message)
+	}
+	metrics.PVCBindTimeoutsTotal.WithLabelValues(storageClass).Inc()
+	if opts.FallbackStorageClass != "" && storageClass != opts.FallbackStorageClass {
+		logger.Warnf("recreate pvc %s with fallback storageClass %s", pvc.Name, opts.FallbackStorageClass)
+		r.events.eventf(pvc, corev1.EventTypeWarning, eventReasonPVCBindTimeout, "recreate pvc with storageClass %s because it is not bound for more than %s",
+			opts.FallbackStorageClass, opts.PVCBindTimeout)
+		if err = r.localStoreHelper.RecordTaskStorageClass(ctx, task.ID, opts.FallbackStorageClass); err != nil {
+			return ctrl.Result{}, false, err
+		}
+		taskInfo.StorageClass = utils.Point(opts.FallbackStorageClass)
+		result, err := r.recreatePVC(ctx, logger, taskInfo, pvc)
+		return result, true, err
+	}
+	r.events.eventf(pvc, corev1.EventTypeWarning, eventReasonPVCBindTimeout, "stop task because pvc is not bound for more than %s", opts.PVCBindTimeout)
+	result, err := r.stopAndCleanTask(ctx, logger, task, consts.TaskSystemError)
+	return result, true, err
+}
+
+// pvcProvisionStart returns when provisioning of pvc starts, which is nil if the pvc of WaitForFirstConsumer storage class
+// is waiting for its consumer scheduled. Unschedulable consumer is handled by processUnschedulable.
+func (r *Runner) pvcProvisionStart(ctx context.Context, pvc *corev1.PersistentVolumeClaim, events []corev1.Event) (*time.Time, error) {
+	storageClass, err := r.kubeClientNative.StorageV1().StorageClasses().Get(ctx, utils.Value(pvc.Spec.StorageClassName), metav1.GetOptions{})
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			// pv will never be provisioned
+			return &pvc.CreationTimestamp.Time, nil
+		}
+		return nil, fmt.Errorf("failed to get storageClass: %w", err)
+	}
+	if utils.Value(storageClass.VolumeBindingMode) != storagev1.VolumeBindingWaitForFirstConsumer {
+		return &pvc.CreationTimestamp.Time, nil
+	}
+	if pvc.Annotations[annSelectedNode] == "" {
+		return nil, nil
+	}
+	// provisioning starts after consumer scheduled, events before are WaitForFirstConsumer
+	var res *time.Time
+	for _, event := range events {
+		if event.Reason == "WaitForFirstConsumer" {
+			continue
+		}
+		eventTime := event.FirstTimestamp.Time
+		if eventTime.IsZero() {
+			eventTime = event.CreationTimestamp.Time
+		}
+		if res == nil || eventTime.Before(*res) {
+			res = &eventTime
+		}
+	}
+	return res, nil
+}
+
+// recreatePVC deletes jobs and pvc of the task, and restarts it from creating pvc of the recorded storage class
+// after the pvc is deleted.
+func (r *Runner) recreatePVC(ctx context.Context, logger filelog.Logger, taskInfo *localstore.TaskInfo, pvc *corev1.PersistentVolumeClaim) (ctrl.Result, error) {
+	if shouldCreateInputsFiler(&taskInfo.Task) {
+		if err := r.deleteJob(ctx, logger, inputsFilerJobName(taskInfo.ID)); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if taskInfo.ExecutorStage != nil {
+		// jobs of executors up to the recorded one are deleted, same as deleteTaskObjects
+		for index := 0; index <= parseExecutorStageValue(*taskInfo.ExecutorStage).index; index++ {
+			if err := r.deleteJob(ctx, logger, executorJobName(taskInfo.ID, index)); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	if pvc != nil {
+		// pvc is protected from deletion until pods using it are deleted
+		if err := r.deletePVC(ctx, logger, pvc.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: waitPodDeleted}, nil
+	}
+	if taskInfo.ExecutorStage != nil {
+		if err := r.recordTaskExecutorStage(ctx, taskInfo.ID, newExecutorStageValue(0, executorStatusToCreate)); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, r.recordTaskStage(ctx, taskInfo.ID, taskStagePVCToCreate)
+}
+
+// observePVCStage observes the duration from pvc creation to pv provisioned, skipped if pvc is not bound
+func (r *Runner) observePVCStage(ctx context.Context, pvcName string) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.kubeClient.Get(ctx, ctrlclient.ObjectKey{Namespace: r.namespace, Name: pvcName}, pvc); err != nil {
+		return
+	}
+	if pvc.Spec.VolumeName == "" {
+		return
+	}
+	pv := &corev1.PersistentVolume{}
+	if err := r.kubeClient.Get(ctx, ctrlclient.ObjectKey{Name: pvc.Spec.VolumeName}, pv); err != nil {
+		return
+	}
+	metrics.StageDurationSeconds.WithLabelValues(metrics.StagePVC, metrics.StageSucceeded).
+		Observe(pv.CreationTimestamp.Sub(pvc.CreationTimestamp.Time).Seconds())
 }
 
 func (r *Runner) deletePVC(ctx context.Context, logger filelog.Logger, pvcName string) error {
@@ -0,0 +1,241 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+func TestWatchPVCBindingFallback(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         fakeNamespace,
+			Name:              pvcName(fakeTaskID),
+			UID:               "pvc-uid",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Minute * 20)),
+			Labels:            map[string]string{consts.LabelTaskID: fakeTaskID},
+			Finalizers:        []string{consts.ProcessTaskFinalizer},
+		},
+		Spec:   corev1.PersistentVolumeClaimSpec{StorageClassName: utils.Point("ebs-ssd")},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}
+	inputsFilerJob := fakeInputsFilerJob.DeepCopy()
+	storageClass := &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "ebs-ssd"},
+		VolumeBindingMode: utils.Point(storagev1.VolumeBindingImmediate),
+	}
+	fakeEvent := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Namespace: fakeNamespace, Name: "pvc-event"},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:       "PersistentVolumeClaim",
+			Namespace:  fakeNamespace,
+			Name:       pvcName(fakeTaskID),
+			UID:        "pvc-uid",
+			APIVersion: "v1",
+		},
+		Reason:  "ProvisioningFailed",
+		Message: "failed to provision volume with StorageClass \"ebs-ssd\": quota exceeded",
+		Type:    corev1.EventTypeWarning,
+	}
+
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(pvc, inputsFilerJob).Build()
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(mockctrl)
+	outputDir := t.TempDir()
+	r := &Runner{
+		kubeClient:       fakeKubeClient,
+		kubeClientNative: kubernetesfake.NewSimpleClientset(storageClass, fakeEvent),
+		localStoreHelper: fakeLocalStoreHelper,
+		clusterID:        fakeClusterID,
+		namespace:        fakeNamespace,
+		opts: &Options{
+			StorageClass:         "ebs-ssd",
+			FallbackStorageClass: "ebs-essd",
+			PVCBindTimeout:       time.Minute * 10,
+			PodPollInterval:      time.Minute,
+			TaskLog:              TaskLogOptions{OutputDir: outputDir},
+		},
+	}
+	task := &models.Task{ID: fakeTaskID}
+	taskInfo := &localstore.TaskInfo{
+		Task:  localstore.Task{ID: fakeTaskID, InputsJSON: `{"a":"b"}`},
+		Stage: utils.Point(taskStageInputsFilerCreated),
+	}
+	logger := r.taskLogger(fakeTaskID)
+
+	fakeLocalStoreHelper.EXPECT().RecordTaskStorageClass(gomock.Any(), fakeTaskID, "ebs-essd").Return(nil)
+	result, done, err := r.watchPVCBinding(context.Background(), logger, task, taskInfo)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(done).To(gomega.BeTrue())
+	g.Expect(result).To(gomega.Equal(ctrl.Result{RequeueAfter: waitPodDeleted}))
+
+	err = fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(inputsFilerJob), &batchv1.Job{})
+	g.Expect(k8sapierrors.IsNotFound(err)).To(gomega.BeTrue())
+	err = fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(pvc), &corev1.PersistentVolumeClaim{})
+	g.Expect(k8sapierrors.IsNotFound(err)).To(gomega.BeTrue())
+
+	// restart from creating pvc after it is deleted
+	fakeLocalStoreHelper.EXPECT().RecordTaskStage(gomock.Any(), fakeTaskID, taskStagePVCToCreate).Return(nil)
+	result, done, err = r.watchPVCBinding(context.Background(), logger, task, taskInfo)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(done).To(gomega.BeTrue())
+	g.Expect(result).To(gomega.Equal(ctrl.Result{}))
+
+	logger.Sync()
+	content, err := os.ReadFile(filepath.Join(outputDir, fakeTaskID, taskLogFileName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(content)).To(gomega.ContainSubstring("ProvisioningFailed: failed to provision volume"))
+}
+
+func TestWatchPVCBindingWaitForFirstConsumer(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         fakeNamespace,
+			Name:              pvcName(fakeTaskID),
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Minute * 20)),
+		},
+		Spec:   corev1.PersistentVolumeClaimSpec{StorageClassName: utils.Point("ebs-ssd")},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}
+	storageClass := &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "ebs-ssd"},
+		VolumeBindingMode: utils.Point(storagev1.VolumeBindingWaitForFirstConsumer),
+	}
+
+	r := &Runner{
+		kubeClient:       ctrlfake.NewClientBuilder().WithObjects(pvc).Build(),
+		kubeClientNative: kubernetesfake.NewSimpleClientset(storageClass),
+		clusterID:        fakeClusterID,
+		namespace:        fakeNamespace,
+		opts: &Options{
+			StorageClass:    "ebs-ssd",
+			PVCBindTimeout:  time.Minute * 10,
+			PodPollInterval: time.Minute,
+			TaskLog:         TaskLogOptions{OutputDir: t.TempDir()},
+		},
+	}
+	taskInfo := &localstore.TaskInfo{
+		Task:  localstore.Task{ID: fakeTaskID, InputsJSON: `{"a":"b"}`},
+		Stage: utils.Point(taskStageInputsFilerCreated),
+	}
+
+	// consumer is not scheduled
+	result, done, err := r.watchPVCBinding(context.Background(), r.taskLogger(fakeTaskID), &models.Task{ID: fakeTaskID}, taskInfo)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(done).To(gomega.BeFalse())
+	g.Expect(result).To(gomega.Equal(ctrl.Result{RequeueAfter: time.Minute}))
+}
+
+func TestWatchPVCBindingStorageClassChanged(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         fakeNamespace,
+			Name:              pvcName(fakeTaskID),
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Minute)),
+		},
+		Spec:   corev1.PersistentVolumeClaimSpec{StorageClassName: utils.Point("ebs-ssd")},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(pvc).Build()
+	r := &Runner{
+		kubeClient: fakeKubeClient,
+		clusterID:  fakeClusterID,
+		namespace:  fakeNamespace,
+		opts: &Options{
+			StorageClass:    "ebs-essd",
+			PVCBindTimeout:  time.Minute * 10,
+			PodPollInterval: time.Minute,
+			TaskLog:         TaskLogOptions{OutputDir: t.TempDir()},
+		},
+	}
+	taskInfo := &localstore.TaskInfo{
+		Task:  localstore.Task{ID: fakeTaskID, InputsJSON: `{"a":"b"}`},
+		Stage: utils.Point(taskStageInputsFilerCreated),
+	}
+	logger := r.taskLogger(fakeTaskID)
+
+	// storage class in config is changed after pvc is created
+	result, done, err := r.watchPVCBinding(context.Background(), logger, &models.Task{ID: fakeTaskID}, taskInfo)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(done).To(gomega.BeFalse())
+	g.Expect(result).To(gomega.Equal(ctrl.Result{RequeueAfter: time.Minute}))
+
+	// bound pvc is kept even if it is not of the recorded storage class
+	pvc.Status.Phase = corev1.ClaimBound
+	g.Expect(fakeKubeClient.Status().Update(context.Background(), pvc)).To(gomega.Succeed())
+	taskInfo.StorageClass = utils.Point("ebs-essd")
+	result, done, err = r.watchPVCBinding(context.Background(), logger, &models.Task{ID: fakeTaskID}, taskInfo)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(done).To(gomega.BeFalse())
+	g.Expect(result).To(gomega.Equal(ctrl.Result{}))
+	g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(pvc), &corev1.PersistentVolumeClaim{})).To(gomega.Succeed())
+}
+
+func TestRecreatePVCDeletesExecutorJobs(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	jobs := make([]ctrlclient.Object, 0)
+	for index := 0; index < 3; index++ {
+		jobs = append(jobs, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Namespace:  fakeNamespace,
+			Name:       executorJobName(fakeTaskID, index),
+			Finalizers: []string{consts.ProcessTaskFinalizer},
+		}})
+	}
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(jobs...).Build()
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(mockctrl)
+	r := &Runner{
+		kubeClient:       fakeKubeClient,
+		localStoreHelper: fakeLocalStoreHelper,
+		clusterID:        fakeClusterID,
+		namespace:        fakeNamespace,
+		opts:             &Options{TaskLog: TaskLogOptions{OutputDir: t.TempDir()}},
+	}
+	taskInfo := &localstore.TaskInfo{
+		Task:          localstore.Task{ID: fakeTaskID},
+		Stage:         utils.Point(taskStageExecutorsToCreate),
+		ExecutorStage: utils.Point(newExecutorStageValue(1, executorStatusCreated)),
+		StorageClass:  utils.Point("ebs-essd"),
+	}
+
+	fakeLocalStoreHelper.EXPECT().RecordTaskExecutorStage(gomock.Any(), fakeTaskID, newExecutorStageValue(0, executorStatusToCreate)).Return(nil)
+	fakeLocalStoreHelper.EXPECT().RecordTaskStage(gomock.Any(), fakeTaskID, taskStagePVCToCreate).Return(nil)
+	result, err := r.recreatePVC(context.Background(), r.taskLogger(fakeTaskID), taskInfo, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(result).To(gomega.Equal(ctrl.Result{}))
+
+	for index := 0; index < 2; index++ {
+		err = fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(jobs[index]), &batchv1.Job{})
+		g.Expect(k8sapierrors.IsNotFound(err)).To(gomega.BeTrue())
+	}
+	g.Expect(fakeKubeClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(jobs[2]), &batchv1.Job{})).To(gomega.Succeed())
+}
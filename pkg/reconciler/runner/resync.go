@@ -0,0 +1,188 @@
+package runner
+
+import (
+	"context"
+	"errors"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/crontab"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+const resyncQueueSize = 1024
+
+// reasons of divergent tasks found by resync
+const (
+	divergenceStopped    = "stopped"
+	divergenceCanceling  = "canceling"
+	divergenceTerminal   = "terminal"
+	divergenceReassigned = "reassigned"
+	divergenceMissing    = "missing"
+)
+
+var divergenceReasons = []string{divergenceStopped, divergenceCanceling, divergenceTerminal, divergenceReassigned, divergenceMissing}
+
+// activeTaskStates are the states of tasks which may be run by the cluster
+var activeTaskStates = []string{consts.TaskQueued, consts.TaskInitializing, consts.TaskRunning, consts.TaskCanceling}
+
+// ResyncSource is the source of tasks enqueued by resync, which should be watched by the task reconciler
+func (r *Runner) ResyncSource() source.Source {
+	return &source.Channel{Source: r.resyncEvents}
+}
+
+// RegisterResyncCrontab compares all tasks in local store with vetes-api periodically,
+// because changes of tasks in vetes-api do not trigger reconciles.
+func RegisterResyncCrontab(cron *crontab.Crontab, runner *Runner, caps *vetesclient.Capabilities) error {
+	period := runner.options().Resync.Period
+	if period <= 0 {
+		return nil
+	}
+	return cron.RegisterCron(period, func() {
+		ctx := context.Background()
+		if err := runner.resyncTasks(ctx, caps); err != nil {
+			log.Errorw("resync tasks failed", "err", err)
+		}
+	})
+}
+
+// resyncTasks enqueues tasks stopped in local store, or canceling or terminal in vetes-api, which are cleaned by ProcessTask.
+// Tasks assigned to other clusters or deleted in vetes-api are cleaned here, because ProcessTask skips them.
+func (r *Runner) resyncTasks(ctx context.Context, caps *vetesclient.Capabilities) error {
+	taskInfos, err := r.localStoreHelper.ListTasks(ctx)
+	if err != nil {
+		return err
+	}
+	r.forgetUntrackedTaskErrors(taskInfos)
+	remoteTasks, err := r.listRemoteTasks(ctx, caps, taskInfos)
+	if err != nil {
+		return err
+	}
+
+	divergence := make(map[string]int, len(divergenceReasons))
+	for _, taskInfo := range taskInfos {
+		reason := r.taskDivergence(taskInfo, remoteTasks[taskInfo.ID])
+		if reason == "" {
+			continue
+		}
+		divergence[reason]++
+		log.Infow("found divergent task in resync", "task", taskInfo.ID, "reason", reason)
+		switch reason {
+		case divergenceReassigned, divergenceMissing:
+			if err = r.cleanDivergentTask(ctx, taskInfo); err != nil {
+				log.Warnw("failed to clean divergent task", "task", taskInfo.ID, "err", err)
+			}
+		default:
+			r.enqueueTask(taskInfo.ID)
+		}
+	}
+	for _, reason := range divergenceReasons {
+		metrics.ResyncDivergentTasks.WithLabelValues(reason).Set(float64(divergence[reason]))
+	}
+	return nil
+}
+
+// listRemoteTasks returns tasks in vetes-api by id, tasks not found in vetes-api are absent.
+// Active tasks of this cluster are listed in batch if vetes-api can filter by cluster, and the others are got one by one.
+func (r *Runner) listRemoteTasks(ctx context.Context, caps *vetesclient.Capabilities, taskInfos []*localstore.TaskInfo) (map[string]*models.Task, error) {
+	res := make(map[string]*models.Task, len(taskInfos))
+	if len(taskInfos) == 0 {
+		return res, nil
+	}
+	if caps.ClusterFilter {
+		var pageToken string
+		for {
+			resp, err := r.vetesClient.ListTasks(ctx, &models.ListTasksRequest{
+				State:     activeTaskStates,
+				ClusterID: r.clusterID,
+				View:      consts.MinimalView,
+				PageSize:  consts.MaximumPageSize,
+				PageToken: pageToken,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, task := range resp.Tasks {
+				task.ClusterID = r.clusterID // not in minimal view
+				res[task.ID] = task
+			}
+			if resp.NextPageToken == "" {
+				break
+			}
+			pageToken = resp.NextPageToken
+		}
+	}
+	for _, taskInfo := range taskInfos {
+		if _, ok := res[taskInfo.ID]; ok {
+			continue
+		}
+		resp, err := r.vetesClient.GetTask(ctx, &models.GetTaskRequest{ID: taskInfo.ID, View: consts.BasicView})
+		if err != nil {
+			if errors.Is(err, vetesclient.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		res[taskInfo.ID] = resp.Task
+	}
+	return res, nil
+}
+
+// taskDivergence returns why the local task diverges from the task in vetes-api, or empty if not divergent
+func (r *Runner) taskDivergence(taskInfo *localstore.TaskInfo, remoteTask *models.Task) string {
+	switch {
+	case remoteTask == nil:
+		return divergenceMissing
+	case remoteTask.ClusterID != r.clusterID:
+		return divergenceReassigned
+	case taskInfo.Stop != nil:
+		return divergenceStopped
+	case remoteTask.State == consts.TaskCanceling:
+		return divergenceCanceling
+	case isTerminalState(remoteTask.State):
+		return divergenceTerminal
+	default:
+		return ""
+	}
+}
+
+func isTerminalState(state string) bool {
+	switch state {
+	case consts.TaskComplete, consts.TaskExecutorError, consts.TaskSystemError, consts.TaskCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// cleanDivergentTask deletes objects and local store of the task which is not run by this cluster any more
+func (r *Runner) cleanDivergentTask(ctx context.Context, taskInfo *localstore.TaskInfo) error {
+	if !r.tryProcessTask(taskInfo.ID) {
+		return nil // try next time
+	}
+	defer r.releaseProcessTask(taskInfo.ID)
+	logger := r.taskLogger(taskInfo.ID)
+	if err := r.deleteTaskObjects(ctx, logger, taskInfo); err != nil {
+		return err
+	}
+	return r.forgetTask(ctx, taskInfo)
+}
+
+func (r *Runner) enqueueTask(taskID string) {
+	object := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+		Name:   taskID,
+		Labels: map[string]string{consts.LabelTaskID: taskID},
+	}}
+	select {
+	case r.resyncEvents <- event.GenericEvent{Object: object}:
+	default:
+		log.Warnw("resync queue is full, task is enqueued next time", "task", taskID)
+	}
+}
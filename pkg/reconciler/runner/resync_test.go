@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	acceleratefake "github.com/GBA-BI/tes-k8s-agent/pkg/accelerate/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
+	vetesclientfake "github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+func TestResyncTasks(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(mockctrl)
+	fakeVeTESClient := vetesclientfake.NewFakeClient(mockctrl)
+	fakeAccelerator := acceleratefake.NewFakeAccelerator(mockctrl)
+	r := &Runner{
+		vetesClient:      fakeVeTESClient,
+		localStoreHelper: fakeLocalStoreHelper,
+		accelerator:      fakeAccelerator,
+		clusterID:        fakeClusterID,
+		namespace:        fakeNamespace,
+		opts:             &Options{TaskLog: TaskLogOptions{OutputDir: t.TempDir()}},
+		resyncEvents:     make(chan event.GenericEvent, resyncQueueSize),
+		taskProcessing:   make(map[string]struct{}),
+		taskErrors:       make(map[string]taskError),
+	}
+
+	taskInfos := []*localstore.TaskInfo{
+		{Task: localstore.Task{ID: "task-running"}},
+		{Task: localstore.Task{ID: "task-stopped"}, Stop: utils.Point(consts.TaskSystemError)},
+		{Task: localstore.Task{ID: "task-terminal"}},
+		{Task: localstore.Task{ID: "task-reassigned"}},
+		{Task: localstore.Task{ID: "task-missing"}},
+	}
+	remoteTasks := map[string]*models.Task{
+		"task-stopped":    {ID: "task-stopped", State: consts.TaskRunning, ClusterID: fakeClusterID},
+		"task-terminal":   {ID: "task-terminal", State: consts.TaskSystemError, ClusterID: fakeClusterID},
+		"task-reassigned": {ID: "task-reassigned", State: consts.TaskQueued, ClusterID: "cluster-other"},
+	}
+
+	fakeLocalStoreHelper.EXPECT().ListTasks(gomock.Any()).Return(taskInfos, nil)
+	fakeVeTESClient.EXPECT().ListTasks(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, req *models.ListTasksRequest) (*models.ListTasksResponse, error) {
+			g.Expect(req.ClusterID).To(gomega.Equal(fakeClusterID))
+			return &models.ListTasksResponse{Tasks: []*models.Task{{ID: "task-running", State: consts.TaskRunning}}}, nil
+		})
+	fakeVeTESClient.EXPECT().GetTask(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, req *models.GetTaskRequest) (*models.GetTaskResponse, error) {
+			task, ok := remoteTasks[req.ID]
+			if !ok {
+				return nil, fmt.Errorf("task %s: %w", req.ID, vetesclient.ErrNotFound)
+			}
+			return &models.GetTaskResponse{Task: task}, nil
+		}).Times(4)
+	for _, taskID := range []string{"task-reassigned", "task-missing"} {
+		fakeAccelerator.EXPECT().OnFinishTask(gomock.Any(), gomock.Any()).Return(nil)
+		fakeLocalStoreHelper.EXPECT().DeleteTask(gomock.Any(), taskID).Return(nil)
+	}
+
+	// errors of tasks leaving local store are forgotten
+	for _, taskID := range []string{"task-running", "task-reassigned", "task-deleted"} {
+		r.recordTaskError(taskID, errors.New("failed"))
+	}
+
+	err := r.resyncTasks(context.Background(), &vetesclient.Capabilities{ClusterFilter: true})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(r.taskErrors).To(gomega.HaveLen(1))
+	g.Expect(r.taskErrors).To(gomega.HaveKey("task-running"))
+
+	enqueued := make([]string, 0)
+	for len(r.resyncEvents) > 0 {
+		enqueued = append(enqueued, (<-r.resyncEvents).Object.GetLabels()[consts.LabelTaskID])
+	}
+	g.Expect(enqueued).To(gomega.Equal([]string{"task-stopped", "task-terminal"}))
+	g.Expect(testutil.ToFloat64(metrics.ResyncDivergentTasks.WithLabelValues(divergenceStopped))).To(gomega.Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(metrics.ResyncDivergentTasks.WithLabelValues(divergenceCanceling))).To(gomega.Equal(float64(0)))
+	g.Expect(testutil.ToFloat64(metrics.ResyncDivergentTasks.WithLabelValues(divergenceMissing))).To(gomega.Equal(float64(1)))
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,20 +15,27 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/crontab"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/filelog"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/offload"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/usage"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
 )
 
 // Runner ...
 type Runner struct {
-	opts *Options
+	// opts and filerResources may be replaced by UpdateOptions, use options() and filerResourceRequirements()
+	optsLock       sync.RWMutex
+	opts           *Options
+	filerResources corev1.ResourceRequirements
 
 	vetesClient      vetesclient.Client
 	localStoreHelper localstore.Helper
@@ -36,16 +45,25 @@ type Runner struct {
 	kubeClient       ctrlclient.Client
 	clusterID        string
 	namespace        string
-
-	filerResources corev1.ResourceRequirements
+	events           *eventRecorder
+	usage            *usage.Tracker
+	// resyncEvents enqueues divergent tasks found by resync to the task reconciler
+	resyncEvents chan event.GenericEvent
+	// gcOrphans is when objects are found orphaned by uid, only used by the gc crontab
+	gcOrphans map[types.UID]time.Time
 
 	taskProcessingLock sync.Mutex
 	taskProcessing     map[string]struct{}
+	// taskErrors is the last error of processing tasks for introspection, guarded by taskProcessingLock
+	taskErrors map[string]taskError
+	// podDisruptions is uids of disrupted pods recorded to task logs, guarded by taskProcessingLock
+	podDisruptions map[string]map[types.UID]struct{}
 }
 
 // New ...
 func New(vetesClient vetesclient.Client, localStoreHelper localstore.Helper, offloadHelper offload.Helper, accelerator accelerate.Accelerator,
-	kubeClientNative kubernetes.Interface, kubeClient ctrlclient.Client, clusterID, namespace string, opts *Options) (*Runner, error) {
+	kubeClientNative kubernetes.Interface, kubeClient ctrlclient.Client, recorder record.EventRecorder, usageTracker *usage.Tracker,
+	clusterID, namespace string, opts *Options) (*Runner, error) {
 	res := &Runner{
 		opts:             opts,
 		vetesClient:      vetesClient,
@@ -56,52 +74,127 @@ func New(vetesClient vetesclient.Client, localStoreHelper localstore.Helper, off
 		kubeClient:       kubeClient,
 		clusterID:        clusterID,
 		namespace:        namespace,
+		events:           newEventRecorder(recorder, opts.Events),
+		usage:            usageTracker,
+		resyncEvents:     make(chan event.GenericEvent, resyncQueueSize),
+		gcOrphans:        make(map[types.UID]time.Time),
 	}
 
-	res.filerResources.Requests = make(corev1.ResourceList)
-	for resourceName, quantity := range opts.FilerResources.Requests {
-		res.filerResources.Requests[corev1.ResourceName(resourceName)] = resource.MustParse(quantity)
-	}
-	res.filerResources.Limits = make(corev1.ResourceList)
-	for resourceName, quantity := range opts.FilerResources.Limits {
-		res.filerResources.Limits[corev1.ResourceName(resourceName)] = resource.MustParse(quantity)
+	res.filerResources = convertFilerResources(opts.FilerResources)
+	if err := res.checkReferences(context.Background(), opts); err != nil {
+		return nil, err
 	}
-	var err error
+
+	res.taskProcessing = make(map[string]struct{})
+	res.taskErrors = make(map[string]taskError)
+	res.podDisruptions = make(map[string]map[types.UID]struct{})
+
+	return res, nil
+}
+
+// checkReferences checks configmaps and secrets referenced by options exist
+func (r *Runner) checkReferences(ctx context.Context, opts *Options) error {
 	if opts.S3.Enable {
 		if opts.S3.SDKConfigmapName != "" {
-			if _, err = kubeClientNative.CoreV1().ConfigMaps(namespace).Get(context.Background(), opts.S3.SDKConfigmapName, metav1.GetOptions{}); err != nil {
-				return nil, fmt.Errorf("failed to get s3 sdk configmap %s: %w", opts.S3.SDKConfigmapName, err)
+			if _, err := r.kubeClientNative.CoreV1().ConfigMaps(r.namespace).Get(ctx, opts.S3.SDKConfigmapName, metav1.GetOptions{}); err != nil {
+				return fmt.Errorf("failed to get s3 sdk configmap %s: %w", opts.S3.SDKConfigmapName, err)
 			}
 		}
 		if opts.S3.StaticSecretName != "" {
-			if _, err = kubeClientNative.CoreV1().Secrets(namespace).Get(context.Background(), opts.S3.StaticSecretName, metav1.GetOptions{}); err != nil {
-				return nil, fmt.Errorf("failed to get s3 static secret %s: %w", opts.S3.StaticSecretName, err)
+			if _, err := r.kubeClientNative.CoreV1().Secrets(r.namespace).Get(ctx, opts.S3.StaticSecretName, metav1.GetOptions{}); err != nil {
+				return fmt.Errorf("failed to get s3 static secret %s: %w", opts.S3.StaticSecretName, err)
 			}
 		}
 	}
 
 	if opts.FilerImage.ImagePullSecretName != "" {
-		if _, err = kubeClientNative.CoreV1().Secrets(namespace).Get(context.Background(), opts.FilerImage.ImagePullSecretName, metav1.GetOptions{}); err != nil {
-			return nil, fmt.Errorf("failed to get filer image pull secret %s: %w", opts.FilerImage.ImagePullSecretName, err)
+		if _, err := r.kubeClientNative.CoreV1().Secrets(r.namespace).Get(ctx, opts.FilerImage.ImagePullSecretName, metav1.GetOptions{}); err != nil {
+			return fmt.Errorf("failed to get filer image pull secret %s: %w", opts.FilerImage.ImagePullSecretName, err)
 		}
 	}
 	if opts.ExecutorImagePullSecret.StaticName != "" {
-		if _, err = kubeClientNative.CoreV1().Secrets(namespace).Get(context.Background(), opts.ExecutorImagePullSecret.StaticName, metav1.GetOptions{}); err != nil {
-			return nil, fmt.Errorf("failed to get static executor image pull secret %s: %w", opts.ExecutorImagePullSecret.StaticName, err)
+		if _, err := r.kubeClientNative.CoreV1().Secrets(r.namespace).Get(ctx, opts.ExecutorImagePullSecret.StaticName, metav1.GetOptions{}); err != nil {
+			return fmt.Errorf("failed to get static executor image pull secret %s: %w", opts.ExecutorImagePullSecret.StaticName, err)
 		}
 	}
 
-	res.taskProcessing = make(map[string]struct{})
+	return nil
+}
 
-	return res, nil
+func convertFilerResources(opts ResourcesOptions) corev1.ResourceRequirements {
+	res := corev1.ResourceRequirements{
+		Requests: make(corev1.ResourceList),
+		Limits:   make(corev1.ResourceList),
+	}
+	for resourceName, quantity := range opts.Requests {
+		res.Requests[corev1.ResourceName(resourceName)] = resource.MustParse(quantity)
+	}
+	for resourceName, quantity := range opts.Limits {
+		res.Limits[corev1.ResourceName(resourceName)] = resource.MustParse(quantity)
+	}
+	return res
+}
+
+func (r *Runner) options() *Options {
+	r.optsLock.RLock()
+	defer r.optsLock.RUnlock()
+	return r.opts
+}
+
+func (r *Runner) filerResourceRequirements() corev1.ResourceRequirements {
+	r.optsLock.RLock()
+	defer r.optsLock.RUnlock()
+	return r.filerResources
+}
+
+// UpdateOptions applies the reloadable subset of opts, which only affects jobs created later.
+// It returns whether some changed options require restart to take effect.
+func (r *Runner) UpdateOptions(ctx context.Context, opts *Options) (needRestart bool, err error) {
+	current := r.options()
+	res := *current
+	res.ExecutorImagePullSecret = opts.ExecutorImagePullSecret
+	res.FilerImage = opts.FilerImage
+	res.FilerResources = opts.FilerResources
+	res.ExecutorRetries = opts.ExecutorRetries
+	res.FilerRetries = opts.FilerRetries
+	res.IgnorePodDisruptions = opts.IgnorePodDisruptions
+	res.PodPollInterval = opts.PodPollInterval
+	res.FallbackStorageClass = opts.FallbackStorageClass
+	res.PVCBindTimeout = opts.PVCBindTimeout
+	res.PodImagePullBackoffTimeout = opts.PodImagePullBackoffTimeout
+	res.PodCreateContainerErrorTimeout = opts.PodCreateContainerErrorTimeout
+	res.PodUnschedulableTimeout = opts.PodUnschedulableTimeout
+	res.PodScaleUpUnschedulableTimeout = opts.PodScaleUpUnschedulableTimeout
+	res.FilerPodLabels = opts.FilerPodLabels
+	res.FilerPodAnnotations = opts.FilerPodAnnotations
+	res.ExecutorECSPodLabels = opts.ExecutorECSPodLabels
+	res.ExecutorECSPodAnnotations = opts.ExecutorECSPodAnnotations
+	res.ExecutorPodEnv = opts.ExecutorPodEnv
+	res.FilerPodEnv = opts.FilerPodEnv
+	res.TaskLog.FilerLogLevel = opts.TaskLog.FilerLogLevel
+	needRestart = !reflect.DeepEqual(&res, opts)
+	if reflect.DeepEqual(&res, current) {
+		return needRestart, nil
+	}
+
+	if err = r.checkReferences(ctx, &res); err != nil {
+		return needRestart, err
+	}
+	filerResources := convertFilerResources(res.FilerResources)
+
+	r.optsLock.Lock()
+	defer r.optsLock.Unlock()
+	r.opts = &res
+	r.filerResources = filerResources
+	return needRestart, nil
 }
 
 func (r *Runner) taskLogger(taskID string) filelog.Logger {
-	return filelog.NewLoggerWithWriteToFile(filepath.Join(r.opts.TaskLog.OutputDir, taskID, taskLogFileName))
+	return filelog.NewLoggerWithWriteToFile(filepath.Join(r.options().TaskLog.OutputDir, taskID, taskLogFileName))
 }
 
 func (r *Runner) removeTaskLogFile(taskID string) {
-	_ = os.RemoveAll(filepath.Join(r.opts.TaskLog.OutputDir, taskID))
+	_ = os.RemoveAll(filepath.Join(r.options().TaskLog.OutputDir, taskID))
 }
 
 func (r *Runner) tryProcessTask(taskID string) bool {
@@ -127,21 +220,27 @@ const taskLogFileName = "app.log"
 
 // RegisterCrontab ...
 func RegisterCrontab(cron *crontab.Crontab, runner *Runner) error {
-	return cron.RegisterCron(time.Hour, runner.cleanTaskLogFiles)
+	if err := cron.RegisterCron(time.Hour, runner.cleanTaskLogFiles); err != nil {
+		return err
+	}
+	if shipInterval := runner.options().TaskLog.ShipInterval; shipInterval > 0 {
+		return cron.RegisterCron(shipInterval, runner.shipTaskLogs)
+	}
+	return nil
 }
 
 // Sometimes, task log file or directory will remain after task finished, because multiple
 // reconciles, or mounted pod terminating. We can clean these files periodically.
 func (r *Runner) cleanTaskLogFiles() {
-	files, err := os.ReadDir(r.opts.TaskLog.OutputDir)
+	files, err := os.ReadDir(r.options().TaskLog.OutputDir)
 	if err != nil {
 		log.Warnw("failed to list task log files", "err", err)
 		return
 	}
 	ctx := context.Background()
 	for _, file := range files {
-		if !file.IsDir() {
-			continue
+		if !file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue // e.g. archive directory
 		}
 		taskID := file.Name()
 		_, err = r.localStoreHelper.GetTask(ctx, taskID)
@@ -154,4 +253,5 @@ func (r *Runner) cleanTaskLogFiles() {
 		}
 		r.removeTaskLogFile(taskID)
 	}
+	r.cleanTaskLogArchives()
 }
@@ -1,15 +1,27 @@
 package runner
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
 	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+var (
+	fakeNamespace = "vetes"
+	fakeTaskID    = "task-xxxx"
+	fakeClusterID = "cluster-xxxx"
 )
 
 func TestCleanTaskLogFiles(t *testing.T) {
@@ -59,3 +71,49 @@ func TestCleanTaskLogFiles(t *testing.T) {
 	}
 	g.Expect(names).To(gomega.ConsistOf("app.log", "task-exist"))
 }
+
+func TestUpdateOptions(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	opts := NewOptions()
+	opts.S3.Enable = false
+	r := &Runner{
+		opts:             opts,
+		filerResources:   convertFilerResources(opts.FilerResources),
+		kubeClientNative: kubernetesfake.NewSimpleClientset(),
+		namespace:        fakeNamespace,
+	}
+
+	newOpts := NewOptions()
+	newOpts.S3.Enable = false
+	newOpts.StorageClass = "other-storage-class"
+	newOpts.FilerPodLabels = map[string]string{"key": "value"}
+	newOpts.FilerResources.Limits[string(corev1.ResourceCPU)] = "2"
+	needRestart, err := r.UpdateOptions(context.Background(), newOpts)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(needRestart).To(gomega.BeTrue())
+	g.Expect(r.options().StorageClass).To(gomega.Equal(opts.StorageClass))
+	g.Expect(r.options().FilerPodLabels).To(gomega.Equal(newOpts.FilerPodLabels))
+	filerLimits := r.filerResourceRequirements().Limits
+	g.Expect(filerLimits.Cpu().String()).To(gomega.Equal("2"))
+
+	newOpts = NewOptions()
+	newOpts.S3.Enable = false
+	newOpts.FilerImage.ImagePullSecretName = "not-exist"
+	_, err = r.UpdateOptions(context.Background(), newOpts)
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(r.options().FilerImage.ImagePullSecretName).To(gomega.BeEmpty())
+}
+
+func TestStopAndCleanTaskGetTaskFailed(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(ctrl)
+	fakeLocalStoreHelper.EXPECT().GetTask(gomock.Any(), fakeTaskID).Return(nil, errors.New("connection refused"))
+	r := &Runner{localStoreHelper: fakeLocalStoreHelper}
+
+	_, err := r.stopAndCleanTask(context.Background(), nil, &models.Task{ID: fakeTaskID}, consts.TaskSystemError)
+	g.Expect(err).To(gomega.MatchError("connection refused"))
+}
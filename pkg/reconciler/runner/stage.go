@@ -37,6 +37,25 @@ const (
 	taskStageOutputsFilerToCreate = taskStageExecutorsFinished
 )
 
+var taskStageNames = map[int]string{
+	taskStageInit:                 "Init",
+	taskStageInitializing:         "Initializing",
+	taskStagePVCCreated:           "PVCCreated",
+	taskStageInputsFilerCreated:   "InputsFilerCreated",
+	taskStageInputsFilerFinished:  "InputsFilerFinished",
+	taskStageRunning:              "Running",
+	taskStageExecutorsFinished:    "ExecutorsFinished",
+	taskStageOutputsFilerCreated:  "OutputsFilerCreated",
+	taskStageOutputsFilerFinished: "OutputsFilerFinished",
+}
+
+func taskStageName(stage int) string {
+	if name, ok := taskStageNames[stage]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
 type executorStatus int
 
 const (
@@ -46,6 +65,20 @@ const (
 	executorStatusSuccess
 )
 
+var executorStatusNames = map[executorStatus]string{
+	executorStatusToCreate: "ToCreate",
+	executorStatusCreated:  "Created",
+	executorStatusFailed:   "Failed",
+	executorStatusSuccess:  "Success",
+}
+
+func executorStatusName(status executorStatus) string {
+	if name, ok := executorStatusNames[status]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
 type executorStage struct {
 	index  int
 	status executorStatus
@@ -4,17 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/filelog"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/tracing"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
@@ -26,11 +30,14 @@ const (
 )
 
 // ProcessTask ...
-func (r *Runner) ProcessTask(ctx context.Context, taskID string) (ctrl.Result, error) {
+func (r *Runner) ProcessTask(ctx context.Context, taskID string) (_ ctrl.Result, reterr error) {
 	if !r.tryProcessTask(taskID) {
 		return ctrl.Result{RequeueAfter: tryProcessLatency}, nil
 	}
 	defer r.releaseProcessTask(taskID)
+	defer func() {
+		r.recordTaskError(taskID, reterr)
+	}()
 	newLogger := r.taskLogger(taskID)
 
 	task, err := r.vetesClient.GetTask(ctx, &models.GetTaskRequest{ID: taskID, View: consts.BasicView})
@@ -52,11 +59,12 @@ func (r *Runner) ProcessTask(ctx context.Context, taskID string) (ctrl.Result, e
 	}
 }
 
-func (r *Runner) runTask(ctx context.Context, logger filelog.Logger, task *models.Task) (ctrl.Result, error) {
+func (r *Runner) runTask(ctx context.Context, logger filelog.Logger, task *models.Task) (res ctrl.Result, reterr error) {
 	taskInfo, err := r.localStoreHelper.GetTask(ctx, task.ID)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	ctx = tracing.ContextWithTraceParent(ctx, taskInfo.TraceParent)
 	if taskInfo.Stop != nil {
 		return r.stopAndCleanTask(ctx, logger, task, *taskInfo.Stop)
 	}
@@ -66,22 +74,34 @@ func (r *Runner) runTask(ctx context.Context, logger filelog.Logger, task *model
 		return result, err
 	}
 
-	s3SecretName := ""
-	if r.opts.S3.Enable {
-		s3SecretName = r.opts.S3.StaticSecretName
-	}
-	executorImagePullSecret := r.opts.ExecutorImagePullSecret.StaticName
+	s3SecretName := r.s3SecretName()
+	executorImagePullSecret := r.options().ExecutorImagePullSecret.StaticName
 
 	if taskInfo.Stage == nil {
-		return ctrl.Result{}, r.localStoreHelper.RecordTaskStage(ctx, task.ID, taskStageInit)
+		return ctrl.Result{}, r.recordTaskStage(ctx, task.ID, taskStageInit)
 	}
 	currentStage := *taskInfo.Stage
 
+	ctx, span := tracing.Start(ctx, "stage "+taskStageName(currentStage), trace.WithAttributes(tracing.AttrTaskID.String(task.ID)))
+	defer func() {
+		tracing.EndSpan(span, reterr)
+	}()
+
+	if currentStage >= taskStagePVCCreated && shouldCreatePVC(&taskInfo.Task) {
+		pvcResult, done, err := r.watchPVCBinding(ctx, logger, task, taskInfo)
+		if err != nil || done {
+			return pvcResult, err
+		}
+		defer func() {
+			res = utils.MergeCtrlResults(res, pvcResult)
+		}()
+	}
+
 	switch {
 	case currentStage < taskStageInitializing:
 		return ctrl.Result{}, r.doInitializing(ctx, logger, task)
 	case currentStage < taskStagePVCCreated:
-		return ctrl.Result{}, r.doCreatePVC(ctx, logger, &taskInfo.Task)
+		return ctrl.Result{}, r.doCreatePVC(ctx, logger, taskInfo)
 	case currentStage < taskStageInputsFilerCreated:
 		return ctrl.Result{}, r.doCreateInputsFiler(ctx, logger, &taskInfo.Task, s3SecretName)
 	case currentStage < taskStageInputsFilerFinished:
@@ -99,6 +119,13 @@ func (r *Runner) runTask(ctx context.Context, logger filelog.Logger, task *model
 	}
 }
 
+func (r *Runner) s3SecretName() string {
+	if r.options().S3.Enable {
+		return r.options().S3.StaticSecretName
+	}
+	return ""
+}
+
 func (r *Runner) doInitializing(ctx context.Context, logger filelog.Logger, task *models.Task) error {
 	updateTaskReq := &models.UpdateTaskRequest{
 		ID:    task.ID,
@@ -109,7 +136,7 @@ func (r *Runner) doInitializing(ctx context.Context, logger filelog.Logger, task
 		return err
 	}
 	logger.Infof("start task: Initializing")
-	return r.localStoreHelper.RecordTaskStage(ctx, task.ID, taskStageInitializing)
+	return r.recordTaskStage(ctx, task.ID, taskStageInitializing)
 }
 
 func (r *Runner) genUpdateTaskLogsInitializing(taskLogs []*models.TaskLog) []*models.TaskLog {
@@ -123,13 +150,13 @@ func (r *Runner) genUpdateTaskLogsInitializing(taskLogs []*models.TaskLog) []*mo
 	}}
 }
 
-func (r *Runner) doCreatePVC(ctx context.Context, logger filelog.Logger, localTask *localstore.Task) error {
-	if shouldCreatePVC(localTask) {
-		if err := r.createPVC(ctx, logger, localTask.ID, localTask.Resources.DiskGB); err != nil {
+func (r *Runner) doCreatePVC(ctx context.Context, logger filelog.Logger, taskInfo *localstore.TaskInfo) error {
+	if shouldCreatePVC(&taskInfo.Task) {
+		if err := r.createPVC(ctx, logger, taskInfo.ID, taskInfo.Resources.DiskGB, r.taskStorageClass(taskInfo)); err != nil {
 			return err
 		}
 	}
-	return r.localStoreHelper.RecordTaskStage(ctx, localTask.ID, taskStagePVCCreated)
+	return r.recordTaskStage(ctx, taskInfo.ID, taskStagePVCCreated)
 }
 
 func (r *Runner) doCreateInputsFiler(ctx context.Context, logger filelog.Logger, localTask *localstore.Task, s3SecretName string) error {
@@ -139,7 +166,7 @@ func (r *Runner) doCreateInputsFiler(ctx context.Context, logger filelog.Logger,
 			return err
 		}
 	}
-	return r.localStoreHelper.RecordTaskStage(ctx, localTask.ID, taskStageInputsFilerCreated)
+	return r.recordTaskStage(ctx, localTask.ID, taskStageInputsFilerCreated)
 }
 
 func (r *Runner) doWatchInputsFiler(ctx context.Context, logger filelog.Logger, task *models.Task, localTask *localstore.Task) (ctrl.Result, error) {
@@ -153,9 +180,11 @@ func (r *Runner) doWatchInputsFiler(ctx context.Context, logger filelog.Logger,
 			return ctrl.Result{}, nil
 		case jobFailed:
 			logger.Errorf("stop task because job %s failed", inputsFilerJob.Name)
+			r.events.eventf(inputsFilerJob, corev1.EventTypeWarning, eventReasonJobFailed, "stop task because job failed")
 			if err := r.recordJobFailedMessage(ctx, logger, inputsFilerJob.Name); err != nil {
 				return ctrl.Result{}, err
 			}
+			observeJobStage(metrics.StageInputsFiler, inputsFilerJob)
 			return r.stopAndCleanTask(ctx, logger, task, consts.TaskSystemError)
 		case jobComplete:
 			deleted, err := r.deleteJobPods(ctx, logger, inputsFilerJob.Name)
@@ -165,9 +194,10 @@ func (r *Runner) doWatchInputsFiler(ctx context.Context, logger filelog.Logger,
 			if !deleted {
 				return ctrl.Result{RequeueAfter: waitPodDeleted}, nil
 			}
+			observeJobStage(metrics.StageInputsFiler, inputsFilerJob)
 		}
 	}
-	return ctrl.Result{}, r.localStoreHelper.RecordTaskStage(ctx, localTask.ID, taskStageInputsFilerFinished)
+	return ctrl.Result{}, r.recordTaskStage(ctx, localTask.ID, taskStageInputsFilerFinished)
 }
 
 func (r *Runner) doRunning(ctx context.Context, logger filelog.Logger, task *models.Task) error {
@@ -179,12 +209,12 @@ func (r *Runner) doRunning(ctx context.Context, logger filelog.Logger, task *mod
 		return err
 	}
 	logger.Infof("start task: Running")
-	return r.localStoreHelper.RecordTaskStage(ctx, task.ID, taskStageRunning)
+	return r.recordTaskStage(ctx, task.ID, taskStageRunning)
 }
 
 func (r *Runner) doExecutors(ctx context.Context, logger filelog.Logger, task *models.Task, taskInfo *localstore.TaskInfo, executorImagePullSecret string) (ctrl.Result, error) {
 	if taskInfo.ExecutorStage == nil {
-		return ctrl.Result{}, r.localStoreHelper.RecordTaskExecutorStage(ctx, task.ID, newExecutorStageValue(0, executorStatusToCreate))
+		return ctrl.Result{}, r.recordTaskExecutorStage(ctx, task.ID, newExecutorStageValue(0, executorStatusToCreate))
 	}
 	eStage := parseExecutorStageValue(*taskInfo.ExecutorStage)
 	maxIndex := len(task.Executors) - 1
@@ -195,14 +225,14 @@ func (r *Runner) doExecutors(ctx context.Context, logger filelog.Logger, task *m
 	case eStage.status == executorStatusCreated:
 		return r.doWatchExecutor(ctx, logger, task.ID, eStage.index)
 	case eStage.status == executorStatusSuccess && eStage.index < maxIndex:
-		return ctrl.Result{}, r.localStoreHelper.RecordTaskExecutorStage(ctx, task.ID, newExecutorStageValue(eStage.index+1, executorStatusToCreate))
+		return ctrl.Result{}, r.recordTaskExecutorStage(ctx, task.ID, newExecutorStageValue(eStage.index+1, executorStatusToCreate))
 	default:
 		if eStage.status == executorStatusSuccess {
 			logger.Infof("finished all executors: Success")
 		} else if eStage.status == executorStatusFailed {
 			logger.Infof("finished all executors: Failed")
 		}
-		return ctrl.Result{}, r.localStoreHelper.RecordTaskStage(ctx, task.ID, taskStageExecutorsFinished)
+		return ctrl.Result{}, r.recordTaskStage(ctx, task.ID, taskStageExecutorsFinished)
 	}
 }
 
@@ -218,6 +248,7 @@ func (r *Runner) doWatchExecutor(ctx context.Context, logger filelog.Logger, tas
 	case jobFailed:
 		eStatus = executorStatusFailed
 		logger.Errorf("executor job %s failed", executorJob.Name)
+		r.events.eventf(executorJob, corev1.EventTypeWarning, eventReasonJobFailed, "executor job failed")
 		if err := r.recordJobFailedMessage(ctx, logger, executorJob.Name); err != nil {
 			return ctrl.Result{}, err
 		}
@@ -231,7 +262,8 @@ func (r *Runner) doWatchExecutor(ctx context.Context, logger filelog.Logger, tas
 	if !deleted {
 		return ctrl.Result{RequeueAfter: waitPodDeleted}, nil
 	}
-	return ctrl.Result{}, r.localStoreHelper.RecordTaskExecutorStage(ctx, taskID, newExecutorStageValue(executorIndex, eStatus))
+	observeJobStage(metrics.StageExecutor, executorJob)
+	return ctrl.Result{}, r.recordTaskExecutorStage(ctx, taskID, newExecutorStageValue(executorIndex, eStatus))
 }
 
 func (r *Runner) doCreateOutputsFiler(ctx context.Context, logger filelog.Logger, localTask *localstore.Task, s3SecretName string) error {
@@ -241,7 +273,7 @@ func (r *Runner) doCreateOutputsFiler(ctx context.Context, logger filelog.Logger
 			return err
 		}
 	}
-	return r.localStoreHelper.RecordTaskStage(ctx, localTask.ID, taskStageOutputsFilerCreated)
+	return r.recordTaskStage(ctx, localTask.ID, taskStageOutputsFilerCreated)
 }
 
 func (r *Runner) doWatchOutputsFiler(ctx context.Context, logger filelog.Logger, task *models.Task, localTask *localstore.Task) (ctrl.Result, error) {
@@ -255,9 +287,11 @@ func (r *Runner) doWatchOutputsFiler(ctx context.Context, logger filelog.Logger,
 			return ctrl.Result{}, nil
 		case jobFailed:
 			logger.Errorf("stop task because job %s failed", outputsFilerJob.Name)
+			r.events.eventf(outputsFilerJob, corev1.EventTypeWarning, eventReasonJobFailed, "stop task because job failed")
 			if err := r.recordJobFailedMessage(ctx, logger, outputsFilerJob.Name); err != nil {
 				return ctrl.Result{}, err
 			}
+			observeJobStage(metrics.StageOutputsFiler, outputsFilerJob)
 			return r.stopAndCleanTask(ctx, logger, task, consts.TaskSystemError)
 		case jobComplete:
 			deleted, err := r.deleteJobPods(ctx, logger, outputsFilerJob.Name)
@@ -267,9 +301,10 @@ func (r *Runner) doWatchOutputsFiler(ctx context.Context, logger filelog.Logger,
 			if !deleted {
 				return ctrl.Result{RequeueAfter: waitPodDeleted}, nil
 			}
+			observeJobStage(metrics.StageOutputsFiler, outputsFilerJob)
 		}
 	}
-	return ctrl.Result{}, r.localStoreHelper.RecordTaskStage(ctx, localTask.ID, taskStageOutputsFilerFinished)
+	return ctrl.Result{}, r.recordTaskStage(ctx, localTask.ID, taskStageOutputsFilerFinished)
 }
 
 func (r *Runner) doComplete(ctx context.Context, logger filelog.Logger, task *models.Task, taskInfo *localstore.TaskInfo) (ctrl.Result, error) {
@@ -290,18 +325,75 @@ func (r *Runner) doComplete(ctx context.Context, logger filelog.Logger, task *mo
 	return r.stopAndCleanTask(ctx, logger, task, finishState)
 }
 
-func (r *Runner) stopAndCleanTask(ctx context.Context, logger filelog.Logger, task *models.Task, state string) (ctrl.Result, error) {
+func (r *Runner) stopAndCleanTask(ctx context.Context, logger filelog.Logger, task *models.Task, state string) (_ ctrl.Result, reterr error) {
 	taskInfo, err := r.localStoreHelper.GetTask(ctx, task.ID)
 	if err != nil {
 		if errors.Is(err, localstore.ErrNotFound) {
 			return ctrl.Result{}, nil
 		}
+		return ctrl.Result{}, err
 	}
+	ctx = tracing.ContextWithTraceParent(ctx, taskInfo.TraceParent)
+	ctx, span := tracing.Start(ctx, "stop", trace.WithAttributes(tracing.AttrTaskID.String(task.ID), attribute.String("state", state)))
+	defer func() {
+		tracing.EndSpan(span, reterr)
+	}()
 	if taskInfo.Stop == nil {
 		if err = r.localStoreHelper.StopTask(ctx, task.ID, state); err != nil {
 			return ctrl.Result{}, err
 		}
+		metrics.TasksFinishedTotal.WithLabelValues(state).Inc()
+		eventType := corev1.EventTypeWarning
+		if state == consts.TaskComplete || state == consts.TaskCanceled {
+			eventType = corev1.EventTypeNormal
+		}
+		r.taskEventf(ctx, task.ID, eventType, eventReasonTaskStopped, "task stopped: %s", state)
+	}
+	defer func() {
+		if reterr != nil {
+			r.taskEventf(ctx, task.ID, corev1.EventTypeWarning, eventReasonCleanupFailed, "failed to clean task: %s", reterr.Error())
+		}
+	}()
+	if err = r.deleteTaskObjects(ctx, logger, taskInfo); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Sync()
+	offset := logOffset(taskInfo)
+	message, end, err := r.unshippedTaskLog(task.ID, offset)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	updateTaskReq := &models.UpdateTaskRequest{
+		ID:   task.ID,
+		Logs: r.genUpdateTaskLogsFinish(task.Logs, message, offset > 0),
+	}
+	if task.State != state {
+		updateTaskReq.State = &state
+	}
+	if state == consts.TaskQueued {
+		// requeued by drain deadline, let other clusters claim it
+		updateTaskReq.ClusterID = utils.Point("")
+	}
+	if _, err = r.vetesClient.UpdateTask(ctx, updateTaskReq); err != nil {
+		if errors.Is(err, vetesclient.ErrBadRequest) {
+			log.Warnw("bad request for update task, maybe because executor end_time not filled", "err", err)
+			return ctrl.Result{RequeueAfter: waitUpdateTaskTimeConflict}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if end > offset {
+		// avoid shipping logs again if cleaning fails later
+		if err = r.localStoreHelper.RecordTaskLogOffset(ctx, task.ID, end); err != nil && !errors.Is(err, localstore.ErrNotFound) {
+			return ctrl.Result{}, err
+		}
 	}
+
+	return ctrl.Result{}, r.forgetTask(ctx, taskInfo)
+}
+
+// deleteTaskObjects deletes jobs and pvc created in stages of the task
+func (r *Runner) deleteTaskObjects(ctx context.Context, logger filelog.Logger, taskInfo *localstore.TaskInfo) error {
 	currentStage := taskStageInit
 	if taskInfo.Stage != nil {
 		currentStage = *taskInfo.Stage
@@ -309,79 +401,68 @@ func (r *Runner) stopAndCleanTask(ctx context.Context, logger filelog.Logger, ta
 
 	if currentStage >= taskStageOutputsFilerToCreate {
 		if shouldCreateOutputsFiler(&taskInfo.Task) {
-			if err = r.deleteJob(ctx, logger, outputsFilerJobName(task.ID)); err != nil {
-				return ctrl.Result{}, err
+			if err := r.deleteJob(ctx, logger, outputsFilerJobName(taskInfo.ID)); err != nil {
+				return err
 			}
 		}
 	}
 
 	if currentStage >= taskStageExecutorsToCreate && taskInfo.ExecutorStage != nil {
 		for index := 0; index <= parseExecutorStageValue(*taskInfo.ExecutorStage).index; index++ {
-			if err = r.deleteJob(ctx, logger, executorJobName(task.ID, index)); err != nil {
-				return ctrl.Result{}, err
+			if err := r.deleteJob(ctx, logger, executorJobName(taskInfo.ID, index)); err != nil {
+				return err
 			}
 		}
 	}
 
 	if currentStage >= taskStageInputsFilerToCreate {
 		if shouldCreateInputsFiler(&taskInfo.Task) {
-			if err = r.deleteJob(ctx, logger, inputsFilerJobName(task.ID)); err != nil {
-				return ctrl.Result{}, err
+			if err := r.deleteJob(ctx, logger, inputsFilerJobName(taskInfo.ID)); err != nil {
+				return err
 			}
 		}
 	}
 
 	if currentStage >= taskStagePVCToCreate {
 		if shouldCreatePVC(&taskInfo.Task) {
-			if err = r.deletePVC(ctx, logger, pvcName(task.ID)); err != nil {
-				return ctrl.Result{}, err
+			r.observePVCStage(ctx, pvcName(taskInfo.ID))
+			if err := r.deletePVC(ctx, logger, pvcName(taskInfo.ID)); err != nil {
+				return err
 			}
 		}
 	}
 
-	logger.Sync()
-	message, err := logger.GetFileContent()
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return ctrl.Result{}, fmt.Errorf("failed to read log file content: %w", err)
-		}
-	}
-	updateTaskReq := &models.UpdateTaskRequest{
-		ID:   task.ID,
-		Logs: r.genUpdateTaskLogsFinish(task.Logs, string(message)),
-	}
-	if task.State != state {
-		updateTaskReq.State = &state
-	}
-	if _, err = r.vetesClient.UpdateTask(ctx, updateTaskReq); err != nil {
-		if errors.Is(err, vetesclient.ErrBadRequest) {
-			log.Warnw("bad request for update task, maybe because executor end_time not filled", "err", err)
-			return ctrl.Result{RequeueAfter: waitUpdateTaskTimeConflict}, nil
-		}
-		return ctrl.Result{}, err
-	}
+	return nil
+}
 
+// forgetTask deletes local files and local store of the task after its objects are deleted
+func (r *Runner) forgetTask(ctx context.Context, taskInfo *localstore.TaskInfo) error {
 	if taskInfo.InputsRef != "" || taskInfo.OutputsRef != "" {
-		r.offloadHelper.DeleteOffloadFile(task.ID)
+		r.offloadHelper.DeleteOffloadFile(taskInfo.ID)
 	}
-	r.removeTaskLogFile(task.ID)
-	if err = r.accelerator.OnFinishTask(ctx, &taskInfo.Task); err != nil {
-		return ctrl.Result{}, err
+	r.removeTaskLogFile(taskInfo.ID)
+	if err := r.accelerator.OnFinishTask(ctx, &taskInfo.Task); err != nil {
+		return err
 	}
-	if err = r.localStoreHelper.DeleteTask(ctx, task.ID); err != nil && !errors.Is(err, localstore.ErrNotFound) {
-		return ctrl.Result{}, err
+	if err := r.localStoreHelper.DeleteTask(ctx, taskInfo.ID); err != nil && !errors.Is(err, localstore.ErrNotFound) {
+		return err
 	}
-	return ctrl.Result{}, nil
+	r.forgetTaskError(taskInfo.ID)
+	r.forgetPodDisruptions(taskInfo.ID)
+	return nil
 }
 
-func (r *Runner) genUpdateTaskLogsFinish(taskLogs []*models.TaskLog, message string) []*models.TaskLog {
-	if message == "" {
+// genUpdateTaskLogsFinish appends message to system logs, shipped means some logs are shipped while running
+func (r *Runner) genUpdateTaskLogsFinish(taskLogs []*models.TaskLog, message string, shipped bool) []*models.TaskLog {
+	if message == "" && !shipped {
 		message = "<empty>"
 	}
 	res := []*models.TaskLog{{
-		ClusterID:  r.clusterID,
-		SystemLogs: []string{message},
+		ClusterID: r.clusterID,
 	}}
+	if message != "" {
+		res[0].SystemLogs = []string{message}
+	}
 
 	now := utils.Point(time.Now().Format(time.RFC3339))
 
@@ -0,0 +1,203 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+const (
+	// taskLogArchiveDir is the directory in TaskLog.OutputDir of archived full task logs, which are truncated in vetes-api
+	taskLogArchiveDir = ".archive"
+	minSystemLogBytes = 4 << 10
+)
+
+func (r *Runner) taskLogFile(taskID string) string {
+	return filepath.Join(r.options().TaskLog.OutputDir, taskID, taskLogFileName)
+}
+
+// shipTaskLogs ships new logs of running tasks to vetes-api, which appends them to system logs.
+// Only the head half of MaxSystemLogBytes is shipped while running, the rest is shipped on finish.
+func (r *Runner) shipTaskLogs() {
+	ctx := context.Background()
+	tasks, err := r.localStoreHelper.ListTasks(ctx)
+	if err != nil {
+		log.Warnw("failed to list tasks to ship logs", "err", err)
+		return
+	}
+	for _, taskInfo := range tasks {
+		if taskInfo.Stop != nil || taskInfo.Stage == nil || *taskInfo.Stage < taskStageInitializing {
+			continue
+		}
+		if err = r.shipTaskLog(ctx, taskInfo.ID); err != nil {
+			log.Warnw("failed to ship task log", "task", taskInfo.ID, "err", err)
+		}
+	}
+}
+
+func (r *Runner) shipTaskLog(ctx context.Context, taskID string) error {
+	if !r.tryProcessTask(taskID) {
+		return nil // shipped next time
+	}
+	defer r.releaseProcessTask(taskID)
+
+	// get again because the task may be stopped during listing
+	taskInfo, err := r.localStoreHelper.GetTask(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, localstore.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if taskInfo.Stop != nil {
+		return nil
+	}
+	offset := logOffset(taskInfo)
+	headBytes := r.options().TaskLog.MaxSystemLogBytes / 2
+	if offset >= headBytes {
+		return nil
+	}
+	chunk, err := readTaskLog(r.taskLogFile(taskID), offset, headBytes-offset)
+	if err != nil {
+		return err
+	}
+	if offset+int64(len(chunk)) < headBytes {
+		// ship complete lines only, unless the head is full
+		chunk = chunk[:bytes.LastIndexByte(chunk, '\n')+1]
+	}
+	if len(chunk) == 0 {
+		return nil
+	}
+	if _, err = r.vetesClient.UpdateTask(ctx, &models.UpdateTaskRequest{
+		ID:   taskID,
+		Logs: []*models.TaskLog{{ClusterID: r.clusterID, SystemLogs: []string{string(chunk)}}},
+	}); err != nil {
+		return err
+	}
+	return r.localStoreHelper.RecordTaskLogOffset(ctx, taskID, offset+int64(len(chunk)))
+}
+
+// unshippedTaskLog returns the task log after offset and the end offset of the log file. If the whole log exceeds
+// MaxSystemLogBytes, only the tail is returned with a pointer to the archived full log, and nothing is returned
+// once MaxSystemLogBytes is used up by logs shipped before.
+func (r *Runner) unshippedTaskLog(taskID string, offset int64) (string, int64, error) {
+	file := r.taskLogFile(taskID)
+	stat, err := os.Stat(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", offset, nil
+		}
+		return "", offset, fmt.Errorf("failed to stat log file: %w", err)
+	}
+	size := stat.Size()
+	if size <= offset {
+		return "", offset, nil
+	}
+	remain := r.options().TaskLog.MaxSystemLogBytes - offset
+	if remain <= 0 {
+		// the limit is used up by logs shipped before, e.g. the finished task is retried with new logs
+		return "", size, nil
+	}
+	if size-offset <= remain {
+		content, err := readTaskLog(file, offset, size-offset)
+		return string(content), size, err
+	}
+
+	// read one more byte to know whether the tail starts with a complete line, or cut the partial line
+	tail, err := readTaskLog(file, size-remain-1, remain+1)
+	if err != nil {
+		return "", offset, err
+	}
+	if index := bytes.IndexByte(tail, '\n'); index >= 0 {
+		tail = tail[index+1:]
+	}
+	pointer := "the full log is not archived"
+	archived, err := r.archiveTaskLog(taskID)
+	if err != nil {
+		log.Warnw("failed to archive task log", "task", taskID, "err", err)
+	} else {
+		pointer = fmt.Sprintf("the full log is archived at %s in pvc %s", archived, r.options().TaskLog.PVCName)
+	}
+	marker := fmt.Sprintf("...... %d bytes truncated, %s ......\n", size-offset-int64(len(tail)), pointer)
+	return marker + string(tail), size, nil
+}
+
+// archiveTaskLog copies the task log file to archive directory, and returns the path relative to TaskLog.OutputDir
+func (r *Runner) archiveTaskLog(taskID string) (string, error) {
+	relPath := filepath.Join(taskLogArchiveDir, taskID, taskLogFileName)
+	dst := filepath.Join(r.options().TaskLog.OutputDir, relPath)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+	srcFile, err := os.Open(r.taskLogFile(taskID))
+	if err != nil {
+		return "", err
+	}
+	defer srcFile.Close()
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	defer dstFile.Close()
+	if _, err = io.Copy(dstFile, srcFile); err != nil {
+		return "", err
+	}
+	return relPath, nil
+}
+
+// cleanTaskLogArchives removes archived task logs older than TaskLog.ArchiveRetention
+func (r *Runner) cleanTaskLogArchives() {
+	archiveDir := filepath.Join(r.options().TaskLog.OutputDir, taskLogArchiveDir)
+	files, err := os.ReadDir(archiveDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnw("failed to list archived task logs", "err", err)
+		}
+		return
+	}
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) > r.options().TaskLog.ArchiveRetention {
+			_ = os.RemoveAll(filepath.Join(archiveDir, file.Name()))
+		}
+	}
+}
+
+func readTaskLog(file string, offset, limit int64) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer f.Close()
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek log file: %w", err)
+	}
+	content, err := io.ReadAll(io.LimitReader(f, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log file: %w", err)
+	}
+	return content, nil
+}
+
+func logOffset(taskInfo *localstore.TaskInfo) int64 {
+	if taskInfo.LogOffset == nil {
+		return 0
+	}
+	return *taskInfo.LogOffset
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	localstorefake "github.com/GBA-BI/tes-k8s-agent/pkg/localstore/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/utils"
+	vetesclientfake "github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/fake"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+func TestShipTaskLog(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	outputDir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(outputDir, fakeTaskID), 0755)).To(gomega.Succeed())
+	line := strings.Repeat("x", 99) + "\n"
+	content := strings.Repeat(line, 100) + "partial line"
+	g.Expect(os.WriteFile(filepath.Join(outputDir, fakeTaskID, taskLogFileName), []byte(content), 0644)).To(gomega.Succeed())
+
+	fakeLocalStoreHelper := localstorefake.NewFakeHelper(mockctrl)
+	fakeVeTESClient := vetesclientfake.NewFakeClient(mockctrl)
+	r := &Runner{
+		opts:             &Options{TaskLog: TaskLogOptions{OutputDir: outputDir, PVCName: "log-pvc", MaxSystemLogBytes: 100 << 10}},
+		localStoreHelper: fakeLocalStoreHelper,
+		vetesClient:      fakeVeTESClient,
+		clusterID:        fakeClusterID,
+		taskProcessing:   map[string]struct{}{},
+	}
+
+	// complete lines are shipped
+	fakeLocalStoreHelper.EXPECT().GetTask(gomock.Any(), fakeTaskID).Return(&localstore.TaskInfo{Task: localstore.Task{ID: fakeTaskID}}, nil)
+	fakeVeTESClient.EXPECT().UpdateTask(gomock.Any(), &models.UpdateTaskRequest{
+		ID:   fakeTaskID,
+		Logs: []*models.TaskLog{{ClusterID: fakeClusterID, SystemLogs: []string{strings.Repeat(line, 100)}}},
+	}).Return(&models.UpdateTaskResponse{}, nil)
+	fakeLocalStoreHelper.EXPECT().RecordTaskLogOffset(gomock.Any(), fakeTaskID, int64(10000)).Return(nil)
+	g.Expect(r.shipTaskLog(context.Background(), fakeTaskID)).To(gomega.Succeed())
+
+	// nothing new
+	fakeLocalStoreHelper.EXPECT().GetTask(gomock.Any(), fakeTaskID).Return(&localstore.TaskInfo{
+		Task: localstore.Task{ID: fakeTaskID}, LogOffset: utils.Point(int64(10000)),
+	}, nil)
+	g.Expect(r.shipTaskLog(context.Background(), fakeTaskID)).To(gomega.Succeed())
+
+	// processing by others
+	r.taskProcessing[fakeTaskID] = struct{}{}
+	g.Expect(r.shipTaskLog(context.Background(), fakeTaskID)).To(gomega.Succeed())
+}
+
+func TestUnshippedTaskLog(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	outputDir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(outputDir, fakeTaskID), 0755)).To(gomega.Succeed())
+	r := &Runner{opts: &Options{TaskLog: TaskLogOptions{OutputDir: outputDir, PVCName: "log-pvc", MaxSystemLogBytes: 300}}}
+
+	message, end, err := r.unshippedTaskLog(fakeTaskID, 0)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(message).To(gomega.BeEmpty())
+	g.Expect(end).To(gomega.BeZero())
+
+	content := strings.Repeat("head\n", 10) + strings.Repeat("tail\n", 40)
+	g.Expect(os.WriteFile(filepath.Join(outputDir, fakeTaskID, taskLogFileName), []byte(content), 0644)).To(gomega.Succeed())
+
+	// within limit
+	message, end, err = r.unshippedTaskLog(fakeTaskID, 150)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(message).To(gomega.Equal(strings.Repeat("tail\n", 20)))
+	g.Expect(end).To(gomega.Equal(int64(250)))
+
+	// truncated and archived
+	r.opts.TaskLog.MaxSystemLogBytes = 100
+	message, end, err = r.unshippedTaskLog(fakeTaskID, 50)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(message).To(gomega.Equal("...... 150 bytes truncated, the full log is archived at .archive/task-xxxx/app.log in pvc log-pvc ......\n" +
+		strings.Repeat("tail\n", 10)))
+	g.Expect(end).To(gomega.Equal(int64(250)))
+	archived, err := os.ReadFile(filepath.Join(outputDir, taskLogArchiveDir, fakeTaskID, taskLogFileName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(archived)).To(gomega.Equal(content))
+
+	// limit used up by logs shipped before, neither truncated nor archived again
+	g.Expect(os.RemoveAll(filepath.Join(outputDir, taskLogArchiveDir))).To(gomega.Succeed())
+	message, end, err = r.unshippedTaskLog(fakeTaskID, 150)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(message).To(gomega.BeEmpty())
+	g.Expect(end).To(gomega.Equal(int64(250)))
+	_, err = os.Stat(filepath.Join(outputDir, taskLogArchiveDir))
+	g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+}
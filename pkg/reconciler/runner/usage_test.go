@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/filelog"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/usage"
+	usagefake "github.com/GBA-BI/tes-k8s-agent/pkg/usage/fake"
+)
+
+func TestReportExecutorUsage(t *testing.T) {
+	g := gomega.NewWithT(t)
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: fakeNamespace, Name: fakeExecutorPodName, Labels: map[string]string{
+			consts.LabelTaskID: fakeTaskID, consts.LabelType: consts.ExecutorType, consts.LabelExecutorNo: "0",
+		}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("4Gi")},
+		}}}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(pod).Build()
+	fakeSource := usagefake.NewFakeSource(mockctrl)
+	fakeSource.EXPECT().Sample(gomock.Any(), fakeNamespace, gomock.Any()).
+		Return(map[string]usage.Sample{fakeExecutorPodName: {CPUCores: 1.5, MemoryBytes: 1 << 30}}, nil)
+	opts := usage.NewOptions()
+	opts.Enable = true
+	tracker := usage.NewTracker(fakeSource, fakeKubeClient, fakeNamespace, opts)
+	tracker.Collect(context.Background())
+
+	logger := filelog.NewLoggerWithWriteToFile(filepath.Join(t.TempDir(), taskLogFileName))
+	r := &Runner{usage: tracker}
+	// running executor is not reported
+	r.reportExecutorUsage(logger, pod)
+
+	pod.Status.Phase = corev1.PodSucceeded
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+		StartedAt: metav1.NewTime(start), FinishedAt: metav1.NewTime(start.Add(time.Hour)),
+	}}}}
+	r.reportExecutorUsage(logger, pod)
+	r.reportExecutorUsage(logger, pod)
+	logger.Sync()
+
+	content, err := logger.GetFileContent()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(content)).To(gomega.ContainSubstring("executor 0 pod " + fakeExecutorPodName + " usage: wall-clock 1h0m0s; " +
+		"cpu peak 1.500 avg 1.500 cores (requested 2), 1.5000 core-hours; " +
+		"memory peak 1.000 avg 1.000 GiB (requested 4.000 GiB), 1.0000 GiB-hours; 1 samples"))
+	g.Expect(string(content)).NotTo(gomega.MatchRegexp("(?s)usage.*usage"))
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
 )
 
-func (s *syncer) syncCancelingTask(ctx context.Context, task taskMinimal) error {
+func (s *Syncer) syncCancelingTask(ctx context.Context, task taskMinimal) error {
 	taskInfo, err := s.localStoreHelper.GetTask(ctx, task.id)
 	if err == nil {
 		if taskInfo.Stop != nil {
@@ -22,6 +22,9 @@ func (s *syncer) syncCancelingTask(ctx context.Context, task taskMinimal) error
 	if !errors.Is(err, localstore.ErrNotFound) {
 		return err
 	}
+	if s.claimedByOthers(task) {
+		return nil
+	}
 	if _, err := s.vetesClient.UpdateTask(ctx, &models.UpdateTaskRequest{ID: task.id, State: utils.Point(consts.TaskCanceled)}); err != nil {
 		return fmt.Errorf("failed to directly cancel task: %w", err)
 	}
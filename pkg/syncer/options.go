@@ -11,6 +11,9 @@ import (
 type Options struct {
 	Period      time.Duration `mapstructure:"period"`
 	Concurrency int           `mapstructure:"concurrency"`
+	// TagKey and TagValue filter tasks to claim when server does not schedule tasks to clusters
+	TagKey   string `mapstructure:"tagKey"`
+	TagValue string `mapstructure:"tagValue"`
 }
 
 // NewOptions ...
@@ -29,6 +32,9 @@ func (o *Options) Validate() error {
 	if o.Concurrency <= 0 {
 		return fmt.Errorf("sync concurrency %d should be positive", o.Concurrency)
 	}
+	if o.TagKey == "" && o.TagValue != "" {
+		return fmt.Errorf("sync tag value %s is set without tag key", o.TagValue)
+	}
 	return nil
 }
 
@@ -36,4 +42,6 @@ func (o *Options) Validate() error {
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.DurationVar(&o.Period, "syncer-period", o.Period, "period of sync tasks")
 	fs.IntVar(&o.Concurrency, "syncer-concurrency", o.Concurrency, "concurrency of sync tasks")
+	fs.StringVar(&o.TagKey, "syncer-tag-key", o.TagKey, "tag key of tasks to claim, only used when server does not schedule tasks to clusters")
+	fs.StringVar(&o.TagValue, "syncer-tag-value", o.TagValue, "tag value of tasks to claim, only used when server does not schedule tasks to clusters")
 }
@@ -11,10 +11,13 @@ import (
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/tracing"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
 )
 
-func (s *syncer) syncQueuedTask(ctx context.Context, task taskMinimal) (reterr error) {
+func (s *Syncer) syncQueuedTask(ctx context.Context, task taskMinimal) (reterr error) {
 	_, err := s.localStoreHelper.GetTask(ctx, task.id)
 	if err == nil {
 		return nil
@@ -23,6 +26,22 @@ func (s *syncer) syncQueuedTask(ctx context.Context, task taskMinimal) (reterr e
 		return err
 	}
 
+	if s.claimedByOthers(task) {
+		return nil
+	}
+	if !s.caps.ClusterFilter && task.clusterID == "" {
+		claimed, err := s.claimTask(ctx, task.id)
+		if err != nil || !claimed {
+			return err
+		}
+	}
+
+	// root span of the task, spans of runner are its children by trace parent in local store
+	ctx, span := tracing.StartTask(ctx, task.id)
+	defer func() {
+		tracing.EndSpan(span, reterr)
+	}()
+
 	taskFull, err := s.vetesClient.GetTask(ctx, &models.GetTaskRequest{ID: task.id, View: consts.FullView})
 	if err != nil {
 		return fmt.Errorf("failed to get full task %s: %w", task.id, err)
@@ -33,6 +52,7 @@ func (s *syncer) syncQueuedTask(ctx context.Context, task taskMinimal) (reterr e
 	}
 	taskStore := taskFullToStore(taskFull.Task)
 	taskStore.AccelerateNames = accelerateNames
+	taskStore.TraceParent = tracing.TraceParent(ctx)
 
 	defer func() {
 		if reterr == nil {
@@ -60,10 +80,9 @@ func (s *syncer) syncQueuedTask(ctx context.Context, task taskMinimal) (reterr e
 	}
 
 	if len(taskFull.Inputs) > 0 {
-		// compatible with the original filer implementation
-		inputsJSON, err := json.Marshal(map[string]interface{}{"inputs": taskFull.Inputs})
+		inputsJSON, err := marshalInputs(taskFull.Task)
 		if err != nil {
-			return fmt.Errorf("failed to marshal inputs for task %s: %w", task.id, err)
+			return err
 		}
 		if len(inputsJSON) <= s.offloadThreshold {
 			taskStore.InputsJSON = string(inputsJSON)
@@ -77,10 +96,9 @@ func (s *syncer) syncQueuedTask(ctx context.Context, task taskMinimal) (reterr e
 	}
 
 	if len(taskFull.Outputs) > 0 {
-		// compatible with the original filer implementation
-		outputsJSON, err := json.Marshal(map[string]interface{}{"outputs": taskFull.Outputs})
+		outputsJSON, err := marshalOutputs(taskFull.Task)
 		if err != nil {
-			return fmt.Errorf("failed to marshal outputs for task %s: %w", task.id, err)
+			return err
 		}
 		if len(outputsJSON) <= s.offloadThreshold {
 			taskStore.OutputsJSON = string(outputsJSON)
@@ -93,7 +111,62 @@ func (s *syncer) syncQueuedTask(ctx context.Context, task taskMinimal) (reterr e
 		}
 	}
 
-	return s.localStoreHelper.StoreTask(ctx, taskStore)
+	if err = s.localStoreHelper.StoreTask(ctx, taskStore); err != nil {
+		return err
+	}
+	metrics.TasksAdmittedTotal.Inc()
+	return nil
+}
+
+// TaskToStore converts a full task to local store like syncing a queued task, for rendering its objects.
+// Inputs and outputs are never offloaded, and accelerators are not applied.
+func TaskToStore(task *models.Task) (*localstore.Task, error) {
+	res := taskFullToStore(task)
+	if len(task.Inputs) > 0 {
+		inputsJSON, err := marshalInputs(task)
+		if err != nil {
+			return nil, err
+		}
+		res.InputsJSON = string(inputsJSON)
+	}
+	if len(task.Outputs) > 0 {
+		outputsJSON, err := marshalOutputs(task)
+		if err != nil {
+			return nil, err
+		}
+		res.OutputsJSON = string(outputsJSON)
+	}
+	return res, nil
+}
+
+// marshalInputs is compatible with the original filer implementation
+func marshalInputs(task *models.Task) ([]byte, error) {
+	res, err := json.Marshal(map[string]interface{}{"inputs": task.Inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal inputs for task %s: %w", task.ID, err)
+	}
+	return res, nil
+}
+
+// marshalOutputs is compatible with the original filer implementation
+func marshalOutputs(task *models.Task) ([]byte, error) {
+	res, err := json.Marshal(map[string]interface{}{"outputs": task.Outputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal outputs for task %s: %w", task.ID, err)
+	}
+	return res, nil
+}
+
+// claimTask sets cluster_id of the task, returns false if the task is claimed by others concurrently.
+func (s *Syncer) claimTask(ctx context.Context, taskID string) (bool, error) {
+	if _, err := s.vetesClient.UpdateTask(ctx, &models.UpdateTaskRequest{ID: taskID, ClusterID: &s.clusterID}); err != nil {
+		if errors.Is(err, vetesclient.ErrConflict) {
+			log.Infow("task is claimed by other cluster", "task", taskID)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim task %s: %w", taskID, err)
+	}
+	return true, nil
 }
 
 func externalBucketsAuthInfoToMap(authInfo []*models.ExternalBucketAuthInfo) map[string]*models.ExternalBucketAuthInfo {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
 	"github.com/panjf2000/ants/v2"
@@ -11,50 +13,90 @@ import (
 	"github.com/GBA-BI/tes-k8s-agent/pkg/accelerate"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/crontab"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/drain"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/localstore"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/offload"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
 )
 
-type syncer struct {
+// Syncer syncs tasks from vetes-api to local store periodically
+type Syncer struct {
 	vetesClient      vetesclient.Client
 	localStoreHelper localstore.Helper
 	offloadHelper    offload.Helper
 	accelerator      accelerate.Accelerator
+	drainHelper      drain.Helper
 	clusterID        string
-	concurrency      int
+	caps             *vetesclient.Capabilities
+	tagKey           string
+	tagValue         string
+	concurrency      atomic.Int64
 	offloadThreshold int // for test
 }
 
 // RegisterCrontab ...
 func RegisterCrontab(cron *crontab.Crontab, vetesClient vetesclient.Client, localStoreHelper localstore.Helper,
-	offloadHelper offload.Helper, accelerator accelerate.Accelerator, clusterID string, opts *Options) error {
-	s := &syncer{
+	offloadHelper offload.Helper, accelerator accelerate.Accelerator, drainHelper drain.Helper, clusterID string, caps *vetesclient.Capabilities,
+	opts *Options) (*Syncer, error) {
+	s := &Syncer{
 		vetesClient:      vetesClient,
 		localStoreHelper: localStoreHelper,
 		offloadHelper:    offloadHelper,
 		accelerator:      accelerator,
+		drainHelper:      drainHelper,
 		clusterID:        clusterID,
-		concurrency:      opts.Concurrency,
+		caps:             caps,
+		tagKey:           opts.TagKey,
+		tagValue:         opts.TagValue,
 		offloadThreshold: consts.OffloadThreshold,
 	}
 
-	return cron.RegisterCron(opts.Period, func() {
+	s.concurrency.Store(int64(opts.Concurrency))
+
+	if err := cron.RegisterCron(opts.Period, func() {
 		ctx := context.Background()
 		if err := s.syncTasks(ctx); err != nil {
 			log.Errorw("sync tasks failed", "err", err)
 		}
-	})
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetConcurrency changes concurrency from next sync
+func (s *Syncer) SetConcurrency(concurrency int) {
+	s.concurrency.Store(int64(concurrency))
 }
 
-func (s *syncer) syncTasks(ctx context.Context) error {
-	tasks, err := s.listTasks(ctx)
+func (s *Syncer) syncTasks(ctx context.Context) error {
+	start := time.Now()
+	defer func() {
+		metrics.SyncerCycleDurationSeconds.Observe(time.Since(start).Seconds())
+	}()
+
+	drainState, err := s.drainHelper.State(ctx)
+	if err != nil {
+		return err
+	}
+	states := []string{consts.TaskQueued, consts.TaskCanceling}
+	if drainState.Draining {
+		// stop claiming queued tasks, canceling is still synced for running tasks
+		states = []string{consts.TaskCanceling}
+		metrics.Draining.Set(1)
+	} else {
+		metrics.Draining.Set(0)
+	}
+
+	tasks, err := s.listTasks(ctx, states)
 	if err != nil {
 		return err
 	}
+	metrics.SyncerBacklog.Set(float64(len(tasks)))
 
-	taskPool, err := ants.NewPool(s.concurrency)
+	taskPool, err := ants.NewPool(int(s.concurrency.Load()))
 	if err != nil { // never
 		return fmt.Errorf("failed to initialize submission goroutine pool: %w", err)
 	}
@@ -62,7 +104,7 @@ func (s *syncer) syncTasks(ctx context.Context) error {
 
 	var wg sync.WaitGroup
 	for _, task := range tasks {
-		localTask := taskMinimal{id: task.ID, state: task.State}
+		localTask := taskMinimal{id: task.ID, state: task.State, clusterID: task.ClusterID}
 		wg.Add(1)
 		if err := taskPool.Submit(func() {
 			defer wg.Done()
@@ -79,17 +121,26 @@ func (s *syncer) syncTasks(ctx context.Context) error {
 	return nil
 }
 
-func (s *syncer) listTasks(ctx context.Context) ([]*models.Task, error) {
+func (s *Syncer) listTasks(ctx context.Context, states []string) ([]*models.Task, error) {
 	res := make([]*models.Task, 0)
 	var pageToken string
 	for {
-		resp, err := s.vetesClient.ListTasks(ctx, &models.ListTasksRequest{
-			State:     []string{consts.TaskQueued, consts.TaskCanceling},
+		req := &models.ListTasksRequest{
+			State:     states,
 			ClusterID: s.clusterID,
 			View:      consts.MinimalView,
 			PageSize:  consts.MaximumPageSize,
 			PageToken: pageToken,
-		})
+		}
+		if !s.caps.ClusterFilter {
+			// server does not schedule tasks to clusters, list tasks by tag and claim them,
+			// basic view is needed to know which cluster the task is claimed by
+			req.ClusterID = ""
+			req.View = consts.BasicView
+			req.TagKey = s.tagKey
+			req.TagValue = s.tagValue
+		}
+		resp, err := s.vetesClient.ListTasks(ctx, req)
 		if err != nil {
 			return nil, err
 		}
@@ -103,11 +154,18 @@ func (s *syncer) listTasks(ctx context.Context) ([]*models.Task, error) {
 }
 
 type taskMinimal struct {
-	id    string
-	state string
+	id        string
+	state     string
+	clusterID string
+}
+
+// claimedByOthers returns whether the task belongs to another cluster.
+// Only happens when server does not schedule tasks to clusters.
+func (s *Syncer) claimedByOthers(task taskMinimal) bool {
+	return !s.caps.ClusterFilter && task.clusterID != "" && task.clusterID != s.clusterID
 }
 
-func (s *syncer) syncTask(ctx context.Context, task taskMinimal) error {
+func (s *Syncer) syncTask(ctx context.Context, task taskMinimal) error {
 	if task.state == consts.TaskQueued {
 		return s.syncQueuedTask(ctx, task)
 	}
@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+// Options ...
+type Options struct {
+	// Endpoint is host:port of the OTLP/HTTP collector, tracing is disabled if empty
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	SampleRatio float64 `mapstructure:"sampleRatio"`
+}
+
+// NewOptions ...
+func NewOptions() *Options {
+	return &Options{
+		Insecure:    true,
+		SampleRatio: 1,
+	}
+}
+
+// Validate ...
+func (o *Options) Validate() error {
+	if o.SampleRatio < 0 || o.SampleRatio > 1 {
+		return fmt.Errorf("invalid tracing sample ratio: %f", o.SampleRatio)
+	}
+	return nil
+}
+
+// AddFlags ...
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Endpoint, "tracing-endpoint", o.Endpoint, "host:port of the OTLP/HTTP trace collector, disabled if empty")
+	fs.BoolVar(&o.Insecure, "tracing-insecure", o.Insecure, "export traces without TLS")
+	fs.Float64Var(&o.SampleRatio, "tracing-sample-ratio", o.SampleRatio, "ratio of tasks to trace")
+}
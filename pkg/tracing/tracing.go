@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/version"
+)
+
+const (
+	instrumentationName = "github.com/GBA-BI/tes-k8s-agent"
+	serviceName         = "vetes-k8s-agent"
+
+	// AttrTaskID is the span attribute of task id
+	AttrTaskID = attribute.Key("task.id")
+
+	traceParentKey = "traceparent"
+)
+
+// propagator is used for the TES API, pods and local store, whether tracing is enabled or not
+var propagator = propagation.TraceContext{}
+
+func init() {
+	otel.SetTextMapPropagator(propagator)
+}
+
+// Setup registers the global tracer provider exporting to opts.Endpoint, and returns a function to flush and stop it.
+// Spans are not recorded if endpoint is empty.
+func Setup(ctx context.Context, opts *Options) (shutdown func(context.Context) error, err error) {
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporterOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(version.Get().Version),
+		)),
+		// sampled by root span of task, so that a task is traced entirely or not at all
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span from the global tracer provider
+func Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, spanName, opts...)
+}
+
+// StartTask starts the root span of a task
+func StartTask(ctx context.Context, taskID string) (context.Context, trace.Span) {
+	return Start(ctx, "task", trace.WithNewRoot(), trace.WithAttributes(AttrTaskID.String(taskID)))
+}
+
+// EndSpan records err if not nil and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceParent returns the W3C traceparent of the span in ctx, empty if no valid span
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get(traceParentKey)
+}
+
+// InjectHeader propagates the span in ctx to http headers
+func InjectHeader(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// ContextWithTraceParent returns ctx with the remote span of traceParent as parent.
+// ctx is returned if traceParent is empty or ctx already has a span.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" || trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{traceParentKey: traceParent})
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTaskSpans(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	defer func() {
+		g.Expect(provider.Shutdown(context.Background())).To(gomega.Succeed())
+	}()
+
+	// syncer
+	ctx, root := StartTask(context.Background(), "task-01")
+	traceParent := TraceParent(ctx)
+	g.Expect(traceParent).To(gomega.MatchRegexp(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`))
+	EndSpan(root, nil)
+
+	// runner in another reconcile
+	ctx = ContextWithTraceParent(context.Background(), traceParent)
+	ctx, stage := Start(ctx, "stage Running")
+	// existing span is not overridden by trace parent
+	g.Expect(ContextWithTraceParent(ctx, traceParent)).To(gomega.Equal(ctx))
+	header := http.Header{}
+	InjectHeader(ctx, header)
+	g.Expect(header.Get(traceParentKey)).To(gomega.Equal(TraceParent(ctx)))
+	EndSpan(stage, context.Canceled)
+
+	spans := exporter.GetSpans()
+	g.Expect(spans).To(gomega.HaveLen(2))
+	g.Expect(spans[0].Name).To(gomega.Equal("task"))
+	g.Expect(spans[0].Attributes).To(gomega.ContainElement(AttrTaskID.String("task-01")))
+	g.Expect(spans[1].Name).To(gomega.Equal("stage Running"))
+	g.Expect(spans[1].Parent.SpanID()).To(gomega.Equal(spans[0].SpanContext.SpanID()))
+	g.Expect(spans[1].SpanContext.TraceID()).To(gomega.Equal(spans[0].SpanContext.TraceID()))
+	g.Expect(spans[1].Events).To(gomega.HaveLen(1)) // recorded error
+}
+
+func TestDisabled(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	shutdown, err := Setup(context.Background(), NewOptions())
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(shutdown(context.Background())).To(gomega.Succeed())
+	g.Expect(ContextWithTraceParent(context.Background(), "")).To(gomega.Equal(context.Background()))
+}
@@ -0,0 +1,52 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/usage/source.go
+
+// Package fake is a generated GoMock package.
+package fake
+
+import (
+	context "context"
+	reflect "reflect"
+
+	usage "github.com/GBA-BI/tes-k8s-agent/pkg/usage"
+	gomock "github.com/golang/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+)
+
+// FakeSource is a mock of Source interface.
+type FakeSource struct {
+	ctrl     *gomock.Controller
+	recorder *FakeSourceMockRecorder
+}
+
+// FakeSourceMockRecorder is the mock recorder for FakeSource.
+type FakeSourceMockRecorder struct {
+	mock *FakeSource
+}
+
+// NewFakeSource creates a new mock instance.
+func NewFakeSource(ctrl *gomock.Controller) *FakeSource {
+	mock := &FakeSource{ctrl: ctrl}
+	mock.recorder = &FakeSourceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *FakeSource) EXPECT() *FakeSourceMockRecorder {
+	return m.recorder
+}
+
+// Sample mocks base method.
+func (m *FakeSource) Sample(ctx context.Context, namespace string, pods []*v1.Pod) (map[string]usage.Sample, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sample", ctx, namespace, pods)
+	ret0, _ := ret[0].(map[string]usage.Sample)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sample indicates an expected call of Sample.
+func (mr *FakeSourceMockRecorder) Sample(ctx, namespace, pods interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sample", reflect.TypeOf((*FakeSource)(nil).Sample), ctx, namespace, pods)
+}
@@ -0,0 +1,52 @@
+package usage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// usage sources
+const (
+	MetricsServerSource = "metrics-server"
+	KubeletSource       = "kubelet"
+)
+
+// Options ...
+type Options struct {
+	Enable bool `mapstructure:"enable"`
+	// Source is metrics-server, which serves metrics.k8s.io, or kubelet, which is read by node proxy of summary api
+	Source         string        `mapstructure:"source"`
+	SampleInterval time.Duration `mapstructure:"sampleInterval"`
+}
+
+// NewOptions ...
+func NewOptions() *Options {
+	return &Options{
+		Enable:         false,
+		Source:         MetricsServerSource,
+		SampleInterval: 30 * time.Second,
+	}
+}
+
+// Validate ...
+func (o *Options) Validate() error {
+	if !o.Enable {
+		return nil
+	}
+	if o.Source != MetricsServerSource && o.Source != KubeletSource {
+		return fmt.Errorf("invalid usage source: %s", o.Source)
+	}
+	if o.SampleInterval < time.Second {
+		return fmt.Errorf("usage sample interval should be at least 1s: %s", o.SampleInterval)
+	}
+	return nil
+}
+
+// AddFlags ...
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.Enable, "usage-enable", o.Enable, "sample resource usage of executors and report it in task system logs")
+	fs.StringVar(&o.Source, "usage-source", o.Source, "source of resource usage, metrics-server or kubelet")
+	fs.DurationVar(&o.SampleInterval, "usage-sample-interval", o.SampleInterval, "interval of sampling resource usage of executors")
+}
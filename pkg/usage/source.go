@@ -0,0 +1,146 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Sample is resource usage of a pod at a moment
+type Sample struct {
+	CPUCores    float64
+	MemoryBytes float64
+}
+
+// Source samples resource usage of running pods
+type Source interface {
+	// Sample returns usage of pods by pod name, pods without metrics yet are omitted
+	Sample(ctx context.Context, namespace string, pods []*corev1.Pod) (map[string]Sample, error)
+}
+
+// NewSource ...
+func NewSource(kubeClientNative kubernetes.Interface, opts *Options) (Source, error) {
+	switch opts.Source {
+	case MetricsServerSource:
+		return &metricsServerSource{kubeClientNative: kubeClientNative}, nil
+	case KubeletSource:
+		return &kubeletSource{kubeClientNative: kubeClientNative}, nil
+	default:
+		return nil, fmt.Errorf("unsupported usage source: %s", opts.Source)
+	}
+}
+
+// metricsServerSource lists metrics.k8s.io pod metrics of the namespace in one request
+type metricsServerSource struct {
+	kubeClientNative kubernetes.Interface
+}
+
+// podMetricsList is the subset of metrics.k8s.io/v1beta1 PodMetricsList
+type podMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Containers []struct {
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+func (s *metricsServerSource) Sample(ctx context.Context, namespace string, pods []*corev1.Pod) (map[string]Sample, error) {
+	content, err := s.kubeClientNative.CoreV1().RESTClient().Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", namespace, "pods").DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod metrics: %w", err)
+	}
+	list := &podMetricsList{}
+	if err = json.Unmarshal(content, list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pod metrics: %w", err)
+	}
+	wanted := podNames(pods)
+	res := make(map[string]Sample, len(pods))
+	for _, item := range list.Items {
+		if _, ok := wanted[item.Metadata.Name]; !ok {
+			continue
+		}
+		var sample Sample
+		for _, container := range item.Containers {
+			sample.CPUCores += quantityValue(container.Usage[corev1.ResourceCPU])
+			sample.MemoryBytes += quantityValue(container.Usage[corev1.ResourceMemory])
+		}
+		res[item.Metadata.Name] = sample
+	}
+	return res, nil
+}
+
+// kubeletSource reads the summary api of each node running the pods through node proxy
+type kubeletSource struct {
+	kubeClientNative kubernetes.Interface
+}
+
+// statsSummary is the subset of kubelet stats/summary
+type statsSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		CPU *struct {
+			UsageNanoCores *uint64 `json:"usageNanoCores"`
+		} `json:"cpu"`
+		Memory *struct {
+			WorkingSetBytes *uint64 `json:"workingSetBytes"`
+		} `json:"memory"`
+	} `json:"pods"`
+}
+
+func (s *kubeletSource) Sample(ctx context.Context, namespace string, pods []*corev1.Pod) (map[string]Sample, error) {
+	nodes := make(map[string]struct{})
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			nodes[pod.Spec.NodeName] = struct{}{}
+		}
+	}
+	wanted := podNames(pods)
+	res := make(map[string]Sample, len(pods))
+	for node := range nodes {
+		content, err := s.kubeClientNative.CoreV1().RESTClient().Get().
+			Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").DoRaw(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stats summary of node %s: %w", node, err)
+		}
+		summary := &statsSummary{}
+		if err = json.Unmarshal(content, summary); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stats summary of node %s: %w", node, err)
+		}
+		for _, pod := range summary.Pods {
+			if _, ok := wanted[pod.PodRef.Name]; !ok || pod.PodRef.Namespace != namespace {
+				continue
+			}
+			if pod.CPU == nil || pod.CPU.UsageNanoCores == nil || pod.Memory == nil || pod.Memory.WorkingSetBytes == nil {
+				continue
+			}
+			res[pod.PodRef.Name] = Sample{
+				CPUCores:    float64(*pod.CPU.UsageNanoCores) / 1e9,
+				MemoryBytes: float64(*pod.Memory.WorkingSetBytes),
+			}
+		}
+	}
+	return res, nil
+}
+
+func podNames(pods []*corev1.Pod) map[string]struct{} {
+	res := make(map[string]struct{}, len(pods))
+	for _, pod := range pods {
+		res[pod.Name] = struct{}{}
+	}
+	return res
+}
+
+func quantityValue(quantity resource.Quantity) float64 {
+	return quantity.AsApproximateFloat64()
+}
@@ -0,0 +1,164 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/crontab"
+)
+
+// staleUsage is how long usage of a pod which is no longer sampled is kept for reporting
+const staleUsage = time.Hour
+
+const gib = 1 << 30
+
+// Usage is the accumulated resource usage of a pod
+type Usage struct {
+	Samples     int
+	CPUPeak     float64
+	CPUSum      float64
+	MemoryPeak  float64
+	MemorySum   float64
+	lastSampled time.Time
+}
+
+func (u *Usage) add(sample Sample, now time.Time) {
+	u.Samples++
+	u.CPUSum += sample.CPUCores
+	u.MemorySum += sample.MemoryBytes
+	if sample.CPUCores > u.CPUPeak {
+		u.CPUPeak = sample.CPUCores
+	}
+	if sample.MemoryBytes > u.MemoryPeak {
+		u.MemoryPeak = sample.MemoryBytes
+	}
+	u.lastSampled = now
+}
+
+// CPUAverage is average cpu cores
+func (u *Usage) CPUAverage() float64 {
+	return u.CPUSum / float64(u.Samples)
+}
+
+// MemoryAverage is average memory bytes
+func (u *Usage) MemoryAverage() float64 {
+	return u.MemorySum / float64(u.Samples)
+}
+
+// Summary formats usage with requests of the pod for right-sizing, resource-hours are average usage over wall-clock time
+func (u *Usage) Summary(wallTime time.Duration, requests corev1.ResourceList) string {
+	hours := wallTime.Hours()
+	return fmt.Sprintf("wall-clock %s; cpu peak %.3f avg %.3f cores (requested %s), %.4f core-hours; "+
+		"memory peak %.3f avg %.3f GiB (requested %s), %.4f GiB-hours; %d samples",
+		wallTime.Round(time.Second), u.CPUPeak, u.CPUAverage(), requestString(requests, corev1.ResourceCPU), u.CPUAverage()*hours,
+		u.MemoryPeak/gib, u.MemoryAverage()/gib, requestString(requests, corev1.ResourceMemory), u.MemoryAverage()/gib*hours, u.Samples)
+}
+
+func requestString(requests corev1.ResourceList, name corev1.ResourceName) string {
+	quantity, ok := requests[name]
+	if !ok {
+		return "none"
+	}
+	if name == corev1.ResourceMemory {
+		return fmt.Sprintf("%.3f GiB", quantity.AsApproximateFloat64()/gib)
+	}
+	return quantity.String()
+}
+
+// Tracker samples resource usage of running executor pods periodically. Usages are kept in memory of the leader,
+// so executors running across leader changes are partially sampled. A nil Tracker samples nothing.
+type Tracker struct {
+	source     Source
+	kubeClient ctrlclient.Client
+	namespace  string
+
+	lock   sync.Mutex
+	usages map[string]*Usage
+}
+
+// NewTracker returns nil if usage is disabled
+func NewTracker(source Source, kubeClient ctrlclient.Client, namespace string, opts *Options) *Tracker {
+	if !opts.Enable {
+		return nil
+	}
+	return &Tracker{
+		source:     source,
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		usages:     make(map[string]*Usage),
+	}
+}
+
+// RegisterCrontab ...
+func RegisterCrontab(cron *crontab.Crontab, tracker *Tracker, opts *Options) error {
+	if tracker == nil {
+		return nil
+	}
+	return cron.RegisterCron(opts.SampleInterval, func() {
+		tracker.Collect(context.Background())
+	})
+}
+
+// Collect samples usage of running executor pods once
+func (t *Tracker) Collect(ctx context.Context) {
+	podList := &corev1.PodList{}
+	if err := t.kubeClient.List(ctx, podList, ctrlclient.InNamespace(t.namespace),
+		ctrlclient.MatchingLabels{consts.LabelType: consts.ExecutorType}); err != nil {
+		log.Warnw("failed to list executor pods for usage", "err", err)
+		return
+	}
+	pods := make([]*corev1.Pod, 0, len(podList.Items))
+	for index := range podList.Items {
+		if podList.Items[index].Status.Phase == corev1.PodRunning {
+			pods = append(pods, &podList.Items[index])
+		}
+	}
+
+	var samples map[string]Sample
+	if len(pods) > 0 {
+		var err error
+		if samples, err = t.source.Sample(ctx, t.namespace, pods); err != nil {
+			log.Warnw("failed to sample usage of executor pods", "err", err)
+			return
+		}
+	}
+
+	now := time.Now()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for podName, sample := range samples {
+		usage, ok := t.usages[podName]
+		if !ok {
+			usage = &Usage{}
+			t.usages[podName] = usage
+		}
+		usage.add(sample, now)
+	}
+	for podName, usage := range t.usages {
+		if now.Sub(usage.lastSampled) > staleUsage {
+			delete(t.usages, podName)
+		}
+	}
+}
+
+// Pop returns and forgets usage of the pod, nil if it is never sampled
+func (t *Tracker) Pop(podName string) *Usage {
+	if t == nil {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	usage, ok := t.usages[podName]
+	if !ok {
+		return nil
+	}
+	delete(t.usages, podName)
+	return usage
+}
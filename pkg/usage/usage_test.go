@@ -0,0 +1,72 @@
+package usage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/usage"
+	usagefake "github.com/GBA-BI/tes-k8s-agent/pkg/usage/fake"
+)
+
+const fakeNamespace = "vetes"
+
+func fakePod(name, podType string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: fakeNamespace, Name: name, Labels: map[string]string{consts.LabelType: podType}},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestTracker(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeKubeClient := ctrlfake.NewClientBuilder().WithObjects(
+		fakePod("executor-running", consts.ExecutorType, corev1.PodRunning),
+		fakePod("executor-pending", consts.ExecutorType, corev1.PodPending),
+		fakePod("filer-running", "inputs-filer", corev1.PodRunning),
+	).Build()
+
+	fakeSource := usagefake.NewFakeSource(ctrl)
+	samples := []map[string]usage.Sample{
+		{"executor-running": {CPUCores: 1, MemoryBytes: 1 << 30}},
+		{"executor-running": {CPUCores: 3, MemoryBytes: 3 << 30}},
+	}
+	fakeSource.EXPECT().Sample(gomock.Any(), fakeNamespace, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, pods []*corev1.Pod) (map[string]usage.Sample, error) {
+			g.Expect(pods).To(gomega.HaveLen(1))
+			g.Expect(pods[0].Name).To(gomega.Equal("executor-running"))
+			res := samples[0]
+			samples = samples[1:]
+			return res, nil
+		}).Times(2)
+
+	g.Expect(usage.NewTracker(fakeSource, fakeKubeClient, fakeNamespace, usage.NewOptions())).To(gomega.BeNil())
+	opts := usage.NewOptions()
+	opts.Enable = true
+	tracker := usage.NewTracker(fakeSource, fakeKubeClient, fakeNamespace, opts)
+	tracker.Collect(context.Background())
+	tracker.Collect(context.Background())
+
+	g.Expect(tracker.Pop("executor-pending")).To(gomega.BeNil())
+	res := tracker.Pop("executor-running")
+	g.Expect(res).NotTo(gomega.BeNil())
+	g.Expect(res.Samples).To(gomega.Equal(2))
+	g.Expect(res.CPUPeak).To(gomega.Equal(3.0))
+	g.Expect(res.CPUAverage()).To(gomega.Equal(2.0))
+	g.Expect(res.MemoryAverage()).To(gomega.Equal(float64(2 << 30)))
+	g.Expect(res.Summary(2*time.Hour, corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")})).To(gomega.Equal(
+		"wall-clock 2h0m0s; cpu peak 3.000 avg 2.000 cores (requested 4), 4.0000 core-hours; " +
+			"memory peak 3.000 avg 2.000 GiB (requested none), 4.0000 GiB-hours; 2 samples"))
+	g.Expect(tracker.Pop("executor-running")).To(gomega.BeNil())
+}
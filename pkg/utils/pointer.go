@@ -4,3 +4,12 @@ package utils
 func Point[T any](i T) *T {
 	return &i
 }
+
+// Value returns the value of a point, or zero value if it is nil
+func Value[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
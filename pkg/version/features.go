@@ -0,0 +1,30 @@
+package version
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	featuresLock sync.RWMutex
+	features     = make(map[string]struct{})
+)
+
+// EnableFeature records a feature enabled in the running agent.
+func EnableFeature(name string) {
+	featuresLock.Lock()
+	defer featuresLock.Unlock()
+	features[name] = struct{}{}
+}
+
+// Features returns sorted names of enabled features.
+func Features() []string {
+	featuresLock.RLock()
+	defer featuresLock.RUnlock()
+	res := make([]string, 0, len(features))
+	for name := range features {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,57 @@
+package vetesclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
+)
+
+// Capabilities describes which veTES extensions the TES server supports.
+type Capabilities struct {
+	// ClusterFilter means server schedules tasks to clusters, and ListTasks can filter by cluster_id.
+	// Otherwise, agent should claim tasks by itself.
+	ClusterFilter bool
+	// ClaimTask means agent can set cluster_id, state and logs of a task.
+	ClaimTask bool
+	// ClusterReport means server accepts cluster capacity reports.
+	ClusterReport bool
+}
+
+// ProbeCapabilities returns all capabilities for veTES, and probes service-info for a standard TES server.
+func ProbeCapabilities(ctx context.Context, client Client, opts *Options) (*Capabilities, error) {
+	if opts.Mode != consts.StandardTESAPIMode {
+		return &Capabilities{
+			ClusterFilter: true,
+			ClaimTask:     true,
+			ClusterReport: true,
+		}, nil
+	}
+
+	resp, err := client.GetServiceInfo(ctx, &models.GetServiceInfoRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service-info: %w", err)
+	}
+	if resp.ServiceInfo == nil || resp.Type == nil || resp.Type.Artifact != "tes" {
+		return nil, errors.New("server is not a GA4GH TES service")
+	}
+
+	res := &Capabilities{}
+	for _, extension := range resp.Extensions {
+		switch extension {
+		case consts.ClusterFilterExtension:
+			res.ClusterFilter = true
+		case consts.ClaimTaskExtension:
+			res.ClaimTask = true
+		case consts.ClusterReportExtension:
+			res.ClusterReport = true
+		}
+	}
+	// agent cannot report task state and logs without claim extension
+	if !res.ClaimTask {
+		return nil, fmt.Errorf("TES service %s does not support %s extension", resp.ID, consts.ClaimTaskExtension)
+	}
+	return res, nil
+}
@@ -11,7 +11,12 @@ import (
 	"net/url"
 	"reflect"
 	"strconv"
+	"time"
 
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/tracing"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
 )
 
@@ -20,11 +25,22 @@ const (
 	otherAPIPrefix = "/api/v1"
 )
 
+// endpoint names in metrics
+const (
+	endpointListTasks      = "list_tasks"
+	endpointGetTask        = "get_task"
+	endpointUpdateTask     = "update_task"
+	endpointPutCluster     = "put_cluster"
+	endpointGetServiceInfo = "get_service_info"
+)
+
 var (
 	// ErrNotFound ...
 	ErrNotFound = errors.New("not found")
 	// ErrBadRequest ...
 	ErrBadRequest = errors.New("bad request")
+	// ErrConflict ...
+	ErrConflict = errors.New("conflict")
 )
 
 // Client ...
@@ -34,6 +50,8 @@ type Client interface {
 	UpdateTask(ctx context.Context, req *models.UpdateTaskRequest) (*models.UpdateTaskResponse, error)
 
 	PutCluster(ctx context.Context, req *models.PutClusterRequest) (*models.PutClusterResponse, error)
+
+	GetServiceInfo(ctx context.Context, req *models.GetServiceInfoRequest) (*models.GetServiceInfoResponse, error)
 }
 
 type impl struct {
@@ -52,7 +70,7 @@ var _ Client = (*impl)(nil)
 // ListTasks ...
 func (i *impl) ListTasks(ctx context.Context, req *models.ListTasksRequest) (*models.ListTasksResponse, error) {
 	resp := new(models.ListTasksResponse)
-	if err := i.doRequest(ctx, http.MethodGet, fmt.Sprintf("%s%s/tasks", i.endpoint, ga4ghAPIPrefix), req, resp); err != nil {
+	if err := i.doRequest(ctx, endpointListTasks, http.MethodGet, fmt.Sprintf("%s%s/tasks", i.endpoint, ga4ghAPIPrefix), req, resp); err != nil {
 		return nil, err
 	}
 	return resp, nil
@@ -61,7 +79,7 @@ func (i *impl) ListTasks(ctx context.Context, req *models.ListTasksRequest) (*mo
 // GetTask ...
 func (i *impl) GetTask(ctx context.Context, req *models.GetTaskRequest) (*models.GetTaskResponse, error) {
 	resp := new(models.GetTaskResponse)
-	if err := i.doRequest(ctx, http.MethodGet, fmt.Sprintf("%s%s/tasks/%s", i.endpoint, ga4ghAPIPrefix, req.ID), req, resp); err != nil {
+	if err := i.doRequest(ctx, endpointGetTask, http.MethodGet, fmt.Sprintf("%s%s/tasks/%s", i.endpoint, ga4ghAPIPrefix, req.ID), req, resp); err != nil {
 		return nil, err
 	}
 	return resp, nil
@@ -70,7 +88,7 @@ func (i *impl) GetTask(ctx context.Context, req *models.GetTaskRequest) (*models
 // UpdateTask ...
 func (i *impl) UpdateTask(ctx context.Context, req *models.UpdateTaskRequest) (*models.UpdateTaskResponse, error) {
 	resp := new(models.UpdateTaskResponse)
-	if err := i.doRequest(ctx, http.MethodPatch, fmt.Sprintf("%s%s/tasks/%s", i.endpoint, otherAPIPrefix, req.ID), req, resp); err != nil {
+	if err := i.doRequest(ctx, endpointUpdateTask, http.MethodPatch, fmt.Sprintf("%s%s/tasks/%s", i.endpoint, otherAPIPrefix, req.ID), req, resp); err != nil {
 		return nil, err
 	}
 	return resp, nil
@@ -79,18 +97,44 @@ func (i *impl) UpdateTask(ctx context.Context, req *models.UpdateTaskRequest) (*
 // PutCluster ...
 func (i *impl) PutCluster(ctx context.Context, req *models.PutClusterRequest) (*models.PutClusterResponse, error) {
 	resp := new(models.PutClusterResponse)
-	if err := i.doRequest(ctx, http.MethodPut, fmt.Sprintf("%s%s/clusters/%s", i.endpoint, otherAPIPrefix, req.ID), req, resp); err != nil {
+	if err := i.doRequest(ctx, endpointPutCluster, http.MethodPut, fmt.Sprintf("%s%s/clusters/%s", i.endpoint, otherAPIPrefix, req.ID), req, resp); err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
-func (i *impl) doRequest(ctx context.Context, method, url string, req, resp interface{}) error {
+// GetServiceInfo ...
+func (i *impl) GetServiceInfo(ctx context.Context, req *models.GetServiceInfoRequest) (*models.GetServiceInfoResponse, error) {
+	resp := new(models.GetServiceInfoResponse)
+	if err := i.doRequest(ctx, endpointGetServiceInfo, http.MethodGet, fmt.Sprintf("%s%s/service-info", i.endpoint, ga4ghAPIPrefix), req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// doRequest observes latency and errors of the request by endpoint, which is not the url to bound cardinality
+func (i *impl) doRequest(ctx context.Context, endpoint, method, url string, req, resp interface{}) (reterr error) {
+	ctx, span := tracing.Start(ctx, "vetes-api "+endpoint, trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	code := ""
+	defer func() {
+		tracing.EndSpan(span, reterr)
+		metrics.VeTESClientRequestDurationSeconds.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+		if reterr == nil {
+			return
+		}
+		if code == "" {
+			code = "error"
+		}
+		metrics.VeTESClientErrorsTotal.WithLabelValues(endpoint, code).Inc()
+	}()
+
 	request, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return err
 	}
 	request.Header.Add("Accept", "application/json")
+	tracing.InjectHeader(ctx, request.Header)
 
 	query, err := parseQuery(req)
 	if err != nil {
@@ -115,12 +159,15 @@ func (i *impl) doRequest(ctx context.Context, method, url string, req, resp inte
 	defer response.Body.Close()
 
 	if response.StatusCode > 399 {
+		code = strconv.Itoa(response.StatusCode)
 		message, _ := io.ReadAll(response.Body)
 		switch response.StatusCode {
 		case http.StatusBadRequest:
 			return fmt.Errorf("%s: %w", message, ErrBadRequest)
 		case http.StatusNotFound:
 			return fmt.Errorf("%s: %w", message, ErrNotFound)
+		case http.StatusConflict:
+			return fmt.Errorf("%s: %w", message, ErrConflict)
 		default:
 			return fmt.Errorf("%d: %s", response.StatusCode, message)
 		}
@@ -9,8 +9,10 @@ import (
 	"github.com/jarcoal/httpmock"
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
+	"github.com/GBA-BI/tes-k8s-agent/pkg/metrics"
 	"github.com/GBA-BI/tes-k8s-agent/pkg/vetesclient/models"
 )
 
@@ -92,3 +94,39 @@ var _ = ginkgo.It("PutCluster", func() {
 	_, err := fakeClient.PutCluster(context.Background(), &models.PutClusterRequest{ID: fakeClusterID})
 	gomega.Expect(err).NotTo(gomega.HaveOccurred())
 })
+
+var _ = ginkgo.It("UpdateTask conflict", func() {
+	responder := httpmock.NewStringResponder(409, "task is claimed")
+	httpmock.RegisterResponder(http.MethodPatch, fmt.Sprintf("%s%s/tasks/%s", fakeEndpoint, otherAPIPrefix, fakeTaskID), responder)
+	_, err := fakeClient.UpdateTask(context.Background(), &models.UpdateTaskRequest{ID: fakeTaskID, ClusterID: &fakeClusterID})
+	gomega.Expect(err).To(gomega.MatchError(ErrConflict))
+})
+
+var _ = ginkgo.It("GetServiceInfo", func() {
+	fakeResp := &models.GetServiceInfoResponse{ServiceInfo: &models.ServiceInfo{
+		ID:         "tes-xxxx",
+		Name:       "tes",
+		Type:       &models.ServiceInfoType{Group: "org.ga4gh", Artifact: "tes", Version: "1.1.0"},
+		Extensions: []string{consts.ClaimTaskExtension},
+	}}
+	responder, _ := httpmock.NewJsonResponder(200, fakeResp)
+	httpmock.RegisterResponder(http.MethodGet, fmt.Sprintf("%s%s/service-info", fakeEndpoint, ga4ghAPIPrefix), responder)
+	resp, err := fakeClient.GetServiceInfo(context.Background(), &models.GetServiceInfoRequest{})
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	gomega.Expect(resp).To(gomega.BeEquivalentTo(fakeResp))
+
+	caps, err := ProbeCapabilities(context.Background(), fakeClient, &Options{Mode: consts.StandardTESAPIMode})
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	gomega.Expect(caps).To(gomega.BeEquivalentTo(&Capabilities{ClaimTask: true}))
+})
+
+var _ = ginkgo.It("Metrics", func() {
+	errCounter := metrics.VeTESClientErrorsTotal.WithLabelValues(endpointGetTask, "404")
+	before := testutil.ToFloat64(errCounter)
+	responder := httpmock.NewStringResponder(http.StatusNotFound, "task not found")
+	httpmock.RegisterResponder(http.MethodGet, fmt.Sprintf("%s%s/tasks/%s", fakeEndpoint, ga4ghAPIPrefix, fakeTaskID), responder)
+	_, err := fakeClient.GetTask(context.Background(), &models.GetTaskRequest{ID: fakeTaskID})
+	gomega.Expect(err).To(gomega.MatchError(ErrNotFound))
+	gomega.Expect(testutil.ToFloat64(errCounter)).To(gomega.Equal(before + 1))
+	gomega.Expect(testutil.CollectAndCount(metrics.VeTESClientRequestDurationSeconds)).To(gomega.BeNumerically(">", 0))
+})
@@ -35,19 +35,19 @@ func (m *FakeClient) EXPECT() *FakeClientMockRecorder {
 	return m.recorder
 }
 
-// GetTOSSecret mocks base method.
-func (m *FakeClient) GetTOSSecret(ctx context.Context, req *models.GetTOSSecretRequest) (*models.GetTOSSecretResponse, error) {
+// GetServiceInfo mocks base method.
+func (m *FakeClient) GetServiceInfo(ctx context.Context, req *models.GetServiceInfoRequest) (*models.GetServiceInfoResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTOSSecret", ctx, req)
-	ret0, _ := ret[0].(*models.GetTOSSecretResponse)
+	ret := m.ctrl.Call(m, "GetServiceInfo", ctx, req)
+	ret0, _ := ret[0].(*models.GetServiceInfoResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetTOSSecret indicates an expected call of GetTOSSecret.
-func (mr *FakeClientMockRecorder) GetTOSSecret(ctx, req interface{}) *gomock.Call {
+// GetServiceInfo indicates an expected call of GetServiceInfo.
+func (mr *FakeClientMockRecorder) GetServiceInfo(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOSSecret", reflect.TypeOf((*FakeClient)(nil).GetTOSSecret), ctx, req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceInfo", reflect.TypeOf((*FakeClient)(nil).GetServiceInfo), ctx, req)
 }
 
 // GetTask mocks base method.
@@ -5,6 +5,8 @@ type PutClusterRequest struct {
 	ID       string    `path:"id" json:"-"`
 	Capacity *Capacity `json:"capacity,omitempty"`
 	Limits   *Limits   `json:"limits,omitempty"`
+	Usage    *Usage    `json:"usage,omitempty"`
+	Agent    *Agent    `json:"agent,omitempty"`
 }
 
 // PutClusterResponse ...
@@ -35,3 +37,22 @@ type Limits struct {
 type GPULimit struct {
 	GPU map[string]float64 `json:"gpu,omitempty"`
 }
+
+// Usage ...
+type Usage struct {
+	RunningTasks int                `json:"running_tasks"`
+	PendingTasks int                `json:"pending_tasks"`
+	CPUCores     float64            `json:"cpu_cores"`
+	RamGB        float64            `json:"ram_gb"` // nolint
+	DiskGB       float64            `json:"disk_gb"`
+	GPU          map[string]float64 `json:"gpu,omitempty"`
+	// OldestPendingSeconds is the age of the oldest pending pod
+	OldestPendingSeconds int64 `json:"oldest_pending_seconds"`
+}
+
+// Agent ...
+type Agent struct {
+	Version   string   `json:"version,omitempty"`
+	GitCommit string   `json:"git_commit,omitempty"`
+	Features  []string `json:"features,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// GetServiceInfoRequest ...
+type GetServiceInfoRequest struct{}
+
+// GetServiceInfoResponse ...
+type GetServiceInfoResponse struct {
+	*ServiceInfo `json:",inline"`
+}
+
+// ServiceInfo ...
+type ServiceInfo struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Type        *ServiceInfoType `json:"type,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Version     string           `json:"version,omitempty"`
+	Storage     []string         `json:"storage,omitempty"`
+	// Extensions is not defined in GA4GH TES, servers advertise supported veTES extensions by it
+	Extensions []string `json:"extensions,omitempty"`
+}
+
+// ServiceInfoType ...
+type ServiceInfoType struct {
+	Group    string `json:"group"`
+	Artifact string `json:"artifact"`
+	Version  string `json:"version"`
+}
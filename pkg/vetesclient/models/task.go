@@ -9,6 +9,8 @@ type ListTasksRequest struct {
 	View           string   `query:"view"`
 	PageSize       int      `query:"page_size"`
 	PageToken      string   `query:"page_token"`
+	TagKey         string   `query:"tag_key"`
+	TagValue       string   `query:"tag_value"`
 }
 
 // ListTasksResponse ...
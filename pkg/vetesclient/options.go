@@ -1,15 +1,21 @@
 package vetesclient
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/pflag"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/consts"
 )
 
 // Options ...
 type Options struct {
 	Endpoint string        `mapstructure:"endpoint"`
 	Timeout  time.Duration `mapstructure:"timeout"`
+	// Mode is veTES or standard. In standard mode, the agent talks to a plain GA4GH TES server,
+	// and veTES extensions are probed from service-info.
+	Mode string `mapstructure:"mode"`
 }
 
 // NewOptions ...
@@ -17,11 +23,17 @@ func NewOptions() *Options {
 	return &Options{
 		Endpoint: "http://vetes-api.vetes-system:8080",
 		Timeout:  10 * time.Minute, // for huge inputs/outputs
+		Mode:     consts.VeTESAPIMode,
 	}
 }
 
 // Validate ...
 func (o *Options) Validate() error {
+	switch o.Mode {
+	case consts.VeTESAPIMode, consts.StandardTESAPIMode:
+	default:
+		return fmt.Errorf("invalid vetes-client mode: %s", o.Mode)
+	}
 	return nil
 }
 
@@ -29,4 +41,5 @@ func (o *Options) Validate() error {
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.Endpoint, "vetes-client-endpoint", o.Endpoint, "endpoint of the vetes-client")
 	fs.DurationVar(&o.Timeout, "vetes-client-timeout", o.Timeout, "timeout of the vetes-client")
+	fs.StringVar(&o.Mode, "vetes-client-mode", o.Mode, "mode of the vetes-client, vetes or standard")
 }
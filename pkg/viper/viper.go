@@ -16,6 +16,9 @@ const ConfigFlagName = "config"
 
 var cfgFile string
 
+// configFileUsed is the config file found by LoadConfig, which is read again by ReloadConfig
+var configFileUsed string
+
 func init() {
 	pflag.StringVarP(&cfgFile, ConfigFlagName, "c", cfgFile, "Read configuration from specified `FILE`, "+
 		"support JSON, YAML formats.")
@@ -23,8 +26,7 @@ func init() {
 
 // LoadConfig ...
 func LoadConfig(conf interface{}) error {
-	// to avoid split podLabels/podAnnotations key
-	v := viper.NewWithOptions(viper.KeyDelimiter("::"))
+	v := newViper()
 
 	v.SetConfigName("config") // name of config file (without extension)
 	if cfgFile != "" {
@@ -39,16 +41,70 @@ func LoadConfig(conf interface{}) error {
 	v.AddConfigPath(".")
 	v.AddConfigPath("$HOME")
 
-	v.SetEnvKeyReplacer(strings.NewReplacer("::", "_"))
-	v.AutomaticEnv() // read in environment variables that match
-
 	// If a config file is found, read it in.
 	if err := v.ReadInConfig(); err != nil {
 		return err
 	}
 
-	fmt.Println("Using config file:", v.ConfigFileUsed())
+	configFileUsed = v.ConfigFileUsed()
+	fmt.Println("Using config file:", configFileUsed)
+
+	return unmarshal(v, conf)
+}
+
+// ConfigFileUsed returns the config file loaded by LoadConfig
+func ConfigFileUsed() string {
+	return configFileUsed
+}
+
+// ReloadConfig reads the config file loaded by LoadConfig again. Command line flags are not applied,
+// which should be applied by ApplyFlags on top of it as they are at startup.
+func ReloadConfig(conf interface{}) error {
+	v := newViper()
+	v.SetConfigFile(configFileUsed)
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+	return unmarshal(v, conf)
+}
+
+// ApplyFlags sets flags of dst with the values of flags changed in src, which have the same names.
+// It is used to apply command line flags to options reloaded from config file.
+func ApplyFlags(dst, src *pflag.FlagSet) error {
+	var err error
+	src.Visit(func(flag *pflag.Flag) {
+		target := dst.Lookup(flag.Name)
+		if err != nil || target == nil {
+			return
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			err = target.Value.(pflag.SliceValue).Replace(slice.GetSlice())
+			return
+		}
+		value := flag.Value.String()
+		if flag.Value.Type() == "stringToString" {
+			// formatted as `[a=1,b=2]`, while Set accepts `a=1,b=2`
+			if value = strings.Trim(value, "[]"); value == "" {
+				return
+			}
+		}
+		err = target.Value.Set(value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply flags: %w", err)
+	}
+	return nil
+}
+
+func newViper() *viper.Viper {
+	// to avoid split podLabels/podAnnotations key
+	v := viper.NewWithOptions(viper.KeyDelimiter("::"))
+	v.SetEnvKeyReplacer(strings.NewReplacer("::", "_"))
+	v.AutomaticEnv() // read in environment variables that match
+	return v
+}
 
+func unmarshal(v *viper.Viper, conf interface{}) error {
 	return v.Unmarshal(conf, viper.DecodeHook(
 		mapstructure.ComposeDecodeHookFunc(
 			mapstructure.StringToTimeDurationHookFunc(),
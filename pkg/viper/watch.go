@@ -0,0 +1,50 @@
+package viper
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/GBA-BI/tes-k8s-agent/pkg/log"
+)
+
+// WatchFile calls onChange when the file is written or replaced, until ctx is done.
+// The directory is watched, so that atomic replacement of k8s configmap volume is also detected.
+func WatchFile(ctx context.Context, path string, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	file := filepath.Clean(path)
+	if err = watcher.Add(filepath.Dir(file)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", file, err)
+	}
+	realFile, _ := filepath.EvalSymlinks(file)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			currentRealFile, _ := filepath.EvalSymlinks(file)
+			// the file is modified or created, or the real path is changed (k8s configmap replacement)
+			if (filepath.Clean(event.Name) == file && (event.Has(fsnotify.Write) || event.Has(fsnotify.Create))) ||
+				(currentRealFile != "" && currentRealFile != realFile) {
+				realFile = currentRealFile
+				onChange()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Warnw("file watcher error", "file", file, "err", err)
+		}
+	}
+}